@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/backup/create"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/backup/db"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/backup/restore"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/backup/verify"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// NewCommand to backup and restore the server.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:     "backup",
+		GroupID: "management",
+		Short:   L("Backup and restore the server"),
+		Long:    L("Backup and restore the server"),
+	}
+
+	backupCmd.AddCommand(create.NewCommand(globalFlags))
+	backupCmd.AddCommand(restore.NewCommand(globalFlags))
+	backupCmd.AddCommand(db.NewCommand(globalFlags))
+	backupCmd.AddCommand(verify.NewCommand(globalFlags))
+
+	return backupCmd
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package create
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type createFlags struct {
+	ForceOverwrite bool `mapstructure:"force"`
+	Encrypt        bool
+	Passphrase     string
+	Backend        string
+}
+
+// NewCommand to create a full backup of the server.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create archive",
+		Short: L("Create a full offline backup of the server"),
+		Long: L(`Create a full offline backup of the server.
+
+The server is stopped during the backup and restarted once done.
+The archive contains the content of all the server volumes, the systemd services configuration
+and a manifest describing the image and the PostgreSQL version of the backed up server.
+The SHA-256 of all the files are stored at the end of the archive to verify its integrity.
+
+With the --encrypt flag, the archive is encrypted with a passphrase and its checksums are signed.
+The passphrase is asked for if not provided with the --passphrase or --passphrase-file flag.
+Unencrypted archives are not authenticated: their checksums only detect accidental corruption,
+anyone able to modify the archive can also update them.
+
+On kubernetes, the server is scaled down and a helper pod mounting all the server volumes claims
+is used to read their content.
+`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags createFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, backupServer)
+		},
+	}
+
+	createCmd.Flags().BoolP("force", "f", false, L("Force overwrite of the archive if it already exists"))
+	createCmd.Flags().Bool("encrypt", false, L("Encrypt the archive with a passphrase"))
+	createCmd.Flags().String("passphrase", "", L("Passphrase to encrypt the archive with, implies --encrypt"))
+	utils.AddPasswordFileFlag(createCmd.Flags(), "passphrase")
+
+	if utils.KubernetesBuilt {
+		utils.AddBackendFlag(createCmd)
+	}
+
+	return createCmd
+}
+
+func backupServer(globalFlags *types.GlobalFlags, flags *createFlags, cmd *cobra.Command, args []string) error {
+	if flags.Encrypt {
+		utils.AskPasswordIfMissing(&flags.Passphrase, L("Backup passphrase"), 0, 0)
+	}
+
+	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanBackup, kubernetesBackup)
+	if err != nil {
+		return err
+	}
+
+	return fn(globalFlags, flags, cmd, args)
+}
+
+// finishBackup adds the checksums to the archive and closes it.
+func finishBackup(tarball *utils.TarGz, flags *createFlags) error {
+	if err := backup.WriteChecksums(tarball, flags.Passphrase); err != nil {
+		return err
+	}
+	if err := tarball.Close(); err != nil {
+		return utils.Errorf(err, L("failed to write the backup archive"))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !nok8s
+
+package create
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_kubernetes "github.com/uyuni-project/uyuni-tools/mgradm/shared/kubernetes"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func kubernetesBackup(
+	globalFlags *types.GlobalFlags,
+	flags *createFlags,
+	cmd *cobra.Command,
+	args []string,
+) error {
+	archivePath := args[0]
+	if utils.FileExists(archivePath) && !flags.ForceOverwrite {
+		return fmt.Errorf(L("%s already exists, use -f to force overwrite"), archivePath)
+	}
+
+	cnx := shared.NewConnection("kubectl", "", kubernetes.ServerFilter)
+	serverImage, err := adm_utils.RunningImage(cnx, "uyuni")
+	if err != nil {
+		return utils.Errorf(err, L("cannot find the image of the server"))
+	}
+
+	inspectedValues, err := adm_utils.InspectRunningServer(cnx)
+	if err != nil {
+		return utils.Errorf(err, L("cannot inspect the server"))
+	}
+
+	namespace, err := cnx.GetNamespace("")
+	if err != nil {
+		return utils.Errorf(err, L("cannot find the server namespace"))
+	}
+
+	replicas, nodeName, err := adm_kubernetes.GetServerPlacement(namespace)
+	if err != nil {
+		return err
+	}
+
+	claims := adm_kubernetes.ServerClaims()
+	volumes := make([]string, len(claims))
+	for i, claim := range claims {
+		volumes[i] = claim.Name
+	}
+	manifest := backup.NewManifest(serverImage, inspectedValues, volumes)
+	manifest.Replicas[kubernetes.ServerApp] = int(replicas)
+
+	if err := kubernetes.ReplicasTo(namespace, kubernetes.ServerApp, 0); err != nil {
+		return utils.Errorf(err, L("cannot set replica to 0"))
+	}
+	defer func() {
+		if err := kubernetes.ReplicasTo(namespace, kubernetes.ServerApp, replicas); err != nil {
+			log.Error().Err(err).Msg(L("failed to restart the server after the backup"))
+		}
+	}()
+
+	if err := adm_kubernetes.StartBackupPod(namespace, serverImage, "IfNotPresent", nodeName); err != nil {
+		return err
+	}
+	defer func() {
+		if err := adm_kubernetes.StopBackupPod(namespace); err != nil {
+			log.Error().Err(err).Msgf(L("failed to delete the %s pod"), adm_kubernetes.BackupPodName)
+		}
+	}()
+
+	tarball, err := utils.NewEncryptedTarGz(archivePath, flags.Passphrase)
+	if err != nil {
+		return err
+	}
+	defer tarball.Close()
+
+	if err := os.Chmod(archivePath, 0600); err != nil {
+		return utils.Errorf(err, L("failed to restrict permissions of %s"), archivePath)
+	}
+
+	if err := manifest.Write(tarball); err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		log.Info().Msgf(L("Backing up volume %s"), volume)
+		err := adm_kubernetes.ReadClaim(namespace, volume, func(header *tar.Header, reader io.Reader) error {
+			header.Name = backup.VolumeFileEntry(volume, header.Name, header.Typeflag == tar.TypeDir)
+			return tarball.AddEntry(header, reader)
+		})
+		if err != nil {
+			return utils.Errorf(err, L("failed to add volume %s to the backup"), volume)
+		}
+	}
+
+	if err := finishBackup(tarball, flags); err != nil {
+		return err
+	}
+
+	log.Info().Msgf(L("Server backup written to %s"), archivePath)
+	return nil
+}
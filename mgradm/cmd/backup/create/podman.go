@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package create
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_podman "github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func podmanBackup(
+	globalFlags *types.GlobalFlags,
+	flags *createFlags,
+	cmd *cobra.Command,
+	args []string,
+) error {
+	archivePath := args[0]
+	if utils.FileExists(archivePath) && !flags.ForceOverwrite {
+		return fmt.Errorf(L("%s already exists, use -f to force overwrite"), archivePath)
+	}
+
+	serverImage := podman.GetServiceImage(podman.ServerService)
+	if serverImage == "" {
+		return errors.New(L("cannot find the image of the server, is it installed?"))
+	}
+
+	inspectedValues, err := adm_podman.Inspect(serverImage)
+	if err != nil {
+		return utils.Errorf(err, L("cannot inspect podman values"))
+	}
+
+	volumes := backup.ServerVolumeNames()
+	manifest := backup.NewManifest(serverImage, inspectedValues, volumes)
+	for _, service := range []string{podman.ServerAttestationService, podman.HubXmlrpcService} {
+		manifest.Replicas[service] = podman.CurrentReplicaCount(service)
+	}
+
+	// Stop the services to get consistent data and restart them when done
+	if err := utils.JoinErrors(
+		podman.StopInstantiated(podman.ServerAttestationService),
+		podman.StopInstantiated(podman.HubXmlrpcService),
+		podman.StopService(podman.ServerService),
+	); err != nil {
+		return utils.Errorf(err, L("cannot stop service"))
+	}
+
+	defer func() {
+		if startErr := utils.JoinErrors(
+			podman.StartService(podman.ServerService),
+			podman.StartInstantiated(podman.ServerAttestationService),
+			podman.StartInstantiated(podman.HubXmlrpcService),
+		); startErr != nil {
+			log.Error().Err(startErr).Msg(L("failed to restart the services after the backup"))
+		}
+	}()
+
+	tarball, err := utils.NewEncryptedTarGz(archivePath, flags.Passphrase)
+	if err != nil {
+		return err
+	}
+	defer tarball.Close()
+
+	if err := os.Chmod(archivePath, 0600); err != nil {
+		return utils.Errorf(err, L("failed to restrict permissions of %s"), archivePath)
+	}
+
+	if err := manifest.Write(tarball); err != nil {
+		return err
+	}
+
+	for _, service := range backup.PodmanServices() {
+		if err := addServiceFiles(tarball, service); err != nil {
+			return err
+		}
+	}
+
+	for _, volume := range volumes {
+		mountPoint, err := adm_podman.GetMountPoint(volume)
+		if err != nil {
+			return utils.Errorf(err, L("cannot inspect volume %s"), volume)
+		}
+
+		log.Info().Msgf(L("Backing up volume %s"), volume)
+		if err := tarball.AddDirectory(mountPoint, backup.VolumeEntry(volume)); err != nil {
+			return utils.Errorf(err, L("failed to add volume %s to the backup"), volume)
+		}
+	}
+
+	if err := finishBackup(tarball, flags); err != nil {
+		return err
+	}
+
+	log.Info().Msgf(L("Server backup written to %s"), archivePath)
+	return nil
+}
+
+// addServiceFiles adds a systemd unit and its configuration folder to the backup if they exist.
+func addServiceFiles(tarball *utils.TarGz, service string) error {
+	servicePath := podman.GetServicePath(service)
+	if !utils.FileExists(servicePath) {
+		log.Debug().Msgf("No %s file to backup", servicePath)
+		return nil
+	}
+
+	if err := tarball.AddFile(servicePath, backup.ServiceEntry(service)); err != nil {
+		return utils.Errorf(err, L("failed to add %s to the backup"), servicePath)
+	}
+
+	confFolder := podman.GetServiceConfFolder(service)
+	if utils.FileExists(confFolder) {
+		if err := tarball.AddDirectory(confFolder, backup.ServiceConfEntry(service)); err != nil {
+			return utils.Errorf(err, L("failed to add %s to the backup"), confFolder)
+		}
+	}
+	return nil
+}
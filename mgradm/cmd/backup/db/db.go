@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type dbFlags struct {
+	ForceOverwrite bool `mapstructure:"force"`
+	Backend        string
+}
+
+// NewCommand to dump the server database.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db dump-file",
+		Short: L("Dump the server database"),
+		Long: L(`Dump the server database to a local file.
+
+The dump is created with pg_dump in the running server container and streamed to the local file.
+The server remains available during the dump. Use 'mgradm restore db' to restore it.
+`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags dbFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, dumpDb)
+		},
+	}
+
+	dbCmd.Flags().BoolP("force", "f", false, L("Force overwrite of the dump file if it already exists"))
+	utils.AddBackendFlag(dbCmd)
+
+	return dbCmd
+}
+
+func dumpDb(globalFlags *types.GlobalFlags, flags *dbFlags, cmd *cobra.Command, args []string) error {
+	output := args[0]
+	if utils.FileExists(output) && !flags.ForceOverwrite {
+		return fmt.Errorf(L("output file %s exists, use -f to force overwrite"), output)
+	}
+
+	cnx := shared.NewConnection(flags.Backend, podman.ServerContainerName, kubernetes.ServerFilter)
+	data, err := adm_utils.InspectRunningServer(cnx)
+	if err != nil {
+		return utils.Errorf(err, L("failed to inspect the server"))
+	}
+
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return utils.Errorf(err, L("failed to create %s"), output)
+	}
+	defer out.Close()
+
+	if err := backup.DumpDatabase(cnx, data, out); err != nil {
+		if rmErr := os.Remove(output); rmErr != nil {
+			log.Warn().Err(rmErr).Msgf(L("failed to remove incomplete dump %s"), output)
+		}
+		return err
+	}
+
+	log.Info().Msgf(L("Database dump stored in %s"), output)
+	return nil
+}
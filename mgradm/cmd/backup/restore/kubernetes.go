@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !nok8s
+
+package restore
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_kubernetes "github.com/uyuni-project/uyuni-tools/mgradm/shared/kubernetes"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func kubernetesRestore(
+	globalFlags *types.GlobalFlags,
+	flags *restoreFlags,
+	cmd *cobra.Command,
+	args []string,
+) (err error) {
+	archivePath := args[0]
+	manifest, err := openBackup(archivePath, flags)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf(L("Restoring backup of %[1]s taken on %[2]s"), manifest.Image, manifest.Date)
+
+	if flags.Image.Name != "" {
+		return errors.New(L("changing the image is not supported on kubernetes, run 'mgradm upgrade kubernetes' after the restore"))
+	}
+	if !flags.Force {
+		return errors.New(L("the restore overwrites the data of the installed server, use --force to confirm"))
+	}
+
+	cnx := shared.NewConnection("kubectl", "", kubernetes.ServerFilter)
+	serverImage, err := adm_utils.RunningImage(cnx, "uyuni")
+	if err != nil {
+		return utils.Errorf(err, L("cannot find the image of the server"))
+	}
+
+	// Check the PostgreSQL versions before touching anything
+	inspectedValues, err := adm_utils.InspectRunningServer(cnx)
+	if err != nil {
+		return utils.Errorf(err, L("cannot inspect the server"))
+	}
+	if err := checkPgVersion(manifest.PgVersion, inspectedValues.ImagePgVersion, flags.Upgrade); err != nil {
+		return err
+	}
+
+	namespace, err := cnx.GetNamespace("")
+	if err != nil {
+		return utils.Errorf(err, L("cannot find the server namespace"))
+	}
+
+	replicas, nodeName, err := adm_kubernetes.GetServerPlacement(namespace)
+	if err != nil {
+		return err
+	}
+
+	if err := kubernetes.ReplicasTo(namespace, kubernetes.ServerApp, 0); err != nil {
+		return utils.Errorf(err, L("cannot set replica to 0"))
+	}
+	// The server is left stopped if its claims could not be put back in their previous state
+	keepStopped := false
+	defer func() {
+		if keepStopped {
+			return
+		}
+		if err := kubernetes.ReplicasTo(namespace, kubernetes.ServerApp, replicas); err != nil {
+			log.Error().Err(err).Msg(L("failed to start the server after the restore"))
+		}
+	}()
+
+	// The previous content of the claims is kept until the end to put it back if anything fails.
+	// This is deferred after starting the server to run before it.
+	var movedClaims []string
+	defer func() {
+		if len(movedClaims) == 0 {
+			return
+		}
+		if finishErr := finishClaims(namespace, serverImage, nodeName, movedClaims, err != nil); finishErr != nil {
+			if err == nil {
+				log.Warn().Err(finishErr).Msg(L("cannot remove the previous content of the claims"))
+				return
+			}
+			keepStopped = true
+			err = utils.JoinErrors(err, finishErr, fmt.Errorf(
+				L("the server is left stopped as its claims could not be put back, their previous content is in their %s folder"),
+				adm_kubernetes.PreRestoreDir,
+			))
+		}
+	}()
+
+	if err := adm_kubernetes.StartBackupPod(namespace, serverImage, "IfNotPresent", nodeName); err != nil {
+		return err
+	}
+	movedClaims, err = restoreClaims(namespace, archivePath, flags.Passphrase)
+	if stopErr := adm_kubernetes.StopBackupPod(namespace); stopErr != nil {
+		err = utils.JoinErrors(err, stopErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if manifest.PgVersion != inspectedValues.ImagePgVersion {
+		imageFlags := types.ImageFlags{Name: serverImage, PullPolicy: flags.Image.PullPolicy}
+		if err := adm_kubernetes.RunPgsqlVersionUpgrade(globalFlags.Registry, imageFlags, flags.DbUpgradeImage,
+			nodeName, manifest.PgVersion, inspectedValues.ImagePgVersion,
+		); err != nil {
+			return utils.Errorf(err, L("cannot run PostgreSQL version upgrade script"))
+		}
+
+		if err := adm_kubernetes.RunPgsqlFinalizeScript(serverImage, flags.Image.PullPolicy, nodeName, true, false); err != nil {
+			return utils.Errorf(err, L("cannot run PostgreSQL finalize script"))
+		}
+
+		if err := adm_kubernetes.RunPostUpgradeScript(serverImage, flags.Image.PullPolicy, nodeName); err != nil {
+			return utils.Errorf(err, L("cannot run post upgrade script"))
+		}
+	}
+
+	log.Info().Msg(L("Server restored"))
+	return nil
+}
+
+// finishClaims removes the previous content of the claims moved aside during the restore
+// or puts it back if the restore failed.
+//
+// The backup pod is started again to access the claims.
+func finishClaims(namespace string, image string, nodeName string, claims []string, failed bool) error {
+	if err := adm_kubernetes.StartBackupPod(namespace, image, "IfNotPresent", nodeName); err != nil {
+		return err
+	}
+
+	var err error
+	for _, claim := range claims {
+		if failed {
+			log.Info().Msgf(L("Putting back the previous content of volume %s"), claim)
+			err = utils.JoinErrors(err, adm_kubernetes.PutBackClaimAside(namespace, claim))
+		} else {
+			err = utils.JoinErrors(err, adm_kubernetes.DiscardClaimAside(namespace, claim))
+		}
+	}
+	return utils.JoinErrors(err, adm_kubernetes.StopBackupPod(namespace))
+}
+
+// restoreClaims streams the volumes content of the archive to the server claims mounted in the backup pod.
+//
+// The previous content of each claim is moved aside before writing it: the moved claims are returned even on error.
+// The entries of a volume are contiguous in the archive, one extraction is running per volume.
+func restoreClaims(namespace string, archivePath string, passphrase string) ([]string, error) {
+	claims := []string{}
+	for _, claim := range adm_kubernetes.ServerClaims() {
+		claims = append(claims, claim.Name)
+	}
+
+	moved := []string{}
+	var writer *adm_kubernetes.ClaimWriter
+	currentVolume := ""
+	closeWriter := func() error {
+		if writer == nil {
+			return nil
+		}
+		err := writer.Close()
+		writer = nil
+		return err
+	}
+
+	err := utils.WalkEncryptedTarGz(archivePath, passphrase, func(header *tar.Header, reader io.Reader) error {
+		volume, name, found := backup.SplitVolumeEntry(header.Name)
+		if !found {
+			log.Debug().Msgf("Skipping %s entry", header.Name)
+			return nil
+		}
+
+		if volume != currentVolume {
+			if err := closeWriter(); err != nil {
+				return err
+			}
+			currentVolume = volume
+			if !utils.Contains(claims, volume) {
+				log.Warn().Msgf(L("Skipping volume %s: no such claim for the server"), volume)
+				return nil
+			}
+			log.Info().Msgf(L("Restoring volume %s"), volume)
+			if err := adm_kubernetes.MoveClaimAside(namespace, volume); err != nil {
+				return err
+			}
+			moved = append(moved, volume)
+			var err error
+			if writer, err = adm_kubernetes.NewClaimWriter(namespace, volume); err != nil {
+				return err
+			}
+		}
+
+		if writer == nil {
+			return nil
+		}
+		return writer.WriteEntry(header, reader, name)
+	})
+	if closeErr := closeWriter(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return moved, utils.Errorf(err, L("failed to extract %s"), archivePath)
+	}
+	return moved, nil
+}
@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build nok8s
+
+package restore
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+func kubernetesRestore(
+	globalFlags *types.GlobalFlags,
+	flags *restoreFlags,
+	cmd *cobra.Command,
+	args []string,
+) error {
+	return errors.New(L("built without kubernetes support"))
+}
@@ -0,0 +1,371 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restore
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_podman "github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func podmanRestore(
+	globalFlags *types.GlobalFlags,
+	flags *restoreFlags,
+	cmd *cobra.Command,
+	args []string,
+) (err error) {
+	archivePath := args[0]
+	manifest, err := openBackup(archivePath, flags)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf(L("Restoring backup of %[1]s taken on %[2]s"), manifest.Image, manifest.Date)
+
+	if utils.FileExists(podman.GetServicePath(podman.ServerService)) && !flags.Force {
+		return errors.New(L("a server is already installed, uninstall it first or use --force to overwrite it"))
+	}
+
+	// Find out the image to restore
+	image := manifest.Image
+	imageFlags := types.ImageFlags{Name: manifest.Image, PullPolicy: flags.Image.PullPolicy}
+	if flags.Image.Name != "" {
+		imageFlags = flags.Image
+		image, err = utils.ComputeImage(globalFlags.Registry, utils.DefaultTag, flags.Image)
+		if err != nil {
+			return utils.Errorf(err, L("failed to compute image URL"))
+		}
+	}
+
+	hostData, err := podman.InspectHost()
+	if err != nil {
+		return err
+	}
+
+	authFile, cleaner, err := podman.PodmanLogin(hostData, flags.SCC)
+	if err != nil {
+		return utils.Errorf(err, L("failed to login to registry.suse.com"))
+	}
+	defer cleaner()
+
+	preparedImage, err := podman.PrepareImage(authFile, image, flags.Image.PullPolicy)
+	if err != nil {
+		return err
+	}
+
+	// Check the PostgreSQL versions before touching anything
+	inspectedValues, err := adm_podman.InspectImage(preparedImage)
+	if err != nil {
+		return utils.Errorf(err, L("cannot inspect podman values"))
+	}
+	if err := checkPgVersion(manifest.PgVersion, inspectedValues.ImagePgVersion, flags.Upgrade); err != nil {
+		return err
+	}
+
+	if flags.Force {
+		if err := utils.JoinErrors(
+			podman.StopInstantiated(podman.ServerAttestationService),
+			podman.StopInstantiated(podman.HubXmlrpcService),
+			podman.StopService(podman.ServerService),
+		); err != nil {
+			return utils.Errorf(err, L("cannot stop service"))
+		}
+
+		// Do not leave the existing server stopped if the restore fails
+		defer func() {
+			if err == nil {
+				return
+			}
+			log.Warn().Msg(L("Restore failed, starting the stopped services again"))
+			if startErr := utils.JoinErrors(
+				podman.StartService(podman.ServerService),
+				podman.StartInstantiated(podman.HubXmlrpcService),
+				podman.StartInstantiated(podman.ServerAttestationService),
+			); startErr != nil {
+				log.Error().Err(startErr).Msg(L("cannot start the stopped services"))
+			}
+		}()
+	}
+
+	mountPoints := map[string]string{}
+	for _, volume := range manifest.Volumes {
+		if err := podman.CreateVolume(volume); err != nil {
+			return err
+		}
+		mountPoint, err := adm_podman.GetMountPoint(volume)
+		if err != nil {
+			return utils.Errorf(err, L("cannot inspect volume %s"), volume)
+		}
+		mountPoints[volume] = mountPoint
+	}
+
+	// Keep the existing volumes content until the restore succeeded to put it back otherwise.
+	// This is deferred after restarting the services to run before it.
+	putBackVolumes, err := moveVolumesAside(mountPoints)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		putBackVolumes(err != nil)
+	}()
+
+	// The existing services files are put back too, before restarting the services
+	putBackServices, err := moveServiceFilesAside()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		putBackServices(err != nil)
+		if err != nil {
+			if reloadErr := podman.ReloadDaemon(false); reloadErr != nil {
+				log.Error().Err(reloadErr).Msg(L("cannot reload the services"))
+			}
+		}
+	}()
+
+	if err := extractBackup(archivePath, flags.Passphrase, mountPoints); err != nil {
+		return err
+	}
+
+	if manifest.Image != preparedImage {
+		if err := podman.SetServiceImage(podman.ServerService, preparedImage); err != nil {
+			return utils.Errorf(err, L("cannot generate systemd conf file"))
+		}
+	}
+
+	if _, err := podman.SetupNetwork(false); err != nil {
+		return utils.Errorf(err, L("cannot setup network"))
+	}
+
+	if err := podman.ReloadDaemon(false); err != nil {
+		return err
+	}
+
+	if manifest.PgVersion != inspectedValues.ImagePgVersion {
+		if err := adm_podman.RunPgsqlVersionUpgrade(authFile, globalFlags.Registry, imageFlags, flags.DbUpgradeImage,
+			manifest.PgVersion, inspectedValues.ImagePgVersion,
+		); err != nil {
+			return utils.Errorf(err, L("cannot run PostgreSQL version upgrade script"))
+		}
+
+		if err := adm_podman.RunPgsqlFinalizeScript(preparedImage, true, false); err != nil {
+			return utils.Errorf(err, L("cannot run PostgreSQL finalize script"))
+		}
+
+		if err := adm_podman.RunPostUpgradeScript(preparedImage); err != nil {
+			return utils.Errorf(err, L("cannot run post upgrade script"))
+		}
+	}
+
+	if err := podman.EnableService(podman.ServerService); err != nil {
+		return err
+	}
+
+	for service, replicas := range manifest.Replicas {
+		if replicas > 0 && utils.FileExists(podman.GetServicePath(service+"@")) {
+			if err := podman.ScaleService(replicas, service); err != nil {
+				return utils.Errorf(err, L("cannot enable service"))
+			}
+		}
+	}
+
+	log.Info().Msg(L("Server restored"))
+	return nil
+}
+
+// checkPgVersion ensures the backed up database can be used with the image PostgreSQL version.
+func checkPgVersion(backupPgVersion string, imagePgVersion string, upgrade bool) error {
+	if backupPgVersion == imagePgVersion {
+		return nil
+	}
+	if imagePgVersion > backupPgVersion {
+		if upgrade {
+			log.Info().Msgf(L("The database will be upgraded from PostgreSQL %[1]s to %[2]s"), backupPgVersion, imagePgVersion)
+			return nil
+		}
+		return fmt.Errorf(
+			L("the backup has PostgreSQL %[1]s while the image has PostgreSQL %[2]s, use --upgrade to upgrade the database"),
+			backupPgVersion, imagePgVersion,
+		)
+	}
+	return fmt.Errorf(L("cannot restore a PostgreSQL %[1]s backup with an image using PostgreSQL %[2]s"),
+		backupPgVersion, imagePgVersion)
+}
+
+// preRestoreSuffix is appended to the volumes mount points to keep their content during the restore.
+const preRestoreSuffix = ".pre-restore"
+
+// moveVolumesAside moves the content of the volumes mount points next to them and leaves them empty.
+//
+// The returned function has to be called once the restore is done:
+// it puts the previous content back if failed is true and removes it otherwise.
+func moveVolumesAside(mountPoints map[string]string) (func(failed bool), error) {
+	moved := []string{}
+	putBack := func(failed bool) {
+		for _, mountPoint := range moved {
+			savedPath := mountPoint + preRestoreSuffix
+			if !failed {
+				if err := os.RemoveAll(savedPath); err != nil {
+					log.Warn().Err(err).Msgf(L("cannot remove the previous volume content in %s"), savedPath)
+				}
+				continue
+			}
+			if err := utils.JoinErrors(os.RemoveAll(mountPoint), os.Rename(savedPath, mountPoint)); err != nil {
+				log.Error().Err(err).Msgf(L("cannot put back the previous volume content from %[1]s to %[2]s"),
+					savedPath, mountPoint)
+			}
+		}
+	}
+
+	for volume, mountPoint := range mountPoints {
+		if err := moveAside(mountPoint); err != nil {
+			putBack(true)
+			return nil, utils.Errorf(err, L("cannot move the content of volume %s aside"), volume)
+		}
+		moved = append(moved, mountPoint)
+	}
+	return putBack, nil
+}
+
+// moveServiceFilesAside renames the existing unit files and drop-in folders of the backed up services
+// with the pre-restore suffix.
+//
+// The returned function has to be called once the restore is done: if failed is true it removes the restored
+// files and puts the previous ones back, otherwise it removes the previous files.
+func moveServiceFilesAside() (func(failed bool), error) {
+	paths := []string{}
+	for _, service := range backup.PodmanServices() {
+		servicePath := podman.GetServicePath(service)
+		paths = append(paths, servicePath, servicePath+".d")
+	}
+
+	moved := []string{}
+	putBack := func(failed bool) {
+		for _, path := range paths {
+			savedPath := path + preRestoreSuffix
+			isMoved := utils.Contains(moved, path)
+			if !failed {
+				if isMoved {
+					if err := os.RemoveAll(savedPath); err != nil {
+						log.Warn().Err(err).Msgf(L("cannot remove the previous service file %s"), savedPath)
+					}
+				}
+				continue
+			}
+			err := os.RemoveAll(path)
+			if isMoved {
+				err = utils.JoinErrors(err, os.Rename(savedPath, path))
+			}
+			if err != nil {
+				log.Error().Err(err).Msgf(L("cannot put back the previous service file %s"), path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if !utils.FileExists(path) {
+			continue
+		}
+		savedPath := path + preRestoreSuffix
+		if utils.FileExists(savedPath) {
+			putBack(true)
+			return nil, fmt.Errorf(L("%s already exists, it may contain data of a previously failed restore"), savedPath)
+		}
+		if err := os.Rename(path, savedPath); err != nil {
+			putBack(true)
+			return nil, utils.Errorf(err, L("cannot move %s aside"), path)
+		}
+		moved = append(moved, path)
+	}
+	return putBack, nil
+}
+
+// moveAside renames mountPoint with the pre-restore suffix and creates an empty folder with the same permissions.
+func moveAside(mountPoint string) error {
+	savedPath := mountPoint + preRestoreSuffix
+	if utils.FileExists(savedPath) {
+		return fmt.Errorf(L("%s already exists, it may contain data of a previously failed restore"), savedPath)
+	}
+
+	info, err := os.Stat(mountPoint)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(mountPoint, savedPath); err != nil {
+		return err
+	}
+	if err := os.Mkdir(mountPoint, info.Mode().Perm()); err == nil {
+		err = os.Chmod(mountPoint, info.Mode().Perm())
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && err == nil {
+			err = os.Chown(mountPoint, int(stat.Uid), int(stat.Gid))
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return utils.JoinErrors(err, os.RemoveAll(mountPoint), os.Rename(savedPath, mountPoint))
+}
+
+// extractBackup writes the volumes content and systemd files of the archive to the host.
+//
+// mountPoints maps the volumes names to their path on the host.
+func extractBackup(archivePath string, passphrase string, mountPoints map[string]string) error {
+	err := utils.WalkEncryptedTarGz(archivePath, passphrase, func(header *tar.Header, reader io.Reader) error {
+		if header.Name == backup.ManifestFilename || header.Name == backup.ChecksumsFilename {
+			return nil
+		}
+
+		if volume, name, found := backup.SplitVolumeEntry(header.Name); found {
+			mountPoint, ok := mountPoints[volume]
+			if !ok {
+				log.Warn().Msgf(L("Skipping %[1]s: volume %[2]s is not listed in the manifest"), header.Name, volume)
+				return nil
+			}
+			return utils.ExtractTarEntry(header, reader, mountPoint, name)
+		}
+
+		for _, service := range backup.PodmanServices() {
+			serviceEntry := backup.ServiceEntry(service)
+			confEntry := backup.ServiceConfEntry(service)
+			if header.Name == serviceEntry {
+				return utils.ExtractTarEntry(header, reader, podman.GetServicesFolder(), service+".service")
+			}
+			if strings.HasPrefix(header.Name, confEntry) {
+				return utils.ExtractTarEntry(header, reader, podman.GetServicesFolder(),
+					strings.TrimPrefix(header.Name, backup.SystemdDir+"/"))
+			}
+		}
+
+		log.Warn().Msgf(L("Skipping unknown entry %s"), header.Name)
+		return nil
+	})
+	if err != nil {
+		return utils.Errorf(err, L("failed to extract %s"), archivePath)
+	}
+
+	// The restored files need their SELinux labels to be set
+	if _, err := exec.LookPath("restorecon"); err == nil {
+		for _, mountPoint := range mountPoints {
+			if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "restorecon", "-F", "-r", mountPoint); err != nil {
+				return utils.Errorf(err, L("cannot restore %s SELinux permissions"), mountPoint)
+			}
+		}
+	}
+	return nil
+}
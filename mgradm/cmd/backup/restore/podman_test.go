@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestCheckPgVersion(t *testing.T) {
+	data := []struct {
+		backup   string
+		image    string
+		upgrade  bool
+		expected bool
+	}{
+		{"16", "16", false, true},
+		{"14", "16", false, false},
+		{"14", "16", true, true},
+		{"16", "14", false, false},
+		{"16", "14", true, false},
+	}
+
+	for i, testCase := range data {
+		err := checkPgVersion(testCase.backup, testCase.image, testCase.upgrade)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected result", i), testCase.expected, err == nil)
+	}
+}
+
+func TestMoveVolumesAside(t *testing.T) {
+	for _, failed := range []bool{true, false} {
+		mountPoint := path.Join(t.TempDir(), "_data")
+		if err := os.Mkdir(mountPoint, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(mountPoint, "old"), []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		putBack, err := moveVolumesAside(map[string]string{"var-pgsql": mountPoint})
+		if err != nil {
+			t.Fatalf("failed to move the volume aside: %s", err)
+		}
+
+		entries, err := os.ReadDir(mountPoint)
+		if err != nil {
+			t.Fatalf("failed to read the emptied volume: %s", err)
+		}
+		test_utils.AssertEquals(t, "volume not emptied", 0, len(entries))
+		info, err := os.Stat(mountPoint)
+		if err != nil {
+			t.Fatalf("failed to stat the emptied volume: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong volume permissions", os.FileMode(0750), info.Mode().Perm())
+
+		if err := os.WriteFile(path.Join(mountPoint, "new"), []byte("new"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		putBack(failed)
+
+		test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: unexpected old file", failed),
+			failed, utils.FileExists(path.Join(mountPoint, "old")))
+		test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: unexpected new file", failed),
+			!failed, utils.FileExists(path.Join(mountPoint, "new")))
+		test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: pre-restore folder left", failed),
+			false, utils.FileExists(mountPoint+preRestoreSuffix))
+	}
+}
+
+func TestMoveServiceFilesAside(t *testing.T) {
+	for _, failed := range []bool{true, false} {
+		t.Cleanup(podman.SetServicesFolder(t.TempDir()))
+		servicePath := podman.GetServicePath(podman.ServerService)
+		confPath := podman.GetServiceConfPath(podman.ServerService)
+		if err := os.MkdirAll(path.Dir(confPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range []string{servicePath, confPath} {
+			if err := os.WriteFile(file, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		putBack, err := moveServiceFilesAside()
+		if err != nil {
+			t.Fatalf("failed to move the service files aside: %s", err)
+		}
+		test_utils.AssertTrue(t, "service file not moved", !utils.FileExists(servicePath))
+		test_utils.AssertTrue(t, "drop-in folder not moved", !utils.FileExists(path.Dir(confPath)))
+
+		// Restored files, including one of a service that wasn't installed
+		hubPath := podman.GetServicePath(podman.HubXmlrpcService + "@")
+		for _, file := range []string{servicePath, hubPath} {
+			if err := os.WriteFile(file, []byte("new"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		putBack(failed)
+
+		expected := "new"
+		if failed {
+			expected = "old"
+		}
+		if content, err := os.ReadFile(servicePath); err != nil {
+			t.Errorf("failed=%v: failed to read the service file: %s", failed, err)
+		} else {
+			test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: wrong service file", failed), expected, string(content))
+		}
+		test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: unexpected previous drop-in", failed),
+			failed, utils.FileExists(confPath))
+		test_utils.AssertEquals(t, fmt.Sprintf("failed=%v: unexpected restored service", failed),
+			!failed, utils.FileExists(hubPath))
+		test_utils.AssertTrue(t, fmt.Sprintf("failed=%v: pre-restore file left", failed),
+			!utils.FileExists(servicePath+preRestoreSuffix))
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restore
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type restoreFlags struct {
+	Image          types.ImageFlags `mapstructure:",squash"`
+	DbUpgradeImage types.ImageFlags `mapstructure:"dbupgrade"`
+	SCC            types.SCCCredentials
+	Upgrade        bool
+	Force          bool
+	Passphrase     string
+	Backend        string
+}
+
+// NewCommand to restore a server from a backup archive.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore archive",
+		Short: L("Restore the server from a backup archive"),
+		Long: L(`Restore the server from a backup archive created by the 'backup create' command.
+
+The volumes, the systemd services and their configuration are recreated from the archive before starting the server.
+By default the server image stored in the backup is used.
+
+The PostgreSQL version of the backup needs to match the one of the image to restore.
+If the image has a newer PostgreSQL, use the --upgrade flag to perform the database upgrade after restoring the data.
+
+The integrity of the archive is verified before restoring anything.
+Only encrypted backups are authenticated, restore unencrypted ones only from a trusted location.
+The passphrase of an encrypted backup is asked for if not provided with the --passphrase or --passphrase-file flag.
+
+On kubernetes, the server needs to be installed and its volumes claims are overwritten with the content of the backup.
+The image of the installed server is used and the systemd services of the archive are ignored.
+`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags restoreFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, restoreServer)
+		},
+	}
+
+	restoreCmd.Flags().StringVar(&globalFlags.Registry, "registry", "", L("specify a private registry"))
+	restoreCmd.Flags().String("image", "", L("Image to restore instead of the one stored in the backup"))
+	restoreCmd.Flags().String("tag", utils.DefaultTag, L("Tag Image"))
+	utils.AddPullPolicyFlag(restoreCmd)
+	_ = utils.AddFlagHelpGroup(restoreCmd, &utils.Group{ID: "image", Title: L("Image Flags")})
+	_ = utils.AddFlagToHelpGroupID(restoreCmd, "image", "image")
+	_ = utils.AddFlagToHelpGroupID(restoreCmd, "tag", "image")
+	_ = utils.AddFlagToHelpGroupID(restoreCmd, "pullPolicy", "image")
+
+	adm_utils.AddSCCFlag(restoreCmd)
+	adm_utils.AddDbUpgradeImageFlag(restoreCmd)
+
+	restoreCmd.Flags().Bool("upgrade", false, L("Upgrade the database if the image has a newer PostgreSQL version than the backup"))
+	restoreCmd.Flags().BoolP("force", "f", false, L("Overwrite the currently installed server"))
+	restoreCmd.Flags().String("passphrase", "", L("Passphrase of the encrypted backup"))
+	utils.AddPasswordFileFlag(restoreCmd.Flags(), "passphrase")
+
+	if utils.KubernetesBuilt {
+		utils.AddBackendFlag(restoreCmd)
+	}
+
+	return restoreCmd
+}
+
+func restoreServer(globalFlags *types.GlobalFlags, flags *restoreFlags, cmd *cobra.Command, args []string) error {
+	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanRestore, kubernetesRestore)
+	if err != nil {
+		if flags.Backend != "" {
+			return err
+		}
+		// No server could be found: restoring on a fresh podman host
+		fn = podmanRestore
+	}
+
+	return fn(globalFlags, flags, cmd, args)
+}
+
+// openBackup reads the manifest of the backup and verifies its integrity.
+func openBackup(archivePath string, flags *restoreFlags) (*backup.Manifest, error) {
+	if err := backup.AskPassphraseIfEncrypted(archivePath, &flags.Passphrase); err != nil {
+		return nil, err
+	}
+
+	manifest, err := backup.ReadManifest(archivePath, flags.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msg(L("Verifying the backup integrity…"))
+	if err := backup.VerifyArchive(archivePath, flags.Passphrase); err != nil {
+		return nil, utils.Errorf(err, L("the backup is corrupted"))
+	}
+	return manifest, nil
+}
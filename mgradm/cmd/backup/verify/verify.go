@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package verify
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type verifyFlags struct {
+	Passphrase string
+}
+
+// NewCommand to check the integrity of a backup archive.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify archive",
+		Short: L("Check the integrity of a backup archive"),
+		Long: L(`Check the integrity of a backup archive without restoring it.
+
+The SHA-256 of all the files of the archive are compared to the checksums stored in it.
+For encrypted backups, the signature of the checksums is verified too.
+Unencrypted backups are not authenticated: the checks only detect accidental corruption
+since anyone able to modify the archive can also update its checksums.
+The passphrase is asked for if not provided with the --passphrase or --passphrase-file flag.
+`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags verifyFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, verify)
+		},
+	}
+
+	verifyCmd.Flags().String("passphrase", "", L("Passphrase of the encrypted backup"))
+	utils.AddPasswordFileFlag(verifyCmd.Flags(), "passphrase")
+
+	return verifyCmd
+}
+
+func verify(globalFlags *types.GlobalFlags, flags *verifyFlags, cmd *cobra.Command, args []string) error {
+	archivePath := args[0]
+	if err := backup.AskPassphraseIfEncrypted(archivePath, &flags.Passphrase); err != nil {
+		return err
+	}
+
+	manifest, err := backup.ReadManifest(archivePath, flags.Passphrase)
+	if err != nil {
+		return err
+	}
+	if err := backup.VerifyArchive(archivePath, flags.Passphrase); err != nil {
+		return err
+	}
+	log.Info().Msgf(L("The backup of %[1]s taken on %[2]s is valid"), manifest.Image, manifest.Date)
+	return nil
+}
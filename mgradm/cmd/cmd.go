@@ -11,9 +11,11 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/uyuni-project/uyuni-tools/shared/completion"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/backup"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/distro"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/gpg"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/hub"
@@ -21,6 +23,7 @@ import (
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/install"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/migrate"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/restart"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/restore"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/scale"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/start"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/status"
@@ -28,6 +31,7 @@ import (
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/support"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/uninstall"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/upgrade"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/volumes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 )
 
@@ -58,7 +62,7 @@ func NewUyuniadmCommand() (*cobra.Command, error) {
 
 	rootCmd.SetUsageTemplate(utils.GetLocalizedUsageTemplate())
 
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		// do not log if running the completion cmd as the output is redirected to create a file to source
 		if cmd.Name() != "completion" {
 			utils.LogInit(true)
@@ -66,10 +70,13 @@ func NewUyuniadmCommand() (*cobra.Command, error) {
 			log.Info().Msgf(L("Welcome to %s"), name)
 			log.Info().Msgf(L("Executing command: %s"), cmd.Name())
 		}
+		_, err := podman.SetInstance(globalFlags.Instance)
+		return err
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&globalFlags.ConfigPath, "config", "c", "", L("configuration file path"))
 	rootCmd.PersistentFlags().StringVar(&globalFlags.LogLevel, "logLevel", "", L("application log level")+"(trace|debug|info|warn|error|fatal|panic)")
+	podman.AddInstanceFlag(rootCmd, globalFlags)
 
 	migrateCmd := migrate.NewCommand(globalFlags)
 	rootCmd.AddCommand(migrateCmd)
@@ -94,6 +101,9 @@ func NewUyuniadmCommand() (*cobra.Command, error) {
 	rootCmd.AddCommand(inspect.NewCommand(globalFlags))
 	rootCmd.AddCommand(upgrade.NewCommand(globalFlags))
 	rootCmd.AddCommand(gpg.NewCommand(globalFlags))
+	rootCmd.AddCommand(backup.NewCommand(globalFlags))
+	rootCmd.AddCommand(restore.NewCommand(globalFlags))
+	rootCmd.AddCommand(volumes.NewCommand(globalFlags))
 
 	rootCmd.AddCommand(utils.GetConfigHelpCommand())
 
@@ -14,6 +14,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/uyuni-project/uyuni-tools/shared"
 	"github.com/uyuni-project/uyuni-tools/shared/api"
+	kickstart_tree "github.com/uyuni-project/uyuni-tools/shared/api/kickstart/tree"
+	api_types "github.com/uyuni-project/uyuni-tools/shared/api/types"
 	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/podman"
@@ -37,25 +39,23 @@ func umountAndRemove(mountpoint string) {
 }
 
 func registerDistro(connection *api.ConnectionDetails, distro *types.Distribution, flags *flagpole) error {
-	// Fill server FQDN if not provided, ignore error, will be handled later
-	if flags.ConnectionDetails.Server == "" {
-		flags.ConnectionDetails.Server, _ = getServerFqdn(flags)
-		log.Debug().Msgf("Using api-server FQDN '%s'", flags.ConnectionDetails.Server)
+	// Fill server FQDN and CA if not provided, the error will be handled later
+	cnx := shared.NewConnection(flags.Backend, podman.ServerContainerName, kubernetes.ServerFilter)
+	if err := api.DiscoverLocalServer(&flags.ConnectionDetails, cnx); err != nil {
+		log.Debug().Err(err).Msg("Failed to discover the local server")
 	}
 
+	tree := api_types.CreateKickstartTreeRequest{
+		TreeLabel:    distro.TreeLabel,
+		BasePath:     distro.BasePath,
+		ChannelLabel: distro.ChannelLabel,
+		InstallType:  distro.InstallType,
+	}
 	client, err := api.Init(connection)
 	if err != nil {
 		return utils.Errorf(err, L("unable to login and register the distribution. Manual distro registration is required"))
 	}
-	data := map[string]interface{}{
-		"treeLabel":    distro.TreeLabel,
-		"basePath":     distro.BasePath,
-		"channelLabel": distro.ChannelLabel,
-		"installType":  distro.InstallType,
-	}
-
-	_, err = client.Post("kickstart/tree/create", data)
-	if err != nil {
+	if err := kickstart_tree.Create(client, &tree); err != nil {
 		return utils.Errorf(err, L("unable to register the distribution. Manual distro registration is required"))
 	}
 	log.Info().Msgf(L("Distribution %s successfully registered"), distro.TreeLabel)
@@ -119,12 +119,6 @@ func copyDistro(srcdir string, distro *types.Distribution, flags *flagpole) erro
 	return nil
 }
 
-func getServerFqdn(flags *flagpole) (string, error) {
-	cnx := shared.NewConnection(flags.Backend, podman.ServerContainerName, kubernetes.ServerFilter)
-	fqdn, err := cnx.Exec("sh", "-c", "cat /etc/rhn/rhn.conf 2>/dev/null | grep 'java.hostname' | cut -d' ' -f3")
-	return strings.TrimSuffix(string(fqdn), "\n"), err
-}
-
 func distroCp(
 	globalFlags *types.GlobalFlags,
 	flags *flagpole,
@@ -12,6 +12,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/uyuni-project/uyuni-tools/shared"
 	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/system"
+	api_types "github.com/uyuni-project/uyuni-tools/shared/api/types"
 	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/podman"
@@ -98,35 +100,22 @@ func registerToHub(config map[string]string, cnxDetails *api.ConnectionDetails)
 	if err != nil {
 		return utils.Errorf(err, L("failed to connect to the Hub server"))
 	}
-	data := map[string]interface{}{
-		"fqdn": config["java.hostname"],
-	}
-
-	ret, err := api.Post[int](client, "system/registerPeripheralServer", data)
+	id, err := system.RegisterPeripheralServer(client, config["java.hostname"])
 	if err != nil {
 		return utils.Errorf(err, L("failed to register this peripheral server"))
 	}
-	if !ret.Success {
-		return fmt.Errorf(L("failed to register this peripheral server: %s"), ret.Message)
-	}
-	id := ret.Result
-
-	data = map[string]interface{}{
-		"sid":              id,
-		"reportDbName":     config["report_db_name"],
-		"reportDbHost":     config["java.hostname"],
-		"reportDbPort":     config["report_db_port"],
-		"reportDbUser":     config["report_db_user"],
-		"reportDbPassword": config["report_db_password"],
+
+	info := api_types.PeripheralServerInfoRequest{
+		Sid:              id,
+		ReportDbName:     config["report_db_name"],
+		ReportDbHost:     config["java.hostname"],
+		ReportDbPort:     config["report_db_port"],
+		ReportDbUser:     config["report_db_user"],
+		ReportDbPassword: config["report_db_password"],
 	}
-	ret, err = api.Post[int](client, "system/updatePeripheralServerInfo", data)
-	if err != nil {
+	if err := system.UpdatePeripheralServerInfo(client, &info); err != nil {
 		return utils.Errorf(err, L("failed to update peripheral server info"))
 	}
-
-	if !ret.Success {
-		return fmt.Errorf(L("failed to update peripheral server info: %s"), ret.Message)
-	}
 	log.Info().Msgf(L("Registered peripheral server: %[1]s, ID: %[2]d"), config["java.hostname"], id)
 	return nil
 }
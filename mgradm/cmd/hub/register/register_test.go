@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package register
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	api_types "github.com/uyuni-project/uyuni-tools/shared/api/types"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestRegisterToHub(t *testing.T) {
+	// Make sure no stored session is used
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	server.Handle("system/registerPeripheralServer", func(call *test_utils.APICall) (interface{}, error) {
+		var data map[string]string
+		if err := call.Decode(&data); err != nil {
+			t.Errorf("Failed to decode payload: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong peripheral FQDN", "peripheral.lab", data["fqdn"])
+		return 1000010000, nil
+	})
+	server.HandleResult("system/updatePeripheralServerInfo", 1)
+
+	config := map[string]string{
+		"java.hostname":      "peripheral.lab",
+		"report_db_name":     "reportdb",
+		"report_db_port":     "5432",
+		"report_db_user":     "pythia",
+		"report_db_password": "reportpass",
+	}
+	cnxDetails := api.ConnectionDetails{Server: server.Host(), User: "admin", Password: "secret", Insecure: true}
+	if err := registerToHub(config, &cnxDetails); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	calls := server.Calls("system/updatePeripheralServerInfo")
+	test_utils.AssertEquals(t, "peripheral info not updated", 1, len(calls))
+	var info api_types.PeripheralServerInfoRequest
+	if err := calls[0].Decode(&info); err != nil {
+		t.Fatalf("Failed to decode payload: %s", err)
+	}
+	expected := api_types.PeripheralServerInfoRequest{
+		Sid:              1000010000,
+		ReportDbName:     "reportdb",
+		ReportDbHost:     "peripheral.lab",
+		ReportDbPort:     "5432",
+		ReportDbUser:     "pythia",
+		ReportDbPassword: "reportpass",
+	}
+	test_utils.AssertEquals(t, "wrong peripheral info", expected, info)
+
+	delete(config, "report_db_user")
+	if err := registerToHub(config, &cnxDetails); err == nil {
+		t.Error("Expected an error for a missing configuration entry")
+	}
+}
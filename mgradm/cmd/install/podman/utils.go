@@ -25,7 +25,9 @@ import (
 )
 
 func waitForSystemStart(cnx *shared.Connection, image string, flags *podmanInstallFlags) error {
-	err := podman.GenerateSystemdService(flags.TZ, image, flags.Debug.Java, flags.Mirror, flags.Podman.Args)
+	err := podman.GenerateSystemdService(
+		flags.TZ, image, flags.Debug.Java, flags.Mirror, flags.Podman.Args, flags.Podman.PortsOffset,
+	)
 	if err != nil {
 		return err
 	}
@@ -145,5 +147,9 @@ func installForPodman(
 	if err := shared_podman.EnablePodmanSocket(); err != nil {
 		return utils.Errorf(err, L("cannot enable podman socket"))
 	}
+
+	if err := podman.RecordInstallation(); err != nil {
+		log.Warn().Err(err).Msg(L("failed to record the deployment"))
+	}
 	return nil
 }
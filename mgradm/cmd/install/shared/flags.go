@@ -134,23 +134,27 @@ func AddInstallFlags(cmd *cobra.Command) {
 
 	cmd.Flags().String("db-user", "spacewalk", L("Database user"))
 	cmd.Flags().String("db-password", "", L("Database password. Randomly generated by default"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "db-password")
 	cmd.Flags().String("db-name", "susemanager", L("Database name"))
 	cmd.Flags().String("db-host", "localhost", L("Database host"))
 	cmd.Flags().Int("db-port", 5432, L("Database port"))
 	cmd.Flags().String("db-protocol", "tcp", L("Database protocol"))
 	cmd.Flags().String("db-admin-user", "", L("External database admin user name"))
 	cmd.Flags().String("db-admin-password", "", L("External database admin password"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "db-admin-password")
 	cmd.Flags().String("db-provider", "", L("External database provider. Possible values 'aws'"))
 
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "db", Title: L("Database Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-user", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-password", "db")
+	_ = utils.AddFlagToHelpGroupID(cmd, "db-password-file", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-name", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-host", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-port", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-protocol", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-admin-user", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-admin-password", "db")
+	_ = utils.AddFlagToHelpGroupID(cmd, "db-admin-password-file", "db")
 	_ = utils.AddFlagToHelpGroupID(cmd, "db-provider", "db")
 
 	cmd.Flags().Bool("tftp", true, L("Enable TFTP"))
@@ -159,6 +163,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 	cmd.Flags().Int("reportdb-port", 5432, L("Report database port"))
 	cmd.Flags().String("reportdb-user", "pythia_susemanager", L("Report Database username"))
 	cmd.Flags().String("reportdb-password", "", L("Report database password. Randomly generated by default"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "reportdb-password")
 
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "reportdb", Title: L("Report DB Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "reportdb-name", "reportdb")
@@ -166,6 +171,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 	_ = utils.AddFlagToHelpGroupID(cmd, "reportdb-port", "reportdb")
 	_ = utils.AddFlagToHelpGroupID(cmd, "reportdb-user", "reportdb")
 	_ = utils.AddFlagToHelpGroupID(cmd, "reportdb-password", "reportdb")
+	_ = utils.AddFlagToHelpGroupID(cmd, "reportdb-password-file", "reportdb")
 
 	// For generated CA and certificate
 	cmd.Flags().StringSlice("ssl-cname", []string{}, L("SSL certificate cnames separated by commas"))
@@ -175,6 +181,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 	cmd.Flags().String("ssl-org", "SUSE", L("SSL certificate organization"))
 	cmd.Flags().String("ssl-ou", "SUSE", L("SSL certificate organization unit"))
 	cmd.Flags().String("ssl-password", "", L("Password for the CA key to generate"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "ssl-password")
 
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "ssl", Title: L("SSL Certificate Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "ssl-cname", "ssl")
@@ -184,6 +191,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 	_ = utils.AddFlagToHelpGroupID(cmd, "ssl-org", "ssl")
 	_ = utils.AddFlagToHelpGroupID(cmd, "ssl-ou", "ssl")
 	_ = utils.AddFlagToHelpGroupID(cmd, "ssl-password", "ssl")
+	_ = utils.AddFlagToHelpGroupID(cmd, "ssl-password-file", "ssl")
 
 	// For SSL 3rd party certificates
 	cmd.Flags().StringSlice("ssl-ca-intermediate", []string{}, L("Intermediate CA certificate path"))
@@ -208,6 +216,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 
 	cmd.Flags().String("admin-login", "admin", L("Administrator user name"))
 	cmd.Flags().String("admin-password", "", L("Administrator password"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "admin-password")
 	cmd.Flags().String("admin-firstName", "Administrator", L("First name of the administrator"))
 	cmd.Flags().String("admin-lastName", "McAdmin", L("Last name of the administrator"))
 	cmd.Flags().String("organization", "Organization", L("First organization name"))
@@ -215,6 +224,7 @@ func AddInstallFlags(cmd *cobra.Command) {
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "first-user", Title: L("First User Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "admin-login", "first-user")
 	_ = utils.AddFlagToHelpGroupID(cmd, "admin-password", "first-user")
+	_ = utils.AddFlagToHelpGroupID(cmd, "admin-password-file", "first-user")
 	_ = utils.AddFlagToHelpGroupID(cmd, "admin-firstName", "first-user")
 	_ = utils.AddFlagToHelpGroupID(cmd, "admin-lastName", "first-user")
 	_ = utils.AddFlagToHelpGroupID(cmd, "organization", "first-user")
@@ -52,44 +52,49 @@ func RunSetup(cnx *shared.Connection, flags *InstallFlags, fqdn string, env map[
 	// Call the org.createFirst api if flags are passed
 	// This should not happen since the password is queried and enforced
 	if flags.Admin.Password != "" {
-		apiCnx := api.ConnectionDetails{
-			Server:   fqdn,
-			Insecure: false,
-			User:     flags.Admin.Login,
-			Password: flags.Admin.Password,
+		serverCnx := api.ConnectionDetails{Server: fqdn, Retries: api.DefaultRetries}
+		if err := createFirstOrg(serverCnx, flags, preconfigured); err != nil {
+			return err
 		}
+	}
 
-		// Check if there is already admin user with given password and organization with same name
-		if _, err := api.Init(&apiCnx); err == nil {
-			if _, err := org.GetOrganizationDetails(&apiCnx, flags.Organization); err == nil {
-				log.Info().Msgf(L("Server organization already exists, reusing"))
-			} else {
-				log.Debug().Err(err).Msg("Error returned by server")
-				log.Warn().Msgf(L("Administration user already exists, but organization %s could not be found"), flags.Organization)
-			}
+	log.Info().Msgf(L("Server set up, login on https://%[1]s with %[2]s user"), fqdn, flags.Admin.Login)
+	return nil
+}
+
+// createFirstOrg creates the first organization and administrator unless the administrator can already login.
+//
+// serverCnx holds the connection details to the server, without any credentials.
+func createFirstOrg(serverCnx api.ConnectionDetails, flags *InstallFlags, preconfigured bool) error {
+	apiCnx := serverCnx
+	apiCnx.User = flags.Admin.Login
+	apiCnx.Password = flags.Admin.Password
+
+	// Check if there is already admin user with given password and organization with same name
+	if _, err := api.Init(&apiCnx); err == nil {
+		if _, err := org.GetOrganizationDetails(&apiCnx, flags.Organization); err == nil {
+			log.Info().Msgf(L("Server organization already exists, reusing"))
 		} else {
-			var connError *url.Error
-			if errors.As(err, &connError) {
-				// We were not able to connect to the server at all
+			log.Debug().Err(err).Msg("Error returned by server")
+			log.Warn().Msgf(L("Administration user already exists, but organization %s could not be found"), flags.Organization)
+		}
+	} else {
+		var connError *url.Error
+		if errors.As(err, &connError) {
+			// We were not able to connect to the server at all
+			return err
+		}
+		// We do not have any user existing, do not try to login
+		apiCnx = serverCnx
+		_, err := org.CreateFirst(&apiCnx, flags.Organization, &flags.Admin)
+		if err != nil {
+			if preconfigured {
+				log.Warn().Msgf(L("Administration user already exists, but provided credentials are not valid"))
+			} else {
 				return err
 			}
-			// We do not have any user existing, do not try to login
-			apiCnx = api.ConnectionDetails{
-				Server:   fqdn,
-				Insecure: false,
-			}
-			_, err := org.CreateFirst(&apiCnx, flags.Organization, &flags.Admin)
-			if err != nil {
-				if preconfigured {
-					log.Warn().Msgf(L("Administration user already exists, but provided credentials are not valid"))
-				} else {
-					return err
-				}
-			}
 		}
 	}
-
-	log.Info().Msgf(L("Server set up, login on https://%[1]s with %[2]s user"), fqdn, flags.Admin.Login)
 	return nil
 }
 
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	apiTypes "github.com/uyuni-project/uyuni-tools/shared/api/types"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestCreateFirstOrg(t *testing.T) {
+	// Make sure no stored session is used
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	// No user can login before the first organization is created
+	server := test_utils.NewFakeAPIServer(t, "", "")
+	server.HandlePublic("org/createFirst", func(call *test_utils.APICall) (interface{}, error) {
+		var data map[string]string
+		if err := call.Decode(&data); err != nil {
+			t.Errorf("Failed to decode payload: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong organization", "MyOrg", data["orgName"])
+		test_utils.AssertEquals(t, "wrong admin login", "admin", data["adminLogin"])
+		test_utils.AssertEquals(t, "wrong admin password", "secret", data["adminPassword"])
+		test_utils.AssertEquals(t, "wrong admin email", "admin@server.lab", data["email"])
+		server.User = data["adminLogin"]
+		server.Password = data["adminPassword"]
+		return map[string]interface{}{"id": 1, "name": data["orgName"]}, nil
+	})
+	server.Handle("org/getDetails", func(call *test_utils.APICall) (interface{}, error) {
+		test_utils.AssertEquals(t, "wrong organization name", "MyOrg", call.Query.Get("name"))
+		return map[string]interface{}{"id": 1, "name": "MyOrg"}, nil
+	})
+
+	flags := InstallFlags{
+		Organization: "MyOrg",
+		Admin: apiTypes.User{
+			Login:     "admin",
+			Password:  "secret",
+			FirstName: "Admin",
+			LastName:  "Admin",
+			Email:     "admin@server.lab",
+		},
+	}
+	serverCnx := api.ConnectionDetails{Server: server.Host(), Insecure: true}
+	if err := createFirstOrg(serverCnx, &flags, false); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "first organization not created", 1, len(server.Calls("org/createFirst")))
+
+	// Running again reuses the existing organization
+	if err := createFirstOrg(serverCnx, &flags, true); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "first organization created twice", 1, len(server.Calls("org/createFirst")))
+	test_utils.AssertEquals(t, "organization not checked", 1, len(server.Calls("org/getDetails")))
+
+	// Failing to create the organization is an error on a new server
+	flags.Admin.Password = "wrong"
+	server.HandlePublic("org/createFirst", func(*test_utils.APICall) (interface{}, error) {
+		return nil, &test_utils.APIError{Status: 500, Message: "Organization already exists"}
+	})
+	if err := createFirstOrg(serverCnx, &flags, false); err == nil {
+		t.Error("Expected an error when the organization cannot be created")
+	}
+	if err := createFirstOrg(serverCnx, &flags, true); err != nil {
+		t.Errorf("Unexpected error on a preconfigured server: %s", err)
+	}
+}
@@ -39,6 +39,11 @@ When migrating a server with a automatically generated SSL Root CA certificate,
 password will be required to convert it to RSA in a kubernetes secret.
 This is not needed if the source server does not have a generated SSL CA certificate.
 
+Before stopping anything, pre-flight checks verify that the source server can be migrated:
+sudo and rsync availability, product version and PostgreSQL migration image.
+The server image is run on the cluster to get its PostgreSQL version.
+Use --check-only to only run those checks and print their report.
+
 NOTE: migrating to a remote cluster is not supported yet!
 `),
 		Args: cobra.ExactArgs(1),
@@ -51,6 +56,7 @@ NOTE: migrating to a remote cluster is not supported yet!
 	shared.AddMigrateFlags(migrateCmd)
 	cmd_utils.AddHelmInstallFlag(migrateCmd)
 	migrateCmd.Flags().String("ssl-password", "", L("SSL CA generated private key password"))
+	utils.AddPasswordFileFlag(migrateCmd.Flags(), "ssl-password")
 
 	return migrateCmd
 }
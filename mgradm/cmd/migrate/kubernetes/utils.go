@@ -9,7 +9,6 @@ package kubernetes
 import (
 	"encoding/base64"
 	"fmt"
-	"os"
 	"os/exec"
 	"path"
 
@@ -32,7 +31,7 @@ func migrateToKubernetes(
 	flags *kubernetesMigrateFlags,
 	cmd *cobra.Command,
 	args []string,
-) error {
+) (err error) {
 	for _, binary := range []string{"kubectl", "helm"} {
 		if _, err := exec.LookPath(binary); err != nil {
 			return fmt.Errorf(L("install %s before running this command"), binary)
@@ -50,17 +49,48 @@ func migrateToKubernetes(
 		return err
 	}
 
+	imagePgVersion, inspectErr := kubernetes.InspectImagePgVersion(serverImage, flags.Image.PullPolicy)
+	if inspectErr != nil {
+		log.Warn().Err(inspectErr).Send()
+	}
+
+	// The volumes are claimed when deploying the helm chart: unlike on podman,
+	// they can't be inspected before the migration starts.
+	target := migration_shared.PreflightTarget{
+		ImagePgVersion: imagePgVersion,
+		AvailableSpace: -1,
+		MigrationImage: func(oldPgsql string, newPgsql string) (string, bool, error) {
+			image, err := kubernetes.ComputeMigrationImage(
+				globalFlags.Registry, flags.Image, flags.DbUpgradeImage, oldPgsql, newPgsql,
+			)
+			if err != nil {
+				return image, false, err
+			}
+			found, err := utils.IsImageInRegistry(image)
+			return image, found, err
+		},
+		DeployedOnly: L("the volumes are only available on the cluster once the server is deployed"),
+	}
+	if done, err := migration_shared.Preflight(fqdn, &flags.MigrateFlags, target); done || err != nil {
+		return err
+	}
+
 	// Find the SSH Socket and paths for the migration
-	sshAuthSocket := migration_shared.GetSshAuthSocket()
+	sshAuthSocket, err := migration_shared.GetSshAuthSocket()
+	if err != nil {
+		return err
+	}
 	sshConfigPath, sshKnownhostsPath := migration_shared.GetSshPaths()
 
 	// Prepare the migration script and folder
-	scriptDir, err := adm_utils.GenerateMigrationScript(fqdn, flags.User, true, flags.Prepare)
+	scriptDir, err := adm_utils.GenerateMigrationScript(fqdn, flags.User, true, flags.Prepare, flags.Resume)
 	if err != nil {
 		return utils.Errorf(err, L("failed to generate migration script"))
 	}
 
-	defer os.RemoveAll(scriptDir)
+	defer func() {
+		adm_utils.CleanMigrationData(scriptDir, err)
+	}()
 
 	// We don't need the SSL certs at this point of the migration
 	clusterInfos, err := shared_kubernetes.CheckCluster()
@@ -86,7 +116,7 @@ func migrateToKubernetes(
 
 	//this is needed because folder with script needs to be mounted
 	//check the node before scaling down
-	nodeName, err := shared_kubernetes.GetNode("uyuni")
+	nodeName, err := shared_kubernetes.GetNode("", "uyuni")
 	if err != nil {
 		return utils.Errorf(err, L("cannot find node running uyuni"))
 	}
@@ -101,7 +131,7 @@ func migrateToKubernetes(
 	}
 
 	// After each command we want to scale to 0
-	err = shared_kubernetes.ReplicasTo(shared_kubernetes.ServerApp, 0)
+	err = shared_kubernetes.ReplicasTo("", shared_kubernetes.ServerApp, 0)
 	if err != nil {
 		return utils.Errorf(err, L("cannot set replicas to 0"))
 	}
@@ -113,8 +143,10 @@ func migrateToKubernetes(
 
 	defer func() {
 		// if something is running, we don't need to set replicas to 1
-		if _, err = shared_kubernetes.GetNode("uyuni"); err != nil {
-			err = shared_kubernetes.ReplicasTo(shared_kubernetes.ServerApp, 1)
+		if _, nodeErr := shared_kubernetes.GetNode("", "uyuni"); nodeErr != nil {
+			if replicasErr := shared_kubernetes.ReplicasTo("", shared_kubernetes.ServerApp, 1); replicasErr != nil {
+				log.Error().Err(replicasErr).Msg(L("cannot set replicas to 1"))
+			}
 		}
 	}()
 
@@ -144,7 +176,7 @@ func migrateToKubernetes(
 		return utils.Errorf(err, L("cannot wait for deployment of %s"), serverImage)
 	}
 
-	err = shared_kubernetes.ReplicasTo(shared_kubernetes.ServerApp, 0)
+	err = shared_kubernetes.ReplicasTo("", shared_kubernetes.ServerApp, 0)
 	if err != nil {
 		return utils.Errorf(err, L("cannot set replicas to 0"))
 	}
@@ -32,6 +32,10 @@ This migration command assumes a few things:
   * an SSH agent is started and the key to use to connect to the server is added to it,
   * podman is installed locally
 
+Before stopping anything, pre-flight checks verify that the source server can be migrated:
+sudo and rsync availability, product version, PostgreSQL migration image and volumes space.
+Use --check-only to only run those checks and print their report.
+
 NOTE: migrating to a remote podman is not supported yet!
 `),
 		Args: cobra.ExactArgs(1),
@@ -43,6 +47,7 @@ NOTE: migrating to a remote podman is not supported yet!
 
 	shared.AddMigrateFlags(migrateCmd)
 	podman_utils.AddPodmanArgFlag(migrateCmd)
+	podman_utils.AddPortsOffsetFlag(migrateCmd)
 
 	return migrateCmd
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/uyuni-project/uyuni-tools/mgradm/shared/coco"
 	"github.com/uyuni-project/uyuni-tools/mgradm/shared/hub"
 	"github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
 	"github.com/uyuni-project/uyuni-tools/shared"
 	podman_utils "github.com/uyuni-project/uyuni-tools/shared/podman"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
@@ -23,7 +24,12 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
-func migrateToPodman(globalFlags *types.GlobalFlags, flags *podmanMigrateFlags, cmd *cobra.Command, args []string) error {
+func migrateToPodman(
+	globalFlags *types.GlobalFlags,
+	flags *podmanMigrateFlags,
+	cmd *cobra.Command,
+	args []string,
+) (err error) {
 	if _, err := exec.LookPath("podman"); err != nil {
 		return fmt.Errorf(L("install podman before running this command"))
 	}
@@ -53,12 +59,43 @@ func migrateToPodman(globalFlags *types.GlobalFlags, flags *podmanMigrateFlags,
 		return err
 	}
 
+	imageData, err := podman.InspectImage(preparedImage)
+	if err != nil {
+		return err
+	}
+	target := migration_shared.PreflightTarget{
+		ImagePgVersion: imageData.ImagePgVersion,
+		AvailableSpace: podman.VolumesAvailableSpace(),
+		VolumeUsage:    podman.VolumeUsage,
+		MigrationImage: func(oldPgsql string, newPgsql string) (string, bool, error) {
+			image, err := podman.ComputeMigrationImage(
+				globalFlags.Registry, flags.Image, flags.DbUpgradeImage, oldPgsql, newPgsql,
+			)
+			if err != nil {
+				return image, false, err
+			}
+			found, err := podman_utils.IsImageAvailable(authFile, image)
+			return image, found, err
+		},
+	}
+	if done, err := migration_shared.Preflight(sourceFqdn, &flags.MigrateFlags, target); done || err != nil {
+		return err
+	}
+
 	// Find the SSH Socket and paths for the migration
-	sshAuthSocket := migration_shared.GetSshAuthSocket()
+	sshAuthSocket, err := migration_shared.GetSshAuthSocket()
+	if err != nil {
+		return err
+	}
 	sshConfigPath, sshKnownhostsPath := migration_shared.GetSshPaths()
 
+	defer func() {
+		adm_utils.CleanMigrationData(adm_utils.MigrationDataDir(), err)
+	}()
+
 	extractedData, err := podman.RunMigration(
 		preparedImage, sshAuthSocket, sshConfigPath, sshKnownhostsPath, sourceFqdn, flags.User, flags.Prepare,
+		flags.Resume,
 	)
 	if err != nil {
 		return utils.Errorf(err, L("cannot run migration script"))
@@ -90,6 +127,7 @@ func migrateToPodman(globalFlags *types.GlobalFlags, flags *podmanMigrateFlags,
 
 	if err := podman.GenerateSystemdService(
 		extractedData.Timezone, preparedImage, false, flags.Mirror, viper.GetStringSlice("podman.arg"),
+		flags.Podman.PortsOffset,
 	); err != nil {
 		return utils.Errorf(err, L("cannot generate systemd service file"))
 	}
@@ -15,17 +15,23 @@ import (
 // MigrateFlags represents flag required by migration command.
 type MigrateFlags struct {
 	Prepare        bool
+	Resume         bool
 	Image          types.ImageFlags `mapstructure:",squash"`
 	DbUpgradeImage types.ImageFlags `mapstructure:"dbupgrade"`
 	Coco           shared.CocoFlags
 	User           string
 	Mirror         string
 	HubXmlrpc      utils.HubXmlrpcFlags
+	Check          struct {
+		Only bool
+	}
 }
 
 // AddMigrateFlags add migration flags to a command.
 func AddMigrateFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("prepare", false, L("Prepare the mgration - copy the data without stopping the source server."))
+	cmd.Flags().Bool("check-only", false, L("Only run the pre-flight checks on the source server and print the report."))
+	cmd.Flags().Bool("resume", false, L("Resume an interrupted migration, skipping the volumes and distributions already copied."))
 	utils.AddMirrorFlag(cmd)
 	utils.AddSCCFlag(cmd)
 	utils.AddImageFlag(cmd)
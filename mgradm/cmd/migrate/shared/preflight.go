@@ -0,0 +1,356 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/templates"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Status values of the pre-flight checks.
+const (
+	CheckPassed  = "passed"
+	CheckWarning = "warning"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// Check is the result of a migration pre-flight check.
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// PreflightReport gathers the results of the migration pre-flight checks.
+type PreflightReport struct {
+	Source string  `json:"source"`
+	Checks []Check `json:"checks"`
+}
+
+// PreflightTarget provides the data of the migration target needed by the pre-flight checks.
+type PreflightTarget struct {
+	// ImagePgVersion is the PostgreSQL major version of the server image, empty if unknown.
+	ImagePgVersion string
+	// AvailableSpace is the space available for the server volumes in bytes, negative if unknown.
+	AvailableSpace int64
+	// VolumeUsage returns the space already used by a volume created by a previous run.
+	VolumeUsage func(volume string) int64
+	// MigrationImage returns the name of the database migration image and whether it is available.
+	MigrationImage func(oldPgsql string, newPgsql string) (string, bool, error)
+	// DeployedOnly explains why the volumes data are only known once the server is deployed.
+	// The space check is then skipped with this reason.
+	DeployedOnly string
+}
+
+// SourceInspectData are the data gathered on the source server by the pre-flight checks.
+type SourceInspectData struct {
+	UyuniRelease       string `mapstructure:"uyuni_release"`
+	SuseManagerRelease string `mapstructure:"suse_manager_release"`
+	CurrentPgVersion   string `mapstructure:"current_pg_version"`
+	HasRsync           bool   `mapstructure:"has_rsync"`
+	VolumesSize        string `mapstructure:"volumes_size"`
+}
+
+// Failed returns whether at least one check failed.
+func (r *PreflightReport) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Log writes the results of the checks to the log.
+func (r *PreflightReport) Log() {
+	for _, check := range r.Checks {
+		event := log.Info()
+		switch check.Status {
+		case CheckFailed:
+			event = log.Error()
+		case CheckWarning, CheckSkipped:
+			event = log.Warn()
+		}
+		event.Msgf("[%s] %s: %s", check.Status, check.Name, check.Message)
+	}
+}
+
+// Print writes the report as JSON on the standard output.
+func (r *PreflightReport) Print() error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the pre-flight report"))
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func (r *PreflightReport) add(name string, status string, message string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: message})
+}
+
+// Preflight runs the pre-flight checks and logs their results.
+//
+// If only the checks are requested, the report is printed and done is true.
+// An error is returned if any of the checks failed.
+func Preflight(sourceFqdn string, flags *MigrateFlags, target PreflightTarget) (done bool, err error) {
+	report := RunPreflightChecks(sourceFqdn, flags.User, target)
+	report.Log()
+	if flags.Check.Only {
+		if err := report.Print(); err != nil {
+			return true, err
+		}
+	}
+	if report.Failed() {
+		return true, errors.New(L("the migration pre-flight checks failed"))
+	}
+	return flags.Check.Only, nil
+}
+
+// RunPreflightChecks verifies that the source server can be migrated without stopping any of its services.
+//
+// The SSH agent and configuration used for the migration are used to connect to the source server.
+func RunPreflightChecks(sourceFqdn string, user string, target PreflightTarget) *PreflightReport {
+	report := PreflightReport{Source: sourceFqdn, Checks: []Check{}}
+	log.Info().Msgf(L("Running pre-flight checks on %s…"), sourceFqdn)
+
+	if _, err := GetSshAuthSocket(); err != nil {
+		report.add("ssh-agent", CheckFailed, err.Error())
+		return &report
+	}
+	sshArgs := getSshArgs(sourceFqdn, user)
+	if out, err := exec.Command("ssh", append(sshArgs, "sudo", "-n", "true")...).CombinedOutput(); err != nil {
+		report.add("sudo", CheckFailed,
+			fmt.Sprintf(L("cannot run passwordless sudo as %[1]s: %[2]s"), user, strings.TrimSpace(string(out))))
+		return &report
+	}
+	report.add("sudo", CheckPassed, fmt.Sprintf(L("passwordless sudo available for %s"), user))
+
+	data, err := inspectSource(sshArgs)
+	if err != nil {
+		report.add("inspect", CheckFailed, err.Error())
+		return &report
+	}
+
+	if data.HasRsync {
+		report.add("rsync", CheckPassed, L("rsync is installed"))
+	} else {
+		report.add("rsync", CheckFailed, L("rsync is not installed on the source server"))
+	}
+
+	report.Checks = append(report.Checks, checkProduct(data.UyuniRelease, data.SuseManagerRelease))
+	report.Checks = append(report.Checks, checkPgVersion(data.CurrentPgVersion, target)...)
+	report.Checks = append(report.Checks, checkSpace(parseVolumesSize(data.VolumesSize), target)...)
+	return &report
+}
+
+func getSshArgs(sourceFqdn string, user string) []string {
+	args := []string{"-o", "User=" + user, "-o", "BatchMode=yes"}
+	sshConfigPath, sshKnownhostsPath := GetSshPaths()
+	if sshConfigPath != "" {
+		args = append(args, "-F", sshConfigPath)
+	}
+	if sshKnownhostsPath != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+sshKnownhostsPath)
+	}
+	return append(args, sourceFqdn)
+}
+
+// inspectSource runs an inspection script on the source server through SSH.
+func inspectSource(sshArgs []string) (*SourceInspectData, error) {
+	folders := []string{}
+	for _, volume := range utils.ServerVolumeMounts {
+		if !utils.Contains(folders, volume.MountPath) {
+			folders = append(folders, volume.MountPath)
+		}
+	}
+
+	var script bytes.Buffer
+	data := templates.InspectTemplateData{
+		Param: []types.InspectData{
+			types.NewInspectData("uyuni_release",
+				"cat /etc/*release | grep 'Uyuni release' | cut -d ' ' -f3 || true"),
+			types.NewInspectData("suse_manager_release",
+				"cat /etc/*release | grep 'SUSE Manager release' | cut -d ' ' -f4 || true"),
+			types.NewInspectData("current_pg_version",
+				"(test -e /var/lib/pgsql/data/PG_VERSION && cat /var/lib/pgsql/data/PG_VERSION) || true"),
+			types.NewInspectData("has_rsync",
+				"command -v rsync >/dev/null && echo true || echo false"),
+			types.NewInspectData("volumes_size",
+				"du -s -x -B1 "+strings.Join(folders, " ")+" 2>/dev/null | awk '{printf \"%s:%s,\", $2, $1}' || true"),
+		},
+		OutputFile: "/dev/stdout",
+	}
+	if err := data.Render(&script); err != nil {
+		return nil, utils.Errorf(err, L("failed to generate inspect script"))
+	}
+
+	log.Info().Msg(L("Inspecting the source server, computing the size of the folders may take a while…"))
+	cmd := exec.Command("ssh", append(sshArgs, "sudo", "-n", "bash", "-s")...)
+	cmd.Stdin = &script
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to inspect the source server"))
+	}
+
+	dataDir, err := os.MkdirTemp("", "mgradm-*")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to create temporary directory"))
+	}
+	defer os.RemoveAll(dataDir)
+
+	dataPath := path.Join(dataDir, "data")
+	if err := os.WriteFile(dataPath, out, 0600); err != nil {
+		return nil, utils.Errorf(err, L("cannot write %s file"), dataPath)
+	}
+	return utils.ReadInspectData[SourceInspectData](dataPath)
+}
+
+// checkProduct verifies that the source server product and version can be migrated.
+func checkProduct(uyuniRelease string, suseManagerRelease string) Check {
+	check := Check{Name: "product"}
+	switch {
+	case suseManagerRelease != "":
+		if strings.HasPrefix(suseManagerRelease, "4.3") {
+			check.Status = CheckPassed
+			check.Message = fmt.Sprintf(L("SUSE Manager %s is supported"), suseManagerRelease)
+		} else {
+			check.Status = CheckFailed
+			check.Message = fmt.Sprintf(L("SUSE Manager %s is not supported, upgrade to 4.3 first"), suseManagerRelease)
+		}
+	case uyuniRelease != "":
+		check.Status = CheckPassed
+		check.Message = fmt.Sprintf(L("Uyuni %s is supported"), uyuniRelease)
+	default:
+		check.Status = CheckFailed
+		check.Message = L("the source server is neither Uyuni nor SUSE Manager")
+	}
+	return check
+}
+
+// checkPgVersion verifies that the source database can be migrated to the PostgreSQL version of the image.
+func checkPgVersion(sourcePgVersion string, target PreflightTarget) []Check {
+	if sourcePgVersion == "" {
+		return []Check{{Name: "postgresql", Status: CheckFailed,
+			Message: L("cannot find the PostgreSQL version of the source server")}}
+	}
+	if target.ImagePgVersion == "" {
+		return []Check{{Name: "postgresql", Status: CheckSkipped,
+			Message: fmt.Sprintf(L("source has PostgreSQL %s, the image PostgreSQL version is unknown"), sourcePgVersion)}}
+	}
+
+	check := Check{Name: "postgresql"}
+	oldVersion, oldErr := strconv.Atoi(sourcePgVersion)
+	newVersion, newErr := strconv.Atoi(target.ImagePgVersion)
+	switch {
+	case sourcePgVersion == target.ImagePgVersion:
+		check.Status = CheckPassed
+		check.Message = fmt.Sprintf(L("source and image both have PostgreSQL %s"), sourcePgVersion)
+	case oldErr == nil && newErr == nil && oldVersion > newVersion:
+		check.Status = CheckFailed
+		check.Message = fmt.Sprintf(L("source has PostgreSQL %[1]s which is newer than the image PostgreSQL %[2]s"),
+			sourcePgVersion, target.ImagePgVersion)
+	case target.MigrationImage == nil:
+		check.Status = CheckSkipped
+		check.Message = fmt.Sprintf(L("cannot check the PostgreSQL %[1]s to %[2]s migration image"),
+			sourcePgVersion, target.ImagePgVersion)
+	default:
+		image, found, err := target.MigrationImage(sourcePgVersion, target.ImagePgVersion)
+		if err != nil {
+			check.Status = CheckWarning
+			check.Message = fmt.Sprintf(L("cannot check the migration image %[1]s: %[2]s"), image, err)
+		} else if !found {
+			check.Status = CheckFailed
+			check.Message = fmt.Sprintf(L("migration image %s is not available"), image)
+		} else {
+			check.Status = CheckPassed
+			check.Message = fmt.Sprintf(L("migration image %s is available"), image)
+		}
+	}
+	return []Check{check}
+}
+
+// checkSpace verifies that the source folders fit in the space available for the volumes.
+func checkSpace(sizes map[string]int64, target PreflightTarget) []Check {
+	checks := []Check{}
+	if target.AvailableSpace < 0 {
+		message := L("cannot compute the space available for the volumes")
+		if target.DeployedOnly != "" {
+			message = fmt.Sprintf(L("cannot compute the space available for the volumes: %s"), target.DeployedOnly)
+		}
+		return append(checks, Check{Name: "space", Status: CheckSkipped, Message: message})
+	}
+
+	var needed int64
+	names := []string{}
+	for _, volume := range utils.ServerVolumeMounts {
+		if utils.Contains(names, volume.Name) {
+			continue
+		}
+		names = append(names, volume.Name)
+
+		size, found := sizes[strings.TrimSuffix(volume.MountPath, "/")]
+		if !found {
+			continue
+		}
+		// Data copied by a previous run doesn't need more space
+		if target.VolumeUsage != nil {
+			size -= target.VolumeUsage(volume.Name)
+		}
+		if size < 0 {
+			size = 0
+		}
+		needed += size
+
+		check := Check{Name: "space:" + volume.Name, Status: CheckPassed,
+			Message: fmt.Sprintf(L("%[1]s needs %[2]d bytes"), volume.MountPath, size)}
+		if size > target.AvailableSpace {
+			check.Status = CheckFailed
+		}
+		checks = append(checks, check)
+	}
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+
+	total := Check{Name: "space", Status: CheckPassed,
+		Message: fmt.Sprintf(L("%[1]d bytes needed, %[2]d bytes available"), needed, target.AvailableSpace)}
+	if needed > target.AvailableSpace {
+		total.Status = CheckFailed
+	}
+	return append(checks, total)
+}
+
+// parseVolumesSize parses the folder:size list computed on the source server.
+func parseVolumesSize(data string) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, item := range strings.Split(data, ",") {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[strings.TrimSuffix(parts[0], "/")] = size
+	}
+	return sizes
+}
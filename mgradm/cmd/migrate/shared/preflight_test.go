@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestCheckProduct(t *testing.T) {
+	data := [][]string{
+		{"", "4.3.11", CheckPassed},
+		{"", "4.2.5", CheckFailed},
+		{"2024.05", "", CheckPassed},
+		{"", "", CheckFailed},
+	}
+
+	for i, testCase := range data {
+		check := checkProduct(testCase[0], testCase[1])
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected status", i), testCase[2], check.Status)
+	}
+}
+
+func TestCheckPgVersion(t *testing.T) {
+	available := func(oldPgsql string, newPgsql string) (string, bool, error) {
+		return "migration-" + oldPgsql + "-" + newPgsql, true, nil
+	}
+	missing := func(oldPgsql string, newPgsql string) (string, bool, error) {
+		return "migration-" + oldPgsql + "-" + newPgsql, false, nil
+	}
+	failing := func(oldPgsql string, newPgsql string) (string, bool, error) {
+		return "", false, errors.New("registry unreachable")
+	}
+
+	type testCase struct {
+		source   string
+		target   PreflightTarget
+		expected string
+	}
+	data := []testCase{
+		{"", PreflightTarget{ImagePgVersion: "16"}, CheckFailed},
+		{"14", PreflightTarget{}, CheckSkipped},
+		{"16", PreflightTarget{ImagePgVersion: "16"}, CheckPassed},
+		{"16", PreflightTarget{ImagePgVersion: "14", MigrationImage: available}, CheckFailed},
+		{"14", PreflightTarget{ImagePgVersion: "16"}, CheckSkipped},
+		{"14", PreflightTarget{ImagePgVersion: "16", MigrationImage: available}, CheckPassed},
+		{"14", PreflightTarget{ImagePgVersion: "16", MigrationImage: missing}, CheckFailed},
+		{"14", PreflightTarget{ImagePgVersion: "16", MigrationImage: failing}, CheckWarning},
+	}
+
+	for i, test := range data {
+		checks := checkPgVersion(test.source, test.target)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected number of checks", i), 1, len(checks))
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected status", i), test.expected, checks[0].Status)
+	}
+}
+
+func TestCheckSpace(t *testing.T) {
+	sizes := map[string]int64{
+		"/var/spacewalk": 1000,
+		"/srv/www":       500,
+	}
+
+	checks := checkSpace(sizes, PreflightTarget{AvailableSpace: -1})
+	test_utils.AssertEquals(t, "unknown space should be skipped", 1, len(checks))
+	test_utils.AssertEquals(t, "unknown space should be skipped", CheckSkipped, checks[0].Status)
+
+	checks = checkSpace(sizes, PreflightTarget{AvailableSpace: -1, DeployedOnly: "not deployed yet"})
+	test_utils.AssertTrue(t, "missing skip reason", strings.HasSuffix(checks[0].Message, ": not deployed yet"))
+
+	checks = checkSpace(sizes, PreflightTarget{AvailableSpace: 2000})
+	test_utils.AssertEquals(t, "unexpected number of checks", 3, len(checks))
+	for _, check := range checks {
+		test_utils.AssertEquals(t, check.Name+" should pass", CheckPassed, check.Status)
+	}
+
+	checks = checkSpace(sizes, PreflightTarget{AvailableSpace: 1200})
+	statuses := map[string]string{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	test_utils.AssertEquals(t, "var-spacewalk should fit", CheckPassed, statuses["space:var-spacewalk"])
+	test_utils.AssertEquals(t, "srv-www should fit", CheckPassed, statuses["space:srv-www"])
+	test_utils.AssertEquals(t, "total should not fit", CheckFailed, statuses["space"])
+
+	usage := func(volume string) int64 {
+		if volume == "var-spacewalk" {
+			return 800
+		}
+		return 0
+	}
+	checks = checkSpace(sizes, PreflightTarget{AvailableSpace: 1200, VolumeUsage: usage})
+	total := checks[len(checks)-1]
+	test_utils.AssertEquals(t, "already copied data should not be counted", CheckPassed, total.Status)
+}
+
+func TestParseVolumesSize(t *testing.T) {
+	sizes := parseVolumesSize("/var/spacewalk:1024,/srv/www/:2048,invalid,/var/log:abc,")
+
+	test_utils.AssertEquals(t, "unexpected number of sizes", 2, len(sizes))
+	test_utils.AssertEquals(t, "wrong /var/spacewalk size", int64(1024), sizes["/var/spacewalk"])
+	test_utils.AssertEquals(t, "wrong /srv/www size", int64(2048), sizes["/srv/www"])
+}
+
+func TestReportFailed(t *testing.T) {
+	report := PreflightReport{}
+	report.add("sudo", CheckPassed, "")
+	report.add("space", CheckSkipped, "")
+	test_utils.AssertTrue(t, "report without failure should not fail", !report.Failed())
+
+	report.add("rsync", CheckFailed, "")
+	test_utils.AssertTrue(t, "report with a failure should fail", report.Failed())
+}
+
+func TestPreflightWithoutSshAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	report := RunPreflightChecks("source.lab", "root", PreflightTarget{AvailableSpace: -1})
+	test_utils.AssertTrue(t, "missing SSH agent not reported", report.Failed())
+	test_utils.AssertEquals(t, "unexpected failed check", "ssh-agent", report.Checks[0].Name)
+}
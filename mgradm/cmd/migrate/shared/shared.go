@@ -5,6 +5,7 @@
 package shared
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 
@@ -14,12 +15,12 @@ import (
 )
 
 // GetSshAuthSocket returns the SSH_AUTH_SOCK environment variable value.
-func GetSshAuthSocket() string {
+func GetSshAuthSocket() (string, error) {
 	path := os.Getenv("SSH_AUTH_SOCK")
 	if len(path) == 0 {
-		log.Fatal().Msg(L("SSH_AUTH_SOCK is not defined, start an SSH agent and try again"))
+		return "", errors.New(L("SSH_AUTH_SOCK is not defined, start an SSH agent and try again"))
 	}
-	return path
+	return path, nil
 }
 
 // GetSshPaths returns the user SSH config and known_hosts paths.
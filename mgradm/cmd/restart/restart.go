@@ -31,15 +31,13 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	}
 	restartCmd.SetUsageTemplate(restartCmd.UsageTemplate())
 
-	if utils.KubernetesBuilt {
-		utils.AddBackendFlag(restartCmd)
-	}
+	utils.AddBackendFlag(restartCmd)
 
 	return restartCmd
 }
 
 func restart(globalFlags *types.GlobalFlags, flags *restartFlags, cmd *cobra.Command, args []string) error {
-	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanRestart, kubernetesRestart)
+	fn, err := shared.ChooseRemotePodmanOrKubernetes(cmd.Flags(), podmanRestart, kubernetesRestart)
 	if err != nil {
 		return err
 	}
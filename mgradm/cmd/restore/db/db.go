@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package db
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/backup"
+	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type dbFlags struct {
+	Backend string
+}
+
+// NewCommand to restore the server database from a dump.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	dbCmd := &cobra.Command{
+		Use:   "db dump-file",
+		Short: L("Restore the server database from a dump"),
+		Long: L(`Restore the server database from a dump created by 'mgradm backup db'.
+
+The dump is streamed from the local file to pg_restore in the running server container.
+The server services are stopped during the restore and started again afterwards.
+`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags dbFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, restoreDb)
+		},
+	}
+
+	utils.AddBackendFlag(dbCmd)
+
+	return dbCmd
+}
+
+func restoreDb(globalFlags *types.GlobalFlags, flags *dbFlags, cmd *cobra.Command, args []string) error {
+	input := args[0]
+	in, err := os.Open(input)
+	if err != nil {
+		return utils.Errorf(err, L("failed to open %s"), input)
+	}
+	defer in.Close()
+
+	cnx := shared.NewConnection(flags.Backend, podman.ServerContainerName, kubernetes.ServerFilter)
+	data, err := adm_utils.InspectRunningServer(cnx)
+	if err != nil {
+		return utils.Errorf(err, L("failed to inspect the server"))
+	}
+
+	if err := backup.RestoreDatabase(cnx, data, in); err != nil {
+		return err
+	}
+
+	log.Info().Msgf(L("Database restored from %s"), input)
+	return nil
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package restore
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/restore/db"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+// NewCommand to restore parts of the server.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:     "restore",
+		GroupID: "management",
+		Short:   L("Restore parts of the server"),
+		Long:    L("Restore parts of the server"),
+	}
+
+	restoreCmd.AddCommand(db.NewCommand(globalFlags))
+
+	return restoreCmd
+}
@@ -31,15 +31,13 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	}
 	startCmd.SetUsageTemplate(startCmd.UsageTemplate())
 
-	if utils.KubernetesBuilt {
-		utils.AddBackendFlag(startCmd)
-	}
+	utils.AddBackendFlag(startCmd)
 
 	return startCmd
 }
 
 func start(globalFlags *types.GlobalFlags, flags *startFlags, cmd *cobra.Command, args []string) error {
-	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanStart, kubernetesStart)
+	fn, err := shared.ChooseRemotePodmanOrKubernetes(cmd.Flags(), podmanStart, kubernetesStart)
 	if err != nil {
 		return err
 	}
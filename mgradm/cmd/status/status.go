@@ -6,6 +6,7 @@ package status
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/spf13/cobra"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
@@ -15,6 +16,7 @@ import (
 )
 
 type statusFlags struct {
+	Backend string
 }
 
 // NewCommand to get the status of the server.
@@ -31,16 +33,23 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 		},
 	}
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
+	utils.AddBackendFlag(cmd)
 
 	return cmd
 }
 
 func status(globalFlags *types.GlobalFlags, flags *statusFlags, cmd *cobra.Command, args []string) error {
-	if podman.HasService(podman.ServerService) {
+	restore, err := utils.UseSSHBackend(flags.Backend)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	if flags.Backend != "kubectl" && podman.HasService(podman.ServerService) {
 		return podmanStatus(globalFlags, flags, cmd, args)
 	}
 
-	if utils.IsInstalled("kubectl") && utils.IsInstalled("helm") {
+	if !strings.HasPrefix(flags.Backend, "podman") && utils.IsInstalled("kubectl") && utils.IsInstalled("helm") {
 		return kubernetesStatus(globalFlags, flags, cmd, args)
 	}
 
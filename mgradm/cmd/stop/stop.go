@@ -32,15 +32,13 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 
 	stopCmd.SetUsageTemplate(stopCmd.UsageTemplate())
 
-	if utils.KubernetesBuilt {
-		utils.AddBackendFlag(stopCmd)
-	}
+	utils.AddBackendFlag(stopCmd)
 
 	return stopCmd
 }
 
 func stop(globalFlags *types.GlobalFlags, flags *stopFlags, cmd *cobra.Command, args []string) error {
-	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanStop, kubernetesStop)
+	fn, err := shared.ChooseRemotePodmanOrKubernetes(cmd.Flags(), podmanStop, kubernetesStop)
 	if err != nil {
 		return err
 	}
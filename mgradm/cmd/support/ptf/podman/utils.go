@@ -41,7 +41,7 @@ func ptfForPodman(
 	}
 	defer cleaner()
 
-	return podman.Upgrade(authFile, "", flags.Image, dummyImage, dummyImage, dummyImage)
+	return podman.Upgrade(authFile, "", flags.Image, dummyImage, dummyImage, dummyImage, false)
 }
 
 func (flags *podmanPTFFlags) checkParameters() error {
@@ -27,7 +27,7 @@ func uninstallForPodman(
 	}
 
 	// Uninstall the service
-	podman.UninstallService("uyuni-server", !flags.Force)
+	podman.UninstallService(podman.ServerService, !flags.Force)
 	// Force stop the pod
 	podman.DeleteContainer(podman.ServerContainerName, !flags.Force)
 
@@ -37,7 +37,7 @@ func uninstallForPodman(
 	// Remove the volumes
 	if flags.Purge.Volumes {
 		allOk := true
-		volumes := []string{"cgroup"}
+		volumes := []string{podman.InstanceVolume("cgroup")}
 		for _, volume := range utils.ServerVolumeMounts {
 			volumes = append(volumes, volume.Name)
 		}
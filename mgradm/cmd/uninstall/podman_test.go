@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package uninstall
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+const serverImage = "registry.opensuse.org/uyuni/server:latest"
+
+func setupServices(t *testing.T) string {
+	servicesDir := t.TempDir()
+	t.Cleanup(podman.SetServicesFolder(servicesDir))
+
+	confDir := path.Join(servicesDir, "uyuni-server.service.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	test_utils.WriteFile(t, path.Join(servicesDir, "uyuni-server.service"), "[Unit]\n")
+	test_utils.WriteFile(t, path.Join(confDir, "generated.conf"), "[Service]\nEnvironment=UYUNI_IMAGE="+serverImage+"\n")
+	return servicesDir
+}
+
+func TestUninstallForPodmanDryRun(t *testing.T) {
+	servicesDir := setupServices(t)
+	executor := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(executor))
+
+	executor.
+		Expect("", nil, "systemctl", "list-unit-files", "uyuni-server.service").
+		Expect("4c0ffee\n", nil, "podman", "ps", "-a", "-q", "-f", "name=uyuni-server").
+		Expect("", nil, "podman", "network", "exists", "uyuni")
+
+	flags := utils.UninstallFlags{}
+	if err := uninstallForPodman(&types.GlobalFlags{}, &flags, nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "service file removed in dry run",
+		utils.FileExists(path.Join(servicesDir, "uyuni-server.service")))
+}
+
+func TestUninstallForPodman(t *testing.T) {
+	servicesDir := setupServices(t)
+	executor := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(executor))
+
+	executor.
+		Expect("", nil, "systemctl", "list-unit-files", "uyuni-server.service").
+		Expect("", nil, "systemctl", "disable", "--now", "uyuni-server").
+		Expect("4c0ffee\n", nil, "podman", "ps", "-a", "-q", "-f", "name=uyuni-server").
+		Expect("", nil, "podman", "kill", "uyuni-server").
+		Expect("", nil, "systemctl", "is-enabled", "uyuni-server-attestation@0.service").
+		Expect("", test_utils.ErrCommandFailed, "systemctl", "is-enabled", "uyuni-server-attestation@1.service").
+		Expect("", nil, "systemctl", "disable", "--now", "uyuni-server-attestation@0").
+		Expect("", test_utils.ErrCommandFailed, "systemctl", "is-enabled", "uyuni-hub-xmlrpc@0.service")
+	for _, volume := range append([]string{"cgroup"}, volumeNames()...) {
+		executor.
+			Expect("", nil, "podman", "volume", "exists", volume).
+			Expect("", nil, "podman", "volume", "rm", volume)
+	}
+	executor.
+		Expect("", nil, "podman", "image", "exists", serverImage).
+		Expect("", nil, "podman", "image", "rm", serverImage).
+		Expect("", nil, "podman", "network", "exists", "uyuni").
+		Expect("", nil, "podman", "network", "rm", "uyuni").
+		Expect("", nil, "systemctl", "reset-failed").
+		Expect("", nil, "systemctl", "daemon-reload")
+
+	flags := utils.UninstallFlags{Force: true}
+	flags.Purge.Volumes = true
+	flags.Purge.Images = true
+	if err := uninstallForPodman(&types.GlobalFlags{}, &flags, nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "service file not removed",
+		!utils.FileExists(path.Join(servicesDir, "uyuni-server.service")))
+	test_utils.AssertTrue(t, "service configuration folder not removed",
+		!utils.FileExists(path.Join(servicesDir, "uyuni-server.service.d")))
+}
+
+func volumeNames() []string {
+	names := []string{}
+	for _, volume := range utils.ServerVolumeMounts {
+		names = append(names, volume.Name)
+	}
+	return names
+}
@@ -19,6 +19,8 @@ type podmanUpgradeFlags struct {
 	SCC                 types.SCCCredentials
 	Podman              podman.PodmanFlags
 	MirrorPath          string
+	Snapshot            bool
+	Backend             string
 }
 
 // NewCommand to upgrade a podman server.
@@ -34,6 +36,14 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	}
 	shared.AddUpgradeFlags(upgradeCmd)
 	podman.AddPodmanArgFlag(upgradeCmd)
+	upgradeCmd.Flags().Bool("snapshot", false,
+		L(`Snapshot the database and configuration volumes before the upgrade and roll back to it if the upgrade fails.
+The snapshot is required by mgradm upgrade rollback if the PostgreSQL major version changes:
+it is then kept until the next snapshot upgrade, a rollback or mgradm upgrade rollback --discard,
+otherwise it is removed once the upgrade succeeded.
+Keeping it uses as much disk space as the database and slows down the writes if it is an LVM snapshot.`))
+	upgradeCmd.Flags().String("backend", "",
+		L("set to 'ssh://[user@]host[:port]' to upgrade the server of a remote host. Default: the local host."))
 
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -5,6 +5,8 @@
 package podman
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
@@ -14,6 +16,16 @@ import (
 )
 
 func upgradePodman(globalFlags *types.GlobalFlags, flags *podmanUpgradeFlags, cmd *cobra.Command, args []string) error {
+	if flags.Backend != "" && flags.Backend != "podman" && !utils.IsSSHBackend(flags.Backend) {
+		return fmt.Errorf(L("unsupported backend %s: expected podman or ssh://[user@]host[:port]"), flags.Backend)
+	}
+	// The podman and systemd commands, scripts and files are all handled on the remote host
+	restore, err := utils.UseSSHBackend(flags.Backend)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
 	hostData, err := shared_podman.InspectHost()
 	if err != nil {
 		return err
@@ -27,5 +39,6 @@ func upgradePodman(globalFlags *types.GlobalFlags, flags *podmanUpgradeFlags, cm
 
 	return podman.Upgrade(
 		authFile, globalFlags.Registry, flags.Image, flags.DbUpgradeImage, flags.Coco.Image, flags.HubXmlrpc,
+		flags.Snapshot,
 	)
 }
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rollback
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+type rollbackFlags struct {
+	Force   bool
+	Discard bool
+}
+
+// NewCommand to roll back a podman server to the previously deployed images.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: L("Roll back the server to the previously deployed images"),
+		Long: L(`Roll back the server to the previously deployed images.
+
+The images of the server, hub XML-RPC API and confidential computing attestation services
+are set back to the ones of the deployment preceding the last upgrade.
+
+If the last upgrade changed the PostgreSQL major version, the database and configuration volumes
+are restored from the snapshot taken before the upgrade: the rollback is then only possible
+if the upgrade has been run with the --snapshot flag and all the changes done on the server
+since the upgrade are lost.
+
+Use --discard to remove the snapshot kept since the last upgrade without rolling back.
+
+This command is only available for podman servers.
+`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags rollbackFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, rollback)
+		},
+	}
+
+	rollbackCmd.Flags().BoolP("force", "f", false, L("Restore the snapshot without asking confirmation"))
+	rollbackCmd.Flags().Bool("discard", false, L("Remove the snapshot taken before the last upgrade instead of rolling back"))
+
+	return rollbackCmd
+}
+
+func rollback(globalFlags *types.GlobalFlags, flags *rollbackFlags, cmd *cobra.Command, args []string) error {
+	if flags.Discard {
+		return podman.DiscardSnapshots()
+	}
+	return podman.Rollback(flags.Force)
+}
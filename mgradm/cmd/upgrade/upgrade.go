@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/upgrade/kubernetes"
 	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/upgrade/podman"
+	"github.com/uyuni-project/uyuni-tools/mgradm/cmd/upgrade/rollback"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 )
@@ -22,6 +23,7 @@ func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
 	upgradeCmd.PersistentFlags().StringVar(&globalFlags.Registry, "registry", "", L("specify a private registry"))
 
 	upgradeCmd.AddCommand(podman.NewCommand(globalFlags))
+	upgradeCmd.AddCommand(rollback.NewCommand(globalFlags))
 
 	if kubernetesCmd := kubernetes.NewCommand(globalFlags); kubernetesCmd != nil {
 		upgradeCmd.AddCommand(kubernetesCmd)
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !nok8s
+
+package volumes
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/kubernetes"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	shared_kubernetes "github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func kubernetesVolumes(
+	globalFlags *types.GlobalFlags,
+	flags *volumesFlags,
+	cmd *cobra.Command,
+	args []string,
+) error {
+	cnx := shared.NewConnection("kubectl", "", shared_kubernetes.ServerFilter)
+	namespace, err := cnx.GetNamespace("")
+	if err != nil {
+		return utils.Errorf(err, L("cannot find the server namespace"))
+	}
+
+	claims, err := kubernetes.GetClaimsInfo(namespace)
+	if err != nil {
+		return err
+	}
+	if flags.Output == outputJSON {
+		return writeJSON(os.Stdout, claims)
+	}
+
+	writer := newTableWriter()
+	fmt.Fprintln(writer, L("VOLUME\tCLAIM\tSTATUS\tSTORAGE CLASS\tREQUESTED\tCAPACITY\tPERSISTENT VOLUME\tNOTE"))
+	for _, claim := range claims {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			claim.Name, claim.Claim, claim.Status, claim.StorageClass, claim.Requested, claim.Capacity,
+			claim.Volume, claim.Error,
+		)
+	}
+	return writer.Flush()
+}
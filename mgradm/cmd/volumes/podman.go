@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package volumes
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/mgradm/shared/podman"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+func podmanVolumes(
+	globalFlags *types.GlobalFlags,
+	flags *volumesFlags,
+	cmd *cobra.Command,
+	args []string,
+) error {
+	volumes := podman.GetVolumesInfo()
+	if flags.Output == outputJSON {
+		return writeJSON(os.Stdout, volumes)
+	}
+
+	writer := newTableWriter()
+	writePodmanTable(writer, volumes)
+	return writer.Flush()
+}
+
+func writePodmanTable(out io.Writer, volumes []podman.VolumeInfo) {
+	fmt.Fprintln(out, L("VOLUME\tMOUNT PATH\tMOUNT POINT\tSIZE\tINODES\tFILESYSTEM\tAVAILABLE\tSELINUX\tNOTE"))
+	for _, volume := range volumes {
+		if volume.Error != "" {
+			fmt.Fprintf(out, "%s\t%s\t\t\t\t\t\t\t%s\n", volume.Name, volume.MountPath, volume.Error)
+			continue
+		}
+		note := ""
+		if volume.OnRootFs {
+			note = L("on root filesystem")
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			volume.Name, volume.MountPath, volume.MountPoint, formatSize(uint64(volume.Size)), volume.Inodes,
+			volume.Filesystem, formatSize(volume.FsAvailable), volume.SELinuxContext, note,
+		)
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package volumes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type volumesFlags struct {
+	Output  string
+	Backend string
+}
+
+// NewCommand to report the usage of the server volumes.
+func NewCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	volumesCmd := &cobra.Command{
+		Use:     "volumes",
+		GroupID: "management",
+		Short:   L("Report the usage of the server volumes"),
+		Long: L(`Report the usage of the server volumes.
+
+For podman, each volume is listed with its path in the container, its mount point on the host,
+its size, inodes usage, filesystem and SELinux context.
+The volumes stored on the same filesystem than / are flagged as they may fill the root filesystem.
+
+For kubernetes, each volume is listed with its persistent volume claim, storage class,
+capacity and bound persistent volume.
+`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags volumesFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, volumes)
+		},
+	}
+
+	volumesCmd.Flags().StringP("output", "o", outputTable, L("Output format: table or json"))
+
+	if utils.KubernetesBuilt {
+		utils.AddBackendFlag(volumesCmd)
+	}
+
+	return volumesCmd
+}
+
+func volumes(globalFlags *types.GlobalFlags, flags *volumesFlags, cmd *cobra.Command, args []string) error {
+	if flags.Output != outputTable && flags.Output != outputJSON {
+		return fmt.Errorf(L("unsupported output format: %s"), flags.Output)
+	}
+
+	fn, err := shared.ChoosePodmanOrKubernetes(cmd.Flags(), podmanVolumes, kubernetesVolumes)
+	if err != nil {
+		return err
+	}
+	return fn(globalFlags, flags, cmd, args)
+}
+
+func writeJSON(out io.Writer, data any) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the volumes report"))
+	}
+	_, err = fmt.Fprintln(out, string(content))
+	return err
+}
+
+func newTableWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// formatSize returns a human readable size using binary units.
+func formatSize(size uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package volumes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestFormatSize(t *testing.T) {
+	data := map[uint64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 40:         "3.0 TiB",
+		1<<30 + 100<<20: "1.1 GiB",
+	}
+
+	for size, expected := range data {
+		test_utils.AssertEquals(t, fmt.Sprintf("wrong format for %d", size), expected, formatSize(size))
+	}
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ManifestFilename is the name of the manifest entry at the root of a backup archive.
+const ManifestFilename = "manifest.json"
+
+// VolumesDir is the folder of the backup archive containing one sub folder per volume.
+const VolumesDir = "volumes"
+
+// SystemdDir is the folder of the backup archive containing the systemd units and their configuration.
+const SystemdDir = "systemd"
+
+// ManifestVersion is the version of the backup archive format.
+const ManifestVersion = 1
+
+// PodmanServices returns the systemd services of the server instance to save in a podman backup.
+//
+// The instantiated services are stored with their template unit name.
+func PodmanServices() []string {
+	return []string{
+		podman.ServerService,
+		podman.ServerAttestationService + "@",
+		podman.HubXmlrpcService + "@",
+	}
+}
+
+// Manifest describes the content of a backup archive.
+type Manifest struct {
+	Version            int       `json:"version"`
+	Date               time.Time `json:"date"`
+	Image              string    `json:"image"`
+	UyuniRelease       string    `json:"uyuniRelease,omitempty"`
+	SuseManagerRelease string    `json:"suseManagerRelease,omitempty"`
+	PgVersion          string    `json:"pgVersion"`
+	Volumes            []string  `json:"volumes"`
+	// Replicas is the number of running instances of the instantiated services
+	// or of the server deployment on kubernetes.
+	Replicas map[string]int `json:"replicas,omitempty"`
+}
+
+// NewManifest creates a manifest for a backup of the server running image with the inspected data.
+func NewManifest(image string, data *utils.ServerInspectData, volumes []string) *Manifest {
+	return &Manifest{
+		Version:            ManifestVersion,
+		Date:               time.Now().UTC(),
+		Image:              image,
+		UyuniRelease:       data.UyuniRelease,
+		SuseManagerRelease: data.SuseManagerRelease,
+		PgVersion:          data.CurrentPgVersion,
+		Volumes:            volumes,
+		Replicas:           map[string]int{},
+	}
+}
+
+// Write adds the manifest to the backup archive.
+func (m *Manifest) Write(tarball *utils.TarGz) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the backup manifest"))
+	}
+	if err := tarball.AddData(data, ManifestFilename, 0600); err != nil {
+		return utils.Errorf(err, L("failed to add the manifest to the backup"))
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest of a backup archive encrypted with passphrase.
+//
+// The passphrase is ignored if the archive is not encrypted.
+// The manifest is expected to be the first entry of the archive.
+func ReadManifest(archivePath string, passphrase string) (*Manifest, error) {
+	var manifest *Manifest
+	err := utils.WalkEncryptedTarGz(archivePath, passphrase, func(header *tar.Header, reader io.Reader) error {
+		if header.Name != ManifestFilename {
+			return io.EOF
+		}
+		manifest = &Manifest{}
+		if err := json.NewDecoder(reader).Decode(manifest); err != nil {
+			return utils.Errorf(err, L("failed to parse the backup manifest"))
+		}
+		return io.EOF
+	})
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to read %s"), archivePath)
+	}
+	if manifest == nil {
+		return nil, errors.New(L("no manifest found, the file is not a server backup"))
+	}
+	if manifest.Version > ManifestVersion {
+		return nil, errors.New(L("the backup has been created by a newer version of the tool"))
+	}
+	return manifest, nil
+}
+
+// ServerVolumeNames returns the names of the server volumes without duplicates.
+func ServerVolumeNames() []string {
+	names := []string{}
+	for _, volume := range utils.ServerVolumeMounts {
+		if !utils.Contains(names, volume.Name) {
+			names = append(names, volume.Name)
+		}
+	}
+	return names
+}
+
+// VolumeEntry returns the path of a volume's folder in the backup archive.
+func VolumeEntry(volume string) string {
+	return path.Join(VolumesDir, volume)
+}
+
+// SplitVolumeEntry returns the volume name and the path in the volume of a backup archive entry.
+// found is false if the entry isn't part of a volume.
+func SplitVolumeEntry(entry string) (volume string, name string, found bool) {
+	if !strings.HasPrefix(entry, VolumesDir+"/") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(entry, VolumesDir+"/"), "/", 2)
+	if parts[0] == "" {
+		return "", "", false
+	}
+	volume = parts[0]
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+	return volume, name, true
+}
+
+// ServiceEntry returns the path of a systemd unit file in the backup archive.
+func ServiceEntry(service string) string {
+	return path.Join(SystemdDir, service+".service")
+}
+
+// ServiceConfEntry returns the path of a systemd unit configuration folder in the backup archive.
+func ServiceConfEntry(service string) string {
+	return path.Join(SystemdDir, service+".service.d")
+}
+
+// VolumeFileEntry returns the path in the backup archive of a file named relatively to the root of a volume.
+//
+// Directories entries are suffixed with a slash, like tar does.
+func VolumeFileEntry(volume string, name string, isDir bool) string {
+	entry := VolumeEntry(volume)
+	if relPath := path.Clean("/" + name); relPath != "/" {
+		entry = path.Join(entry, relPath)
+	}
+	if isDir {
+		entry += "/"
+	}
+	return entry
+}
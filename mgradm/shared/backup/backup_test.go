@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestServerVolumeNames(t *testing.T) {
+	names := ServerVolumeNames()
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		test_utils.AssertTrue(t, "duplicated volume "+name, !seen[name])
+		seen[name] = true
+	}
+
+	for _, volume := range utils.ServerVolumeMounts {
+		test_utils.AssertTrue(t, "missing volume "+volume.Name, seen[volume.Name])
+	}
+}
+
+func TestEntries(t *testing.T) {
+	test_utils.AssertEquals(t, "wrong volume entry", "volumes/var-pgsql", VolumeEntry("var-pgsql"))
+	test_utils.AssertEquals(t, "wrong service entry", "systemd/uyuni-server.service", ServiceEntry("uyuni-server"))
+	test_utils.AssertEquals(t, "wrong service conf entry",
+		"systemd/uyuni-server.service.d", ServiceConfEntry("uyuni-server"),
+	)
+}
+
+func TestSplitVolumeEntry(t *testing.T) {
+	data := []struct {
+		entry  string
+		volume string
+		name   string
+		found  bool
+	}{
+		{"volumes/var-pgsql/data/PG_VERSION", "var-pgsql", "data/PG_VERSION", true},
+		{"volumes/var-pgsql/", "var-pgsql", "", true},
+		{"volumes/", "", "", false},
+		{"systemd/uyuni-server.service", "", "", false},
+		{"manifest.json", "", "", false},
+	}
+
+	for i, testCase := range data {
+		volume, name, found := SplitVolumeEntry(testCase.entry)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected volume", i), testCase.volume, volume)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected name", i), testCase.name, name)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected found", i), testCase.found, found)
+	}
+}
+
+func TestVolumeFileEntry(t *testing.T) {
+	data := []struct {
+		name     string
+		isDir    bool
+		expected string
+	}{
+		{"./", true, "volumes/var-pgsql/"},
+		{".", true, "volumes/var-pgsql/"},
+		{"./data/", true, "volumes/var-pgsql/data/"},
+		{"./data/PG_VERSION", false, "volumes/var-pgsql/data/PG_VERSION"},
+		{"data/PG_VERSION", false, "volumes/var-pgsql/data/PG_VERSION"},
+		{"../outside", false, "volumes/var-pgsql/outside"},
+	}
+
+	for i, testCase := range data {
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong entry", i),
+			testCase.expected, VolumeFileEntry("var-pgsql", testCase.name, testCase.isDir),
+		)
+	}
+}
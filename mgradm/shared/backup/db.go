@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// readPasswordCmd reads the database password from the first line of the standard input.
+// This avoids the password to show up in the process list of the container.
+const readPasswordCmd = "read -r PGPASSWORD && export PGPASSWORD && exec "
+
+// DumpDatabase runs pg_dump in the server container and writes the dump to out.
+//
+// The dump is in PostgreSQL custom format and streamed out of the container
+// without being stored in it.
+func DumpDatabase(cnx *shared.Connection, data *utils.ServerInspectData, out io.Writer) error {
+	if err := checkDbData(data); err != nil {
+		return err
+	}
+
+	log.Info().Msgf(L("Dumping database %s"), data.DbName)
+	stdin := strings.NewReader(data.DbPassword + "\n")
+	if err := cnx.ExecStream(stdin, out, "sh", "-c", readPasswordCmd+dumpCommand(data)); err != nil {
+		return utils.Errorf(err, L("failed to dump the database"))
+	}
+	return nil
+}
+
+// RestoreDatabase runs pg_restore in the server container reading the dump from in.
+//
+// The services using the database are stopped during the restore and started again afterwards.
+func RestoreDatabase(cnx *shared.Connection, data *utils.ServerInspectData, in io.Reader) error {
+	if err := checkDbData(data); err != nil {
+		return err
+	}
+
+	log.Info().Msg(L("Stopping the server services"))
+	if _, err := cnx.Exec("spacewalk-service", "stop"); err != nil {
+		return utils.Errorf(err, L("failed to stop the server services"))
+	}
+	defer func() {
+		log.Info().Msg(L("Starting the server services"))
+		if _, err := cnx.Exec("spacewalk-service", "start"); err != nil {
+			log.Error().Err(err).Msg(L("failed to start the server services"))
+		}
+	}()
+
+	log.Info().Msgf(L("Restoring database %s"), data.DbName)
+	stdin := io.MultiReader(strings.NewReader(data.DbPassword+"\n"), in)
+	if err := cnx.ExecStream(stdin, io.Discard, "sh", "-c", readPasswordCmd+restoreCommand(data)); err != nil {
+		return utils.Errorf(err, L("failed to restore the database"))
+	}
+	return nil
+}
+
+func checkDbData(data *utils.ServerInspectData) error {
+	if data.DbName == "" || data.DbUser == "" {
+		return errors.New(L("failed to find the database configuration in the server container"))
+	}
+	return nil
+}
+
+func dbConnectionArgs(data *utils.ServerInspectData) string {
+	port := data.DbPort
+	if port == 0 {
+		port = 5432
+	}
+	return fmt.Sprintf("-h localhost -p %d -U %s", port, utils.ShellQuote(data.DbUser))
+}
+
+func dumpCommand(data *utils.ServerInspectData) string {
+	return fmt.Sprintf("pg_dump -Fc %s %s", dbConnectionArgs(data), utils.ShellQuote(data.DbName))
+}
+
+func restoreCommand(data *utils.ServerInspectData) string {
+	return fmt.Sprintf("pg_restore --clean --if-exists --single-transaction %s -d %s",
+		dbConnectionArgs(data), utils.ShellQuote(data.DbName))
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestDbCommands(t *testing.T) {
+	data := utils.ServerInspectData{
+		CommonInspectData: utils.CommonInspectData{
+			DbUser:     "spacewalk",
+			DbPassword: "secret",
+			DbName:     "susemanager",
+			DbPort:     5433,
+		},
+	}
+
+	test_utils.AssertEquals(t, "wrong dump command",
+		"pg_dump -Fc -h localhost -p 5433 -U spacewalk susemanager", dumpCommand(&data),
+	)
+	test_utils.AssertEquals(t, "wrong restore command",
+		"pg_restore --clean --if-exists --single-transaction -h localhost -p 5433 -U spacewalk -d susemanager",
+		restoreCommand(&data),
+	)
+
+	data.DbPort = 0
+	test_utils.AssertEquals(t, "wrong default port",
+		"pg_dump -Fc -h localhost -p 5432 -U spacewalk susemanager", dumpCommand(&data),
+	)
+
+	data.DbName = "uyuni's db"
+	test_utils.AssertEquals(t, "wrong quoting",
+		`pg_dump -Fc -h localhost -p 5432 -U spacewalk 'uyuni'\''s db'`, dumpCommand(&data),
+	)
+
+	data.DbName = ""
+	test_utils.AssertTrue(t, "missing database name not detected", checkDbData(&data) != nil)
+}
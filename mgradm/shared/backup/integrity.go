@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"archive/tar"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ChecksumsFilename is the name of the entry listing the SHA-256 of the archive files.
+//
+// This entry is the last one of the archive to be computed while writing the other ones.
+const ChecksumsFilename = "checksums.json"
+
+// checksumsAlgorithm is the hash algorithm of the checksums.
+const checksumsAlgorithm = "sha256"
+
+// Checksums lists the SHA-256 of the regular files of a backup archive.
+//
+// When the backup is created with a passphrase the checksums are signed with an HMAC-SHA256
+// using a key derived from the passphrase.
+// Without passphrase there is no secret to sign with: the checksums only detect corruption,
+// not deliberate modifications.
+type Checksums struct {
+	Algorithm string            `json:"algorithm"`
+	Entries   map[string]string `json:"entries"`
+	Salt      string            `json:"salt,omitempty"`
+	Signature string            `json:"signature,omitempty"`
+}
+
+// WriteChecksums adds the checksums of all the files written so far to the backup archive.
+//
+// The checksums are signed if the passphrase is not empty.
+func WriteChecksums(tarball *utils.TarGz, passphrase string) error {
+	checksums := Checksums{
+		Algorithm: checksumsAlgorithm,
+		Entries:   tarball.Checksums(),
+	}
+	if passphrase != "" {
+		if err := checksums.sign(passphrase); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the backup checksums"))
+	}
+	if err := tarball.AddData(data, ChecksumsFilename, 0600); err != nil {
+		return utils.Errorf(err, L("failed to add the checksums to the backup"))
+	}
+	return nil
+}
+
+// VerifyArchive checks the integrity of a backup archive without extracting it.
+//
+// All the files are read and their SHA-256 compared to the checksums stored in the archive.
+// The signature of the checksums is verified if the backup has been created with a passphrase.
+func VerifyArchive(archivePath string, passphrase string) error {
+	computed := map[string]string{}
+	var checksums *Checksums
+	err := utils.WalkEncryptedTarGz(archivePath, passphrase, func(header *tar.Header, reader io.Reader) error {
+		if checksums != nil {
+			return fmt.Errorf(L("unexpected entry %s after the checksums"), header.Name)
+		}
+		if header.Name == ChecksumsFilename {
+			checksums = &Checksums{}
+			if err := json.NewDecoder(reader).Decode(checksums); err != nil {
+				return utils.Errorf(err, L("failed to parse the backup checksums"))
+			}
+			return nil
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, reader); err != nil {
+			return err
+		}
+		computed[header.Name] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return utils.Errorf(err, L("failed to read %s"), archivePath)
+	}
+
+	if checksums == nil {
+		return errors.New(L("no checksums found in the backup, it may be truncated"))
+	}
+	if checksums.Algorithm != checksumsAlgorithm {
+		return fmt.Errorf(L("unsupported checksum algorithm: %s"), checksums.Algorithm)
+	}
+	if err := checksums.verifySignature(passphrase); err != nil {
+		return err
+	}
+
+	problems := compareChecksums(checksums.Entries, computed)
+	for _, problem := range problems {
+		log.Error().Msg(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf(NL("%d file failed the integrity check", "%d files failed the integrity check",
+			len(problems)), len(problems))
+	}
+	return nil
+}
+
+// compareChecksums lists the differences between the expected and computed checksums.
+func compareChecksums(expected map[string]string, computed map[string]string) []string {
+	problems := []string{}
+	for name, checksum := range computed {
+		expectedChecksum, found := expected[name]
+		if !found {
+			problems = append(problems, fmt.Sprintf(L("%s is not listed in the checksums"), name))
+		} else if expectedChecksum != checksum {
+			problems = append(problems, fmt.Sprintf(L("%s has been modified"), name))
+		}
+	}
+	for name := range expected {
+		if _, found := computed[name]; !found {
+			problems = append(problems, fmt.Sprintf(L("%s is missing"), name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func (c *Checksums) sign(passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return utils.Errorf(err, L("failed to generate random salt"))
+	}
+	c.Salt = hex.EncodeToString(salt)
+	c.Signature = ""
+
+	signature, err := c.computeSignature(passphrase)
+	if err != nil {
+		return err
+	}
+	c.Signature = signature
+	return nil
+}
+
+func (c *Checksums) verifySignature(passphrase string) error {
+	if c.Signature == "" {
+		if passphrase != "" {
+			return errors.New(L("the backup checksums are not signed"))
+		}
+		log.Warn().Msg(L("The backup checksums are not signed, only the consistency of the archive can be checked"))
+		return nil
+	}
+	if passphrase == "" {
+		return errors.New(L("the backup checksums are signed, a passphrase is required to verify them"))
+	}
+
+	expected, err := c.computeSignature(passphrase)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(c.Signature)) {
+		return errors.New(L("invalid signature of the backup checksums: wrong passphrase or altered checksums"))
+	}
+	return nil
+}
+
+// computeSignature computes the HMAC of the checksums serialized without signature.
+func (c *Checksums) computeSignature(passphrase string) (string, error) {
+	salt, err := hex.DecodeString(c.Salt)
+	if err != nil {
+		return "", utils.Errorf(err, L("invalid salt in the backup checksums"))
+	}
+	key, err := utils.DeriveKey(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := *c
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to serialize the backup checksums"))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// AskPassphraseIfEncrypted prompts for the passphrase of an encrypted archive if none has been provided.
+func AskPassphraseIfEncrypted(archivePath string, passphrase *string) error {
+	encrypted, err := utils.IsEncrypted(archivePath)
+	if err != nil {
+		return utils.Errorf(err, L("failed to read %s"), archivePath)
+	}
+	for encrypted && *passphrase == "" {
+		utils.CheckValidPassword(passphrase, L("Backup passphrase"), 0, 0)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestVerifyArchive(t *testing.T) {
+	testDir, cleaner := test_utils.CreateTmpFolder(t)
+	defer cleaner()
+
+	for i, passphrase := range []string{"", "secret"} {
+		archivePath := path.Join(testDir, fmt.Sprintf("backup%d.tar.gz", i))
+		writeTestArchive(t, archivePath, passphrase, func(tarball *utils.TarGz) error {
+			return WriteChecksums(tarball, passphrase)
+		})
+
+		if err := VerifyArchive(archivePath, passphrase); err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+		}
+		if passphrase != "" {
+			if err := VerifyArchive(archivePath, "wrong"); err == nil {
+				t.Errorf("case %d: expected an error with a wrong passphrase", i)
+			}
+		}
+	}
+}
+
+func TestVerifyArchiveAltered(t *testing.T) {
+	testDir, cleaner := test_utils.CreateTmpFolder(t)
+	defer cleaner()
+
+	archivePath := path.Join(testDir, "backup.tar.gz")
+	writeTestArchive(t, archivePath, "", func(tarball *utils.TarGz) error {
+		data, _ := json.Marshal(Checksums{
+			Algorithm: checksumsAlgorithm,
+			Entries:   map[string]string{ManifestFilename: "0000", "volumes/etc-rhn/rhn.conf": "0000"},
+		})
+		return tarball.AddData(data, ChecksumsFilename, 0600)
+	})
+
+	err := VerifyArchive(archivePath, "")
+	test_utils.AssertTrue(t, "altered archive passed the verification", err != nil)
+	if err != nil {
+		test_utils.AssertTrue(t, "wrong error: "+err.Error(), strings.HasPrefix(err.Error(), "3 "))
+	}
+
+	truncatedPath := path.Join(testDir, "truncated.tar.gz")
+	writeTestArchive(t, truncatedPath, "", func(tarball *utils.TarGz) error { return nil })
+	err = VerifyArchive(truncatedPath, "")
+	test_utils.AssertTrue(t, "archive without checksums passed the verification", err != nil)
+}
+
+func TestCompareChecksums(t *testing.T) {
+	data := []struct {
+		expected map[string]string
+		computed map[string]string
+		problems string
+	}{
+		{map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1", "b": "2"}, ""},
+		{map[string]string{"a": "1"}, map[string]string{"a": "2"}, "a has been modified"},
+		{map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1"}, "b is missing"},
+		{map[string]string{"a": "1"}, map[string]string{"a": "1", "b": "2"}, "b is not listed in the checksums"},
+	}
+
+	for i, testCase := range data {
+		problems := compareChecksums(testCase.expected, testCase.computed)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong problems", i),
+			testCase.problems, strings.Join(problems, "|"))
+	}
+}
+
+// writeTestArchive creates a backup archive with a manifest and a volume file before calling finish.
+func writeTestArchive(t *testing.T, archivePath string, passphrase string, finish func(*utils.TarGz) error) {
+	tarball, err := utils.NewEncryptedTarGz(archivePath, passphrase)
+	if err != nil {
+		t.Fatalf("failed to create archive: %s", err)
+	}
+	manifest := NewManifest("uyuni/server:latest", &utils.ServerInspectData{}, []string{"etc-rhn"})
+	if err := manifest.Write(tarball); err != nil {
+		t.Fatalf("failed to write the manifest: %s", err)
+	}
+	if err := tarball.AddData([]byte("db_password = secret"), VolumeEntry("etc-rhn")+"/rhn.conf", 0600); err != nil {
+		t.Fatalf("failed to write a volume file: %s", err)
+	}
+	if err := tarball.AddData([]byte("unlisted"), VolumeEntry("etc-rhn")+"/other", 0600); err != nil {
+		t.Fatalf("failed to write a volume file: %s", err)
+	}
+	if err := finish(tarball); err != nil {
+		t.Fatalf("failed to finish the archive: %s", err)
+	}
+	if err := tarball.Close(); err != nil {
+		t.Fatalf("failed to close the archive: %s", err)
+	}
+}
@@ -62,7 +62,7 @@ func writeCocoServiceFiles(
 	}
 
 	attestationData := templates.AttestationServiceTemplateData{
-		NamePrefix: "uyuni",
+		NamePrefix: podman.NamePrefix,
 		Network:    podman.UyuniNetwork,
 		Image:      preparedImage,
 	}
@@ -75,9 +75,9 @@ func writeCocoServiceFiles(
 	}
 
 	environment := fmt.Sprintf(`Environment=UYUNI_IMAGE=%s
-Environment=database_connection=jdbc:postgresql://uyuni-server.mgr.internal:%d/%s
+Environment=database_connection=jdbc:postgresql://%s.mgr.internal:%d/%s
 Environment=database_user=%s
-Environment=database_password=%s`, preparedImage, dbPort, dbName, dbUser, dbPassword)
+Environment=database_password=%s`, preparedImage, podman.ServerContainerName, dbPort, dbName, dbUser, dbPassword)
 
 	if err := podman.GenerateSystemdConfFile(
 		podman.ServerAttestationService+"@", "generated.conf", environment, true,
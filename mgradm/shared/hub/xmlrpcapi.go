@@ -77,8 +77,8 @@ func Upgrade(authFile string, registry string, pullPolicy string, tag string, hu
 func generateHubXmlrpcSystemdService(image string) error {
 	hubXmlrpcData := templates.HubXmlrpcServiceTemplateData{
 		Volumes:    utils.HubXmlrpcVolumeMounts,
-		Ports:      utils.HUB_XMLRPC_PORTS,
-		NamePrefix: "uyuni",
+		Ports:      podman.OffsetPorts(utils.HUB_XMLRPC_PORTS),
+		NamePrefix: podman.NamePrefix,
 		Network:    podman.UyuniNetwork,
 		Image:      image,
 	}
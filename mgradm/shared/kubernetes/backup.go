@@ -0,0 +1,273 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// BackupPodName is the name of the helper pod mounting the server volumes during backup and restore.
+const BackupPodName = "uyuni-backup"
+
+const backupPodFilter = "-lapp=" + BackupPodName
+
+// PreRestoreDir is the folder of a claim keeping its previous content during a restore.
+const PreRestoreDir = ".pre-restore"
+
+// backupMountDir is the folder where the claims are mounted in the backup pod.
+const backupMountDir = "/mnt"
+
+// ServerClaims returns the persistent volumes claims of the server without duplicates.
+func ServerClaims() []types.Volume {
+	claims := []types.Volume{}
+	names := []string{}
+	for _, volume := range utils.ServerVolumes {
+		if volume.PersistentVolumeClaim == nil || utils.Contains(names, volume.Name) {
+			continue
+		}
+		names = append(names, volume.Name)
+		claims = append(claims, volume)
+	}
+	return claims
+}
+
+// GetServerPlacement returns the number of replicas of the server deployment and the node running the server.
+//
+// The backup pod needs to run on the same node to access the claims. The node name is empty if the server
+// is scaled down: the backup pod is then placed by the cluster according to the claims.
+func GetServerPlacement(namespace string) (uint, string, error) {
+	replicas, err := kubernetes.GetReplicas(namespace, kubernetes.ServerApp)
+	if err != nil {
+		return 0, "", err
+	}
+	if replicas == 0 {
+		return 0, "", nil
+	}
+	nodeName, err := kubernetes.GetNode(namespace, kubernetes.ServerFilter)
+	if err != nil {
+		return 0, "", utils.Errorf(err, L("cannot find node running uyuni"))
+	}
+	return replicas, nodeName, nil
+}
+
+// StartBackupPod starts a pod on nodeName mounting all the server claims of namespace.
+//
+// The server needs to be scaled down before as the claims may not be shared between pods.
+// The pod needs to be removed with StopBackupPod once done.
+func StartBackupPod(namespace string, image string, pullPolicy string, nodeName string) error {
+	// Delete a pod left over by a previous failed run
+	if err := StopBackupPod(namespace); err != nil {
+		return err
+	}
+
+	claims := ServerClaims()
+	mounts := make([]types.VolumeMount, len(claims))
+	for i, claim := range claims {
+		mounts[i] = types.VolumeMount{MountPath: claimMountPath(claim.Name), Name: claim.Name}
+	}
+
+	deployData := types.Deployment{
+		APIVersion: "v1",
+		Spec: &types.Spec{
+			RestartPolicy: "Never",
+			NodeName:      nodeName,
+			Containers: []types.Container{
+				{
+					Name:         BackupPodName,
+					VolumeMounts: mounts,
+					Image:        image,
+				},
+			},
+			Volumes: claims,
+		},
+	}
+	override, err := kubernetes.GenerateOverrideDeployment(deployData)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg(L("Starting the backup pod"))
+	err = kubernetes.StartPod(namespace, BackupPodName, backupPodFilter, image, kubernetes.GetPullPolicy(pullPolicy), override,
+		"sleep", "infinity",
+	)
+	if err != nil {
+		return utils.Errorf(err, L("cannot start the backup pod"))
+	}
+	return nil
+}
+
+// StopBackupPod deletes the backup pod.
+func StopBackupPod(namespace string) error {
+	return kubernetes.DeletePod(namespace, BackupPodName, backupPodFilter)
+}
+
+// ReadClaim streams the content of a server claim from the backup pod and calls fn for each entry.
+//
+// The entries names are relative to the root of the claim, the root entry itself is named "./".
+func ReadClaim(namespace string, claim string, fn func(header *tar.Header, reader io.Reader) error) error {
+	pipeReader, pipeWriter := io.Pipe()
+	execErr := make(chan error, 1)
+	go func() {
+		err := execInBackupPod(namespace, nil, pipeWriter, "tar", "--numeric-owner", "-C", claimMountPath(claim), "-cf", "-", ".")
+		pipeWriter.CloseWithError(err)
+		execErr <- err
+	}()
+
+	tarReader := tar.NewReader(pipeReader)
+	var err error
+	for {
+		var header *tar.Header
+		header, err = tarReader.Next()
+		if err != nil {
+			break
+		}
+		if err = fn(header, tarReader); err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	// Unblock the tar process if we stopped reading before the end
+	pipeReader.Close()
+
+	if cmdErr := <-execErr; cmdErr != nil && err == nil {
+		err = cmdErr
+	}
+	if err != nil {
+		return utils.Errorf(err, L("failed to read the content of claim %s"), claim)
+	}
+	return nil
+}
+
+// ClaimWriter streams tar entries to a server claim in the backup pod.
+type ClaimWriter struct {
+	claim      string
+	pipeWriter *io.PipeWriter
+	tarWriter  *tar.Writer
+	execErr    chan error
+}
+
+// NewClaimWriter starts extracting the entries written to a server claim.
+//
+// The claim is expected to be empty: use MoveClaimAside before.
+// The returned writer needs to be closed to finish the extraction.
+func NewClaimWriter(namespace string, claim string) (*ClaimWriter, error) {
+	mountPath := claimMountPath(claim)
+	pipeReader, pipeWriter := io.Pipe()
+	writer := ClaimWriter{
+		claim:      claim,
+		pipeWriter: pipeWriter,
+		tarWriter:  tar.NewWriter(pipeWriter),
+		execErr:    make(chan error, 1),
+	}
+	go func() {
+		err := execInBackupPod(namespace, pipeReader, io.Discard, "tar", "--numeric-owner", "-C", mountPath, "-xpf", "-")
+		pipeReader.CloseWithError(err)
+		writer.execErr <- err
+	}()
+	return &writer, nil
+}
+
+// WriteEntry writes an entry in the claim.
+//
+// The name is relative to the root of the claim, an empty name refers to the root of the claim.
+func (w *ClaimWriter) WriteEntry(header *tar.Header, reader io.Reader, name string) error {
+	entry := *header
+	entry.Name = "./" + strings.TrimPrefix(name, "/")
+	if err := w.tarWriter.WriteHeader(&entry); err != nil {
+		return utils.Errorf(err, L("failed to write %[1]s to claim %[2]s"), name, w.claim)
+	}
+	if _, err := io.Copy(w.tarWriter, reader); err != nil {
+		return utils.Errorf(err, L("failed to write %[1]s to claim %[2]s"), name, w.claim)
+	}
+	return nil
+}
+
+// Close finishes the extraction and waits for it to complete.
+func (w *ClaimWriter) Close() error {
+	err := w.tarWriter.Close()
+	w.pipeWriter.Close()
+	if cmdErr := <-w.execErr; cmdErr != nil {
+		err = cmdErr
+	}
+	if err != nil {
+		return utils.Errorf(err, L("failed to extract the content of claim %s"), w.claim)
+	}
+	return nil
+}
+
+// MoveClaimAside moves the content of a server claim to a folder of the claim to restore a backup in it.
+//
+// The previous content needs to be removed with DiscardClaimAside or put back with PutBackClaimAside.
+func MoveClaimAside(namespace string, claim string) error {
+	script := fmt.Sprintf(`cd %[1]s && mkdir %[2]s && `+
+		`find . -mindepth 1 -maxdepth 1 ! -name %[2]s -exec mv -t %[2]s {} +`,
+		utils.ShellQuote(claimMountPath(claim)), PreRestoreDir)
+	if err := execInBackupPod(namespace, nil, io.Discard, "sh", "-c", script); err != nil {
+		return utils.Errorf(err, L("failed to move the content of claim %[1]s to its %[2]s folder"), claim, PreRestoreDir)
+	}
+	return nil
+}
+
+// DiscardClaimAside removes the previous content of a server claim moved by MoveClaimAside.
+func DiscardClaimAside(namespace string, claim string) error {
+	if err := execInBackupPod(namespace, nil, io.Discard, "rm", "-rf", path.Join(claimMountPath(claim), PreRestoreDir)); err != nil {
+		return utils.Errorf(err, L("failed to remove the %[1]s folder of claim %[2]s"), PreRestoreDir, claim)
+	}
+	return nil
+}
+
+// PutBackClaimAside replaces the content of a server claim by its previous content moved by MoveClaimAside.
+func PutBackClaimAside(namespace string, claim string) error {
+	script := fmt.Sprintf(`cd %[1]s && test -d %[2]s && `+
+		`find . -mindepth 1 -maxdepth 1 ! -name %[2]s -exec rm -rf {} + && `+
+		`find %[2]s -mindepth 1 -maxdepth 1 -exec mv -t . {} + && rmdir %[2]s`,
+		utils.ShellQuote(claimMountPath(claim)), PreRestoreDir)
+	if err := execInBackupPod(namespace, nil, io.Discard, "sh", "-c", script); err != nil {
+		return utils.Errorf(err, L("failed to put back the content of claim %[1]s from its %[2]s folder"),
+			claim, PreRestoreDir)
+	}
+	return nil
+}
+
+func claimMountPath(claim string) string {
+	return path.Join(backupMountDir, claim)
+}
+
+// execInBackupPod runs a command in the backup pod through the Kubernetes API server or kubectl.
+func execInBackupPod(namespace string, stdin io.Reader, stdout io.Writer, command ...string) error {
+	var err error
+	if kubernetes.HasAPIClient() {
+		pod := kubernetes.Pod{Name: BackupPodName, Namespace: namespace}
+		err = kubernetes.ExecInPod(&pod, BackupPodName, stdin, stdout, os.Stderr, command...)
+	} else {
+		args := []string{"exec"}
+		if stdin != nil {
+			args = append(args, "-i")
+		}
+		if namespace != "" {
+			args = append(args, "-n", namespace)
+		}
+		args = append(args, BackupPodName, "--")
+		args = append(args, command...)
+		err = utils.CurrentExecutor().RunStream(stdin, stdout, "kubectl", args...)
+	}
+	if err != nil {
+		return utils.Errorf(err, L("%s command failed in the backup pod"), command[0])
+	}
+	return nil
+}
@@ -143,28 +143,23 @@ func installCertManager(helmFlags *cmd_utils.HelmFlags, kubeconfig string, image
 
 func extractCaCertToConfig() {
 	// TODO Replace with [trust-manager](https://cert-manager.io/docs/projects/trust-manager/) to automate this
-	const jsonPath = "-o=jsonpath={.data.ca\\.crt}"
+	const caKey = "ca.crt"
 
 	log.Info().Msg(L("Extracting CA certificate to a configmap"))
 	// Skip extracting if the configmap is already present
-	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "configmap", "uyuni-ca", jsonPath)
-	log.Info().Msgf(L("CA cert: %s"), string(out))
+	out, err := kubernetes.GetConfigMap("uyuni-ca", caKey)
+	log.Info().Msgf(L("CA cert: %s"), out)
 	if err == nil && len(out) > 0 {
 		log.Info().Msg(L("uyuni-ca configmap already existing, skipping extraction"))
 		return
 	}
 
-	out, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "secret", "uyuni-ca", jsonPath)
+	ca, err := kubernetes.GetSecret("uyuni-ca", caKey)
 	if err != nil {
 		log.Fatal().Err(err).Msgf(L("Failed to get uyuni-ca certificate"))
 	}
 
-	decoded, err := base64.StdEncoding.DecodeString(string(out))
-	if err != nil {
-		log.Fatal().Err(err).Msgf(L("Failed to base64 decode CA certificate"))
-	}
-
-	createCaConfig(decoded)
+	createCaConfig([]byte(ca))
 }
 
 func createCaConfig(ca []byte) {
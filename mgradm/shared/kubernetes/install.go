@@ -177,20 +177,20 @@ func Upgrade(
 
 	//this is needed because folder with script needs to be mounted
 	//check the node before scaling down
-	nodeName, err := kubernetes.GetNode("uyuni")
+	nodeName, err := kubernetes.GetNode("", "uyuni")
 	if err != nil {
 		return utils.Errorf(err, L("cannot find node running uyuni"))
 	}
 
-	err = kubernetes.ReplicasTo(kubernetes.ServerApp, 0)
+	err = kubernetes.ReplicasTo("", kubernetes.ServerApp, 0)
 	if err != nil {
 		return utils.Errorf(err, L("cannot set replica to 0"))
 	}
 
 	defer func() {
 		// if something is running, we don't need to set replicas to 1
-		if _, err = kubernetes.GetNode("uyuni"); err != nil {
-			err = kubernetes.ReplicasTo(kubernetes.ServerApp, 1)
+		if _, err = kubernetes.GetNode("", "uyuni"); err != nil {
+			err = kubernetes.ReplicasTo("", kubernetes.ServerApp, 1)
 		}
 	}()
 	if inspectedValues.ImagePgVersion > inspectedValues.CurrentPgVersion {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	adm_utils "github.com/uyuni-project/uyuni-tools/mgradm/shared/utils"
@@ -40,17 +41,9 @@ func RunPgsqlVersionUpgrade(registry string, image types.ImageFlags, upgradeImag
 
 		pgsqlVersionUpgradeContainer := "uyuni-upgrade-pgsql"
 
-		upgradeImageUrl := ""
-		if upgradeImage.Name == "" {
-			upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, image, fmt.Sprintf("-migration-%s-%s", oldPgsql, newPgsql))
-			if err != nil {
-				return utils.Errorf(err, L("failed to compute image URL"))
-			}
-		} else {
-			upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, upgradeImage)
-			if err != nil {
-				return utils.Errorf(err, L("failed to compute image URL"))
-			}
+		upgradeImageUrl, err := ComputeMigrationImage(registry, image, upgradeImage, oldPgsql, newPgsql)
+		if err != nil {
+			return err
 		}
 
 		log.Info().Msgf(L("Using database upgrade image %s"), upgradeImageUrl)
@@ -60,7 +53,7 @@ func RunPgsqlVersionUpgrade(registry string, image types.ImageFlags, upgradeImag
 		}
 
 		//delete pending pod and then check the node, because in presence of more than a pod GetNode return is wrong
-		if err := kubernetes.DeletePod(pgsqlVersionUpgradeContainer, kubernetes.ServerFilter); err != nil {
+		if err := kubernetes.DeletePod("", pgsqlVersionUpgradeContainer, kubernetes.ServerFilter); err != nil {
 			return utils.Errorf(err, L("cannot delete %s"), pgsqlVersionUpgradeContainer)
 		}
 
@@ -96,6 +89,44 @@ func RunPgsqlVersionUpgrade(registry string, image types.ImageFlags, upgradeImag
 	return nil
 }
 
+// ComputeMigrationImage returns the name of the image upgrading the database from oldPgsql to newPgsql.
+func ComputeMigrationImage(
+	registry string,
+	image types.ImageFlags,
+	upgradeImage types.ImageFlags,
+	oldPgsql string,
+	newPgsql string,
+) (string, error) {
+	var upgradeImageUrl string
+	var err error
+	if upgradeImage.Name == "" {
+		upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, image, fmt.Sprintf("-migration-%s-%s", oldPgsql, newPgsql))
+	} else {
+		upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, upgradeImage)
+	}
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to compute image URL"))
+	}
+	return upgradeImageUrl, nil
+}
+
+// InspectImagePgVersion returns the PostgreSQL major version of a server image.
+//
+// The image is pulled and run by the cluster: no server needs to be deployed.
+func InspectImagePgVersion(serverImage string, pullPolicy string) (string, error) {
+	const podName = "uyuni-inspect-pgsql"
+
+	if err := kubernetes.DeletePod("", podName, kubernetes.ServerFilter); err != nil {
+		return "", utils.Errorf(err, L("cannot delete %s"), podName)
+	}
+	out, err := kubernetes.RunPodOutput(podName, kubernetes.ServerFilter, serverImage, pullPolicy,
+		"sh", "-c", utils.ImagePgVersionCommand)
+	if err != nil {
+		return "", utils.Errorf(err, L("cannot find the PostgreSQL version of image %s"), serverImage)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // RunPgsqlFinalizeScript run the script with all the action required to a db after upgrade.
 func RunPgsqlFinalizeScript(
 	serverImage string, pullPolicy string, nodeName string, schemaUpdateRequired bool, migration bool,
@@ -111,7 +142,7 @@ func RunPgsqlFinalizeScript(
 		return utils.Errorf(err, L("cannot generate PostgreSQL finalization script"))
 	}
 	//delete pending pod and then check the node, because in presence of more than a pod GetNode return is wrong
-	if err := kubernetes.DeletePod(pgsqlFinalizeContainer, kubernetes.ServerFilter); err != nil {
+	if err := kubernetes.DeletePod("", pgsqlFinalizeContainer, kubernetes.ServerFilter); err != nil {
 		return utils.Errorf(err, L("cannot delete %s"), pgsqlFinalizeContainer)
 	}
 	//generate deploy data
@@ -157,7 +188,7 @@ func RunPostUpgradeScript(serverImage string, pullPolicy string, nodeName string
 	}
 
 	//delete pending pod and then check the node, because in presence of more than a pod GetNode return is wrong
-	if err := kubernetes.DeletePod(postUpgradeContainer, kubernetes.ServerFilter); err != nil {
+	if err := kubernetes.DeletePod("", postUpgradeContainer, kubernetes.ServerFilter); err != nil {
 		return utils.Errorf(err, L("cannot delete %s"), postUpgradeContainer)
 	}
 	//generate deploy data
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+)
+
+// ClaimInfo describes a server persistent volume claim.
+type ClaimInfo struct {
+	Name         string `json:"name"`
+	Claim        string `json:"claim"`
+	Status       string `json:"status,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	Requested    string `json:"requested,omitempty"`
+	Capacity     string `json:"capacity,omitempty"`
+	Volume       string `json:"volume,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// GetClaimsInfo inspects the persistent volume claims of the server volumes in namespace.
+func GetClaimsInfo(namespace string) ([]ClaimInfo, error) {
+	pvcs, err := kubernetes.GetPersistentVolumeClaims(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return newClaimsInfo(pvcs), nil
+}
+
+func newClaimsInfo(pvcs []kubernetes.PersistentVolumeClaim) []ClaimInfo {
+	claims := []ClaimInfo{}
+	for _, volume := range ServerClaims() {
+		info := ClaimInfo{Name: volume.Name, Claim: volume.PersistentVolumeClaim.ClaimName}
+		found := false
+		for _, pvc := range pvcs {
+			if pvc.Name != info.Claim {
+				continue
+			}
+			found = true
+			info.Status = pvc.Phase
+			info.StorageClass = pvc.StorageClass
+			info.Requested = pvc.Requested
+			info.Capacity = pvc.Capacity
+			info.Volume = pvc.Volume
+			break
+		}
+		if !found {
+			info.Error = L("claim not found")
+		}
+		claims = append(claims, info)
+	}
+	return claims
+}
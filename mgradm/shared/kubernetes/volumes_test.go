@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestNewClaimsInfo(t *testing.T) {
+	pvcs := []kubernetes.PersistentVolumeClaim{
+		{
+			Name:         "var-pgsql",
+			StorageClass: "local-path",
+			Volume:       "pvc-1234",
+			Requested:    "50Gi",
+			Capacity:     "50Gi",
+			Phase:        "Bound",
+		},
+		{Name: "other"},
+	}
+
+	claims := newClaimsInfo(pvcs)
+	test_utils.AssertEquals(t, "wrong number of claims", len(ServerClaims()), len(claims))
+
+	for _, claim := range claims {
+		if claim.Name != "var-pgsql" {
+			test_utils.AssertEquals(t, "missing claim not reported for "+claim.Name, "claim not found", claim.Error)
+			continue
+		}
+		test_utils.AssertEquals(t, "wrong status", "Bound", claim.Status)
+		test_utils.AssertEquals(t, "wrong storage class", "local-path", claim.StorageClass)
+		test_utils.AssertEquals(t, "wrong requested size", "50Gi", claim.Requested)
+		test_utils.AssertEquals(t, "wrong capacity", "50Gi", claim.Capacity)
+		test_utils.AssertEquals(t, "wrong volume", "pvc-1234", claim.Volume)
+		test_utils.AssertEquals(t, "unexpected error", "", claim.Error)
+	}
+}
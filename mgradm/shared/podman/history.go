@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// deploymentHistoryPath returns the file storing the history of the server instance deployments.
+func deploymentHistoryPath() string {
+	return path.Join(podman.InstanceStateFolder(), "deployments.json")
+}
+
+// maxDeployments is the number of deployments to keep in the history.
+const maxDeployments = 10
+
+// Deployment describes the images deployed for the server at a given time.
+type Deployment struct {
+	Date             time.Time `json:"date"`
+	ServerImage      string    `json:"serverImage"`
+	HubXmlrpcImage   string    `json:"hubXmlrpcImage,omitempty"`
+	AttestationImage string    `json:"attestationImage,omitempty"`
+	PgVersion        string    `json:"pgVersion"`
+	// Snapshot is the state of the server volumes before this deployment, if kept.
+	Snapshot *UpgradeSnapshot `json:"snapshot,omitempty"`
+}
+
+// DeploymentHistory lists the server deployments, the last one being the current one.
+type DeploymentHistory struct {
+	Deployments []Deployment `json:"deployments"`
+}
+
+// LoadDeploymentHistory reads the history of the server deployments.
+//
+// An empty history is returned if the history file doesn't exist yet.
+func LoadDeploymentHistory() (*DeploymentHistory, error) {
+	history := DeploymentHistory{Deployments: []Deployment{}}
+	historyPath := deploymentHistoryPath()
+	content, err := utils.ReadHostFile(historyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &history, nil
+	} else if err != nil {
+		return nil, utils.Errorf(err, L("failed to read %s"), historyPath)
+	}
+
+	if err := json.Unmarshal(content, &history); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse %s"), historyPath)
+	}
+	return &history, nil
+}
+
+// Save writes the history of the server deployments.
+func (h *DeploymentHistory) Save() error {
+	content, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the deployment history"))
+	}
+	historyPath := deploymentHistoryPath()
+	if err := utils.MkdirHostAll(path.Dir(historyPath), 0700); err != nil {
+		return utils.Errorf(err, L("failed to create %s folder"), path.Dir(historyPath))
+	}
+	if err := utils.WriteHostFile(historyPath, content, 0600); err != nil {
+		return utils.Errorf(err, L("cannot write %s file"), historyPath)
+	}
+	return nil
+}
+
+// Current returns the current deployment or nil if the history is empty.
+func (h *DeploymentHistory) Current() *Deployment {
+	if len(h.Deployments) == 0 {
+		return nil
+	}
+	return &h.Deployments[len(h.Deployments)-1]
+}
+
+// Previous returns the deployment before the current one or nil if there is none.
+func (h *DeploymentHistory) Previous() *Deployment {
+	if len(h.Deployments) < 2 {
+		return nil
+	}
+	return &h.Deployments[len(h.Deployments)-2]
+}
+
+// Add appends a deployment to the history.
+//
+// The oldest deployments are dropped from the history and only the snapshot of the new deployment is kept.
+func (h *DeploymentHistory) Add(deployment Deployment) {
+	if deployment.Snapshot != nil {
+		h.RemoveSnapshots()
+	}
+	h.Deployments = append(h.Deployments, deployment)
+	if len(h.Deployments) > maxDeployments {
+		h.Deployments = h.Deployments[len(h.Deployments)-maxDeployments:]
+	}
+}
+
+// DropCurrent removes the current deployment from the history.
+func (h *DeploymentHistory) DropCurrent() {
+	if len(h.Deployments) > 0 {
+		h.Deployments = h.Deployments[:len(h.Deployments)-1]
+	}
+}
+
+// RemoveSnapshots deletes the snapshots kept in the history.
+func (h *DeploymentHistory) RemoveSnapshots() {
+	for i := range h.Deployments {
+		snapshot := h.Deployments[i].Snapshot
+		if snapshot == nil {
+			continue
+		}
+		if err := snapshot.Remove(); err != nil {
+			log.Warn().Err(err).Msg(L("failed to remove the upgrade snapshot"))
+		}
+		h.Deployments[i].Snapshot = nil
+	}
+}
+
+// CurrentDeployment describes the currently configured server deployment.
+func CurrentDeployment() Deployment {
+	return Deployment{
+		Date:             time.Now().UTC(),
+		ServerImage:      podman.GetServiceImage(podman.ServerService),
+		HubXmlrpcImage:   podman.GetServiceImage(podman.HubXmlrpcService + "@"),
+		AttestationImage: podman.GetServiceImage(podman.ServerAttestationService + "@"),
+		PgVersion:        CurrentPgVersion(),
+	}
+}
+
+// CurrentPgVersion returns the major version of the PostgreSQL data stored in the database volume.
+func CurrentPgVersion() string {
+	mountPoint, err := GetMountPoint(podman.InstanceVolume("var-pgsql"))
+	if err != nil {
+		log.Debug().Err(err).Msg("cannot find the database volume")
+		return ""
+	}
+	content, err := utils.ReadHostFile(path.Join(mountPoint, "data", "PG_VERSION"))
+	if err != nil {
+		log.Debug().Err(err).Msg("cannot read the PostgreSQL data version")
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// RecordInstallation starts a new history with the current server deployment.
+func RecordInstallation() error {
+	// A previous history may remain from an uninstalled server
+	if history, err := LoadDeploymentHistory(); err == nil {
+		history.RemoveSnapshots()
+	}
+
+	history := DeploymentHistory{Deployments: []Deployment{}}
+	history.Add(CurrentDeployment())
+	return history.Save()
+}
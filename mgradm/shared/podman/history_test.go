@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestDeploymentHistory(t *testing.T) {
+	history := DeploymentHistory{Deployments: []Deployment{}}
+	test_utils.AssertTrue(t, "empty history has a current deployment", history.Current() == nil)
+	test_utils.AssertTrue(t, "empty history has a previous deployment", history.Previous() == nil)
+
+	for i := 0; i < maxDeployments+2; i++ {
+		history.Add(Deployment{ServerImage: fmt.Sprintf("server:%d", i), PgVersion: "16"})
+	}
+	test_utils.AssertEquals(t, "history not pruned", maxDeployments, len(history.Deployments))
+	test_utils.AssertEquals(t, "oldest deployment not dropped", "server:2", history.Deployments[0].ServerImage)
+	test_utils.AssertEquals(t, "wrong current deployment",
+		fmt.Sprintf("server:%d", maxDeployments+1), history.Current().ServerImage)
+	test_utils.AssertEquals(t, "wrong previous deployment",
+		fmt.Sprintf("server:%d", maxDeployments), history.Previous().ServerImage)
+
+	history.DropCurrent()
+	test_utils.AssertEquals(t, "current deployment not dropped",
+		fmt.Sprintf("server:%d", maxDeployments), history.Current().ServerImage)
+}
+
+func TestDeploymentHistorySaveLoad(t *testing.T) {
+	testDir, cleaner := test_utils.CreateTmpFolder(t)
+	defer cleaner()
+
+	defer podman.SetStateFolder(path.Join(testDir, "uyuni-tools"))()
+
+	history, err := LoadDeploymentHistory()
+	if err != nil {
+		t.Fatalf("failed to load missing history: %s", err)
+	}
+	test_utils.AssertEquals(t, "missing history not empty", 0, len(history.Deployments))
+
+	history.Add(Deployment{ServerImage: "server:1", PgVersion: "14"})
+	history.Add(Deployment{
+		ServerImage:    "server:2",
+		HubXmlrpcImage: "hub:2",
+		PgVersion:      "16",
+		Snapshot: &UpgradeSnapshot{
+			Image:   "server:1",
+			Volumes: []*volumeSnapshot{{Name: "var-pgsql", Kind: snapshotCopy, Path: "/snapshot"}},
+		},
+	})
+	if err := history.Save(); err != nil {
+		t.Fatalf("failed to save history: %s", err)
+	}
+
+	loaded, err := LoadDeploymentHistory()
+	if err != nil {
+		t.Fatalf("failed to load history: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of deployments", 2, len(loaded.Deployments))
+	test_utils.AssertEquals(t, "wrong hub image", "hub:2", loaded.Current().HubXmlrpcImage)
+	test_utils.AssertEquals(t, "wrong previous PostgreSQL version", "14", loaded.Previous().PgVersion)
+	test_utils.AssertEquals(t, "wrong snapshot image", "server:1", loaded.Current().Snapshot.Image)
+	test_utils.AssertEquals(t, "wrong snapshot path", "/snapshot", loaded.Current().Snapshot.Volumes[0].Path)
+
+	// Each instance has its own history
+	restore, err := podman.SetInstance("staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer restore()
+	instanceHistory, err := LoadDeploymentHistory()
+	if err != nil {
+		t.Fatalf("failed to load instance history: %s", err)
+	}
+	test_utils.AssertEquals(t, "instance history not empty", 0, len(instanceHistory.Deployments))
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	previous := Deployment{ServerImage: "server:1", PgVersion: "14"}
+
+	// No snapshot is needed without PostgreSQL major version change
+	current := Deployment{ServerImage: "server:2", PgVersion: "14"}
+	snapshot, err := rollbackSnapshot(&current, &previous)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "unexpected snapshot", snapshot == nil)
+
+	// The snapshot is required after a PostgreSQL major version upgrade
+	current.PgVersion = "16"
+	if _, err := rollbackSnapshot(&current, &previous); err == nil {
+		t.Error("Expected an error without snapshot")
+	}
+	current.Snapshot = &UpgradeSnapshot{Image: "server:0"}
+	if _, err := rollbackSnapshot(&current, &previous); err == nil {
+		t.Error("Expected an error with the snapshot of another image")
+	}
+}
+
+func TestDiscardSnapshots(t *testing.T) {
+	testDir := t.TempDir()
+	defer podman.SetStateFolder(path.Join(testDir, "uyuni-tools"))()
+
+	snapshotDir := path.Join(testDir, "snapshot")
+	if err := os.Mkdir(snapshotDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	history := DeploymentHistory{Deployments: []Deployment{}}
+	history.Add(Deployment{ServerImage: "server:1", PgVersion: "14"})
+	history.Add(Deployment{
+		ServerImage: "server:2",
+		PgVersion:   "16",
+		Snapshot: &UpgradeSnapshot{
+			Image:   "server:1",
+			Volumes: []*volumeSnapshot{{Name: "var-pgsql", Kind: snapshotCopy, Path: snapshotDir}},
+		},
+	})
+	if err := history.Save(); err != nil {
+		t.Fatalf("failed to save history: %s", err)
+	}
+
+	if err := DiscardSnapshots(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "snapshot not removed", !utils.FileExists(snapshotDir))
+	loaded, err := LoadDeploymentHistory()
+	if err != nil {
+		t.Fatalf("failed to load history: %s", err)
+	}
+	test_utils.AssertTrue(t, "snapshot still in the history", loaded.Current().Snapshot == nil)
+	test_utils.AssertEquals(t, "deployment dropped", 2, len(loaded.Deployments))
+}
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -45,7 +44,15 @@ func GetExposedPorts(debug bool) []types.PortMap {
 }
 
 // GenerateSystemdService creates a serverY systemd file.
-func GenerateSystemdService(tz string, image string, debug bool, mirrorPath string, podmanArgs []string) error {
+// portsOffset is added to the ports exposed on the host and kept for the later commands.
+func GenerateSystemdService(
+	tz string,
+	image string,
+	debug bool,
+	mirrorPath string,
+	podmanArgs []string,
+	portsOffset int,
+) error {
 	ipv6Enabled, err := podman.SetupNetwork(false)
 	if err != nil {
 		return utils.Errorf(err, L("cannot setup network"))
@@ -59,24 +66,28 @@ func GenerateSystemdService(tz string, image string, debug bool, mirrorPath stri
 	}
 
 	ports := GetExposedPorts(debug)
-	if _, err := exec.LookPath("csp-billing-adapter"); err == nil {
+	if utils.IsInstalled("csp-billing-adapter") {
 		ports = append(ports, utils.NewPortMap("csp-billing", 18888, 18888))
 		args = append(args, "-e ISPAYG=1")
 	}
 
+	if err := podman.SetPortsOffset(portsOffset, ports); err != nil {
+		return err
+	}
+
 	data := templates.PodmanServiceTemplateData{
 		Volumes:     utils.ServerVolumeMounts,
-		NamePrefix:  "uyuni",
+		NamePrefix:  podman.NamePrefix,
 		Args:        strings.Join(args, " "),
-		Ports:       ports,
+		Ports:       podman.OffsetPorts(ports),
 		Network:     podman.UyuniNetwork,
 		IPV6Enabled: ipv6Enabled,
 	}
-	if err := utils.WriteTemplateToFile(data, podman.GetServicePath("uyuni-server"), 0555, false); err != nil {
+	if err := utils.WriteTemplateToFile(data, podman.GetServicePath(podman.ServerService), 0555, false); err != nil {
 		return utils.Errorf(err, L("failed to generate systemd service unit file"))
 	}
 
-	if err := podman.GenerateSystemdConfFile("uyuni-server", "generated.conf",
+	if err := podman.GenerateSystemdConfFile(podman.ServerService, "generated.conf",
 		"Environment=UYUNI_IMAGE="+image, true,
 	); err != nil {
 		return utils.Errorf(err, L("cannot generate systemd conf file"))
@@ -86,7 +97,7 @@ func GenerateSystemdService(tz string, image string, debug bool, mirrorPath stri
 Environment="PODMAN_EXTRA_ARGS=%s"
 `, strings.TrimSpace(tz), strings.Join(podmanArgs, " "))
 
-	if err := podman.GenerateSystemdConfFile("uyuni-server", "custom.conf", config, false); err != nil {
+	if err := podman.GenerateSystemdConfFile(podman.ServerService, "custom.conf", config, false); err != nil {
 		return utils.Errorf(err, L("cannot generate systemd user configuration file"))
 	}
 	return podman.ReloadDaemon(false)
@@ -98,7 +109,7 @@ func UpdateSslCertificate(cnx *shared.Connection, chain *ssl.CaChain, serverPair
 
 	// Copy the CAs, certificate and key to the container
 	const certDir = "/tmp/uyuni-tools"
-	if err := utils.RunCmd("podman", "exec", podman.ServerContainerName, "mkdir", "-p", certDir); err != nil {
+	if _, err := cnx.Exec("mkdir", "-p", certDir); err != nil {
 		return fmt.Errorf(L("failed to create temporary folder on container to copy certificates to"))
 	}
 
@@ -109,9 +120,6 @@ func UpdateSslCertificate(cnx *shared.Connection, chain *ssl.CaChain, serverPair
 	log.Debug().Msgf("Intermediate CA flags: %v", chain.Intermediate)
 
 	args := []string{
-		"exec",
-		podman.ServerContainerName,
-		"mgr-ssl-cert-setup",
 		"-vvv",
 		"--root-ca-file", rootCaPath,
 		"--server-cert-file", serverCrtPath,
@@ -138,28 +146,28 @@ func UpdateSslCertificate(cnx *shared.Connection, chain *ssl.CaChain, serverPair
 	}
 
 	// Check and install then using mgr-ssl-cert-setup
-	if _, err := utils.RunCmdOutput(zerolog.InfoLevel, "podman", args...); err != nil {
+	if _, err := cnx.Exec("mgr-ssl-cert-setup", args...); err != nil {
 		return errors.New(L("failed to update SSL certificate"))
 	}
 
 	// Clean the copied files and the now useless ssl-build
-	if err := utils.RunCmd("podman", "exec", podman.ServerContainerName, "rm", "-rf", certDir); err != nil {
+	if _, err := cnx.Exec("rm", "-rf", certDir); err != nil {
 		return errors.New(L("failed to remove copied certificate files in the container"))
 	}
 
 	const sslbuildPath = "/root/ssl-build"
 	if cnx.TestExistenceInPod(sslbuildPath) {
-		if err := utils.RunCmd("podman", "exec", podman.ServerContainerName, "rm", "-rf", sslbuildPath); err != nil {
+		if _, err := cnx.Exec("rm", "-rf", sslbuildPath); err != nil {
 			return errors.New(L("failed to remove now useless ssl-build folder in the container"))
 		}
 	}
 
 	// The services need to be restarted
 	log.Info().Msg(L("Restarting services after updating the certificate"))
-	if err := utils.RunCmd("podman", "exec", podman.ServerContainerName, "systemctl", "restart", "postgresql.service"); err != nil {
+	if _, err := cnx.Exec("systemctl", "restart", "postgresql.service"); err != nil {
 		return err
 	}
-	return utils.RunCmdStdMapping(zerolog.DebugLevel, "podman", "exec", podman.ServerContainerName, "spacewalk-service", "restart")
+	return cnx.ExecStream(nil, os.Stdout, "spacewalk-service", "restart")
 }
 
 // RunMigration migrate an existing remote server to a container.
@@ -171,12 +179,12 @@ func RunMigration(
 	sourceFqdn string,
 	user string,
 	prepare bool,
+	resume bool,
 ) (*utils.InspectResult, error) {
-	scriptDir, err := adm_utils.GenerateMigrationScript(sourceFqdn, user, false, prepare)
+	scriptDir, err := adm_utils.GenerateMigrationScript(sourceFqdn, user, false, prepare, resume)
 	if err != nil {
 		return nil, utils.Errorf(err, L("cannot generate migration script"))
 	}
-	defer os.RemoveAll(scriptDir)
 
 	extraArgs := []string{
 		"--security-opt", "label=disable",
@@ -219,6 +227,28 @@ func RunMigration(
 	return extractedData, nil
 }
 
+// ComputeMigrationImage returns the name of the image upgrading the database from oldPgsql to newPgsql.
+func ComputeMigrationImage(
+	registry string,
+	image types.ImageFlags,
+	upgradeImage types.ImageFlags,
+	oldPgsql string,
+	newPgsql string,
+) (string, error) {
+	var upgradeImageUrl string
+	var err error
+	if upgradeImage.Name == "" {
+		upgradeImageUrl, err = utils.ComputeImage(registry, utils.DefaultTag, image,
+			fmt.Sprintf("-migration-%s-%s", oldPgsql, newPgsql))
+	} else {
+		upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, upgradeImage)
+	}
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to compute image URL"))
+	}
+	return upgradeImageUrl, nil
+}
+
 // RunPgsqlVersionUpgrade perform a PostgreSQL major upgrade.
 func RunPgsqlVersionUpgrade(
 	authFile string,
@@ -230,11 +260,11 @@ func RunPgsqlVersionUpgrade(
 ) error {
 	log.Info().Msgf(L("Previous PostgreSQL is %[1]s, new one is %[2]s. Performing a DB version upgrade…"), oldPgsql, newPgsql)
 
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	defer os.RemoveAll(scriptDir)
+	scriptDir, err := utils.MkdirHostTemp("mgradm-*")
 	if err != nil {
 		return utils.Errorf(err, L("failed to create temporary directory"))
 	}
+	defer utils.RemoveHostAll(scriptDir)
 	if newPgsql > oldPgsql {
 		pgsqlVersionUpgradeContainer := "uyuni-upgrade-pgsql"
 		extraArgs := []string{
@@ -242,18 +272,9 @@ func RunPgsqlVersionUpgrade(
 			"--security-opt", "label=disable",
 		}
 
-		upgradeImageUrl := ""
-		if upgradeImage.Name == "" {
-			upgradeImageUrl, err = utils.ComputeImage(registry, utils.DefaultTag, image,
-				fmt.Sprintf("-migration-%s-%s", oldPgsql, newPgsql))
-			if err != nil {
-				return utils.Errorf(err, L("failed to compute image URL"))
-			}
-		} else {
-			upgradeImageUrl, err = utils.ComputeImage(registry, image.Tag, upgradeImage)
-			if err != nil {
-				return utils.Errorf(err, L("failed to compute image URL"))
-			}
+		upgradeImageUrl, err := ComputeMigrationImage(registry, image, upgradeImage, oldPgsql, newPgsql)
+		if err != nil {
+			return err
 		}
 
 		preparedImage, err := podman.PrepareImage(authFile, upgradeImageUrl, image.PullPolicy)
@@ -279,11 +300,11 @@ func RunPgsqlVersionUpgrade(
 
 // RunPgsqlFinalizeScript run the script with all the action required to a db after upgrade.
 func RunPgsqlFinalizeScript(serverImage string, schemaUpdateRequired bool, migration bool) error {
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	defer os.RemoveAll(scriptDir)
+	scriptDir, err := utils.MkdirHostTemp("mgradm-*")
 	if err != nil {
 		return utils.Errorf(err, L("failed to create temporary directory"))
 	}
+	defer utils.RemoveHostAll(scriptDir)
 
 	extraArgs := []string{
 		"-v", scriptDir + ":/var/lib/uyuni-tools/",
@@ -306,11 +327,11 @@ func RunPgsqlFinalizeScript(serverImage string, schemaUpdateRequired bool, migra
 
 // RunPostUpgradeScript run the script with the changes to apply after the upgrade.
 func RunPostUpgradeScript(serverImage string) error {
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	defer os.RemoveAll(scriptDir)
+	scriptDir, err := utils.MkdirHostTemp("mgradm-*")
 	if err != nil {
 		return utils.Errorf(err, L("failed to create temporary directory"))
 	}
+	defer utils.RemoveHostAll(scriptDir)
 	postUpgradeContainer := "uyuni-post-upgrade"
 	extraArgs := []string{
 		"-v", scriptDir + ":/var/lib/uyuni-tools/",
@@ -336,6 +357,7 @@ func Upgrade(
 	upgradeImage types.ImageFlags,
 	cocoImage types.ImageFlags,
 	hubXmlrpcImage types.ImageFlags,
+	snapshot bool,
 ) error {
 	if err := CallCloudGuestRegistryAuth(); err != nil {
 		return err
@@ -362,6 +384,19 @@ func Upgrade(
 		return err
 	}
 
+	if inspectedValues.ImagePgVersion < inspectedValues.CurrentPgVersion {
+		return fmt.Errorf(L("trying to downgrade PostgreSQL from %[1]s to %[2]s"), inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion)
+	}
+
+	history, err := LoadDeploymentHistory()
+	if err != nil {
+		return err
+	}
+	if history.Current() == nil {
+		// Record the deployment before the first upgrade to be able to roll back to it
+		history.Add(CurrentDeployment())
+	}
+
 	if err := podman.StopService(podman.ServerService); err != nil {
 		return utils.Errorf(err, L("cannot stop service"))
 	}
@@ -369,40 +404,55 @@ func Upgrade(
 	defer func() {
 		err = podman.StartService(podman.ServerService)
 	}()
-	if inspectedValues.ImagePgVersion > inspectedValues.CurrentPgVersion {
-		log.Info().Msgf(
-			L("Previous postgresql is %[1]s, instead new one is %[2]s. Performing a DB version upgrade…"),
-			inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion,
-		)
-		if err := RunPgsqlVersionUpgrade(
-			authFile, registry, image, upgradeImage, inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion,
-		); err != nil {
-			return utils.Errorf(err, L("cannot run PostgreSQL version upgrade script"))
-		}
-	} else if inspectedValues.ImagePgVersion == inspectedValues.CurrentPgVersion {
-		log.Info().Msgf(L("Upgrading to %s without changing PostgreSQL version"), inspectedValues.UyuniRelease)
-	} else {
-		return fmt.Errorf(L("trying to downgrade PostgreSQL from %[1]s to %[2]s"), inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion)
-	}
 
-	schemaUpdateRequired := inspectedValues.CurrentPgVersion != inspectedValues.ImagePgVersion
-	if err := RunPgsqlFinalizeScript(preparedImage, schemaUpdateRequired, false); err != nil {
-		return utils.Errorf(err, L("cannot run PostgreSQL finalize script"))
+	var upgradeSnapshot *UpgradeSnapshot
+	if snapshot {
+		// Free the space used by the snapshot of a previous upgrade
+		history.RemoveSnapshots()
+		if err := history.Save(); err != nil {
+			return err
+		}
+		if upgradeSnapshot, err = CreateUpgradeSnapshot(); err != nil {
+			return utils.Errorf(err, L("cannot snapshot the server before the upgrade"))
+		}
 	}
 
-	if err := RunPostUpgradeScript(preparedImage); err != nil {
-		return utils.Errorf(err, L("cannot run post upgrade script"))
+	if err := upgradeData(authFile, registry, image, upgradeImage, preparedImage, inspectedValues); err != nil {
+		if upgradeSnapshot == nil {
+			return err
+		}
+		if rollbackErr := upgradeSnapshot.Rollback(); rollbackErr != nil {
+			return utils.JoinErrors(err, utils.Errorf(rollbackErr, L("failed to roll back the server")))
+		}
+		log.Warn().Msgf(L("The upgrade failed, the server has been rolled back to %s"), upgradeSnapshot.Image)
+		return err
 	}
 
-	if err := podman.CleanSystemdConfFile("uyuni-server"); err != nil {
+	if err := podman.CleanSystemdConfFile(podman.ServerService); err != nil {
 		return err
 	}
 
-	if err := podman.GenerateSystemdConfFile("uyuni-server", "generated.conf",
+	if err := podman.GenerateSystemdConfFile(podman.ServerService, "generated.conf",
 		"Environment=UYUNI_IMAGE="+preparedImage, true,
 	); err != nil {
 		return err
 	}
+
+	// The snapshot is only needed to roll back a PostgreSQL major version upgrade
+	if upgradeSnapshot != nil && inspectedValues.ImagePgVersion == inspectedValues.CurrentPgVersion {
+		if err := upgradeSnapshot.Remove(); err != nil {
+			log.Warn().Err(err).Msg(L("failed to remove the upgrade snapshot"))
+		}
+		upgradeSnapshot = nil
+	}
+
+	deployment := CurrentDeployment()
+	deployment.PgVersion = inspectedValues.ImagePgVersion
+	deployment.Snapshot = upgradeSnapshot
+	history.Add(deployment)
+	if err := history.Save(); err != nil {
+		log.Warn().Err(err).Msg(L("failed to record the deployment"))
+	}
 	log.Info().Msg(L("Waiting for the server to start…"))
 
 	err = coco.Upgrade(authFile, registry, cocoImage, image,
@@ -417,16 +467,70 @@ func Upgrade(
 		return err
 	}
 
+	// The attestation and hub images are only known once upgraded
+	if current := history.Current(); current != nil {
+		current.HubXmlrpcImage = podman.GetServiceImage(podman.HubXmlrpcService + "@")
+		current.AttestationImage = podman.GetServiceImage(podman.ServerAttestationService + "@")
+		if err := history.Save(); err != nil {
+			log.Warn().Err(err).Msg(L("failed to record the deployment"))
+		}
+	}
+
 	return podman.ReloadDaemon(false)
 }
 
+// upgradeData upgrades the database and runs the post upgrade steps on the server volumes.
+func upgradeData(
+	authFile string,
+	registry string,
+	image types.ImageFlags,
+	upgradeImage types.ImageFlags,
+	preparedImage string,
+	inspectedValues *utils.ServerInspectData,
+) error {
+	if inspectedValues.ImagePgVersion > inspectedValues.CurrentPgVersion {
+		log.Info().Msgf(
+			L("Previous postgresql is %[1]s, instead new one is %[2]s. Performing a DB version upgrade…"),
+			inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion,
+		)
+		if err := RunPgsqlVersionUpgrade(
+			authFile, registry, image, upgradeImage, inspectedValues.CurrentPgVersion, inspectedValues.ImagePgVersion,
+		); err != nil {
+			return utils.Errorf(err, L("cannot run PostgreSQL version upgrade script"))
+		}
+	} else {
+		log.Info().Msgf(L("Upgrading to %s without changing PostgreSQL version"), inspectedValues.UyuniRelease)
+	}
+
+	schemaUpdateRequired := inspectedValues.CurrentPgVersion != inspectedValues.ImagePgVersion
+	if err := RunPgsqlFinalizeScript(preparedImage, schemaUpdateRequired, false); err != nil {
+		return utils.Errorf(err, L("cannot run PostgreSQL finalize script"))
+	}
+
+	if err := RunPostUpgradeScript(preparedImage); err != nil {
+		return utils.Errorf(err, L("cannot run post upgrade script"))
+	}
+	return nil
+}
+
 // Inspect check values on a given image and deploy.
 func Inspect(preparedImage string) (*utils.ServerInspectData, error) {
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	defer os.RemoveAll(scriptDir)
+	return inspect(preparedImage, utils.ServerVolumeMounts)
+}
+
+// InspectImage check values on a given image without mounting the server volumes.
+//
+// Only the values coming from the image itself are relevant in the result.
+func InspectImage(preparedImage string) (*utils.ServerInspectData, error) {
+	return inspect(preparedImage, []types.VolumeMount{})
+}
+
+func inspect(preparedImage string, volumes []types.VolumeMount) (*utils.ServerInspectData, error) {
+	scriptDir, err := utils.MkdirHostTemp("mgradm-*")
 	if err != nil {
 		return nil, utils.Errorf(err, L("failed to create temporary directory"))
 	}
+	defer utils.RemoveHostAll(scriptDir)
 
 	inspector := utils.NewServerInspector(scriptDir)
 	if err := inspector.GenerateScript(); err != nil {
@@ -438,7 +542,7 @@ func Inspect(preparedImage string) (*utils.ServerInspectData, error) {
 		"--security-opt", "label=disable",
 	}
 
-	err = podman.RunContainer("uyuni-inspect", preparedImage, utils.ServerVolumeMounts, podmanArgs,
+	err = podman.RunContainer("uyuni-inspect", preparedImage, volumes, podmanArgs,
 		[]string{utils.InspectContainerDirectory + "/" + utils.InspectScriptFilename})
 	if err != nil {
 		return nil, err
@@ -456,7 +560,7 @@ func Inspect(preparedImage string) (*utils.ServerInspectData, error) {
 func CallCloudGuestRegistryAuth() error {
 	cloudguestregistryauth := "cloudguestregistryauth"
 
-	path, err := exec.LookPath(cloudguestregistryauth)
+	path, err := utils.CurrentExecutor().LookPath(cloudguestregistryauth)
 	if err == nil {
 		// the binary is installed
 		return utils.RunCmdStdMapping(zerolog.DebugLevel, path)
@@ -467,10 +571,5 @@ func CallCloudGuestRegistryAuth() error {
 
 // GetMountPoint return folder where a given volume is mounted.
 func GetMountPoint(volumeName string) (string, error) {
-	args := []string{"volume", "inspect", "--format", "{{.Mountpoint}}", volumeName}
-	mountPoint, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", args...)
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSuffix(string(mountPoint), "\n"), nil
+	return podman.GetVolumeMountPoint(volumeName)
 }
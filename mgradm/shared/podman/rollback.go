@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Rollback deploys the server images preceding the current deployment.
+//
+// If the database has been upgraded to a newer PostgreSQL major version since, the volumes are restored
+// from the snapshot taken before the upgrade and the rollback is refused if there is none.
+// Since the changes done after the upgrade are then lost, confirmation is asked unless force is true.
+func Rollback(force bool) error {
+	history, err := LoadDeploymentHistory()
+	if err != nil {
+		return err
+	}
+	current := history.Current()
+	previous := history.Previous()
+	if previous == nil {
+		return errors.New(L("no previous deployment to roll back to"))
+	}
+
+	pgDowngrade := current.PgVersion != previous.PgVersion
+	snapshot, err := rollbackSnapshot(current, previous)
+	if err != nil {
+		return err
+	}
+	if pgDowngrade {
+		log.Warn().Msgf(
+			L("The server volumes will be restored from the snapshot taken on %s: all the changes done since will be lost"),
+			current.Date,
+		)
+		if !force {
+			confirmed, err := utils.YesNo(L("Do you really want to roll back the server"))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				log.Info().Msg(L("Rollback canceled"))
+				return nil
+			}
+		}
+	}
+
+	log.Info().Msgf(L("Rolling back to %[1]s deployed on %[2]s"), previous.ServerImage, previous.Date)
+
+	if err := utils.JoinErrors(
+		podman.StopInstantiated(podman.ServerAttestationService),
+		podman.StopInstantiated(podman.HubXmlrpcService),
+		podman.StopService(podman.ServerService),
+	); err != nil {
+		return utils.Errorf(err, L("cannot stop service"))
+	}
+
+	if pgDowngrade {
+		if err := snapshot.Rollback(); err != nil {
+			return err
+		}
+	} else {
+		if err := podman.SetServiceImage(podman.ServerService, previous.ServerImage); err != nil {
+			return err
+		}
+		if snapshot != nil {
+			if err := snapshot.Remove(); err != nil {
+				log.Warn().Err(err).Msg(L("failed to remove the upgrade snapshot"))
+			}
+		}
+	}
+
+	images := map[string]string{
+		podman.HubXmlrpcService:         previous.HubXmlrpcImage,
+		podman.ServerAttestationService: previous.AttestationImage,
+	}
+	for service, image := range images {
+		if image == "" || !utils.HostFileExists(podman.GetServicePath(service+"@")) {
+			continue
+		}
+		if err := podman.SetServiceImage(service+"@", image); err != nil {
+			return err
+		}
+	}
+
+	if err := podman.ReloadDaemon(false); err != nil {
+		return err
+	}
+
+	history.DropCurrent()
+	if err := history.Save(); err != nil {
+		log.Warn().Err(err).Msg(L("failed to record the deployment"))
+	}
+
+	if err := utils.JoinErrors(
+		podman.StartService(podman.ServerService),
+		podman.StartInstantiated(podman.ServerAttestationService),
+		podman.StartInstantiated(podman.HubXmlrpcService),
+	); err != nil {
+		return utils.Errorf(err, L("cannot start service"))
+	}
+
+	log.Info().Msg(L("Server rolled back"))
+	return nil
+}
+
+// rollbackSnapshot returns the snapshot to restore to roll back from current to previous deployment.
+//
+// The snapshot is only needed if the PostgreSQL major version changed: an error is returned if it is missing.
+// Otherwise the snapshot of the current deployment is returned, if any, to be removed.
+func rollbackSnapshot(current *Deployment, previous *Deployment) (*UpgradeSnapshot, error) {
+	snapshot := current.Snapshot
+	if current.PgVersion == previous.PgVersion {
+		return snapshot, nil
+	}
+	if snapshot != nil && (snapshot.Image != previous.ServerImage || !snapshot.Exists()) {
+		log.Warn().Msg(L("The snapshot of the previous deployment is not usable"))
+		snapshot = nil
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf(
+			L("the database has been upgraded from PostgreSQL %[1]s to %[2]s and no snapshot of the previous state is available"),
+			previous.PgVersion, current.PgVersion,
+		)
+	}
+	return snapshot, nil
+}
+
+// DiscardSnapshots removes the snapshots kept to roll back the upgrades.
+func DiscardSnapshots() error {
+	history, err := LoadDeploymentHistory()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, deployment := range history.Deployments {
+		found = found || deployment.Snapshot != nil
+	}
+	if !found {
+		log.Info().Msg(L("No upgrade snapshot to discard"))
+		return nil
+	}
+
+	history.RemoveSnapshots()
+	if err := history.Save(); err != nil {
+		return err
+	}
+	log.Info().Msg(L("Upgrade snapshot discarded, the last upgrade can only be rolled back if PostgreSQL was not upgraded"))
+	return nil
+}
@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// snapshotDirName is the name of the folder next to the volume data where the volume snapshot is stored.
+const snapshotDirName = "_snapshot"
+
+// lvmSnapshotSuffix is appended to the name of a logical volume to name its snapshot.
+const lvmSnapshotSuffix = "-uyuni-snapshot"
+
+const (
+	snapshotCopy  = "copy"
+	snapshotBtrfs = "btrfs"
+	snapshotLvm   = "lvm"
+)
+
+// UpgradeSnapshot holds the state of the server volumes modified by an upgrade.
+//
+// It is used to roll back the server to its previous state if the upgrade fails.
+// The snapshot can be serialized to be kept after the upgrade.
+type UpgradeSnapshot struct {
+	// Image is the server image before the upgrade.
+	Image          string                  `json:"image"`
+	Volumes        []*volumeSnapshot       `json:"volumes"`
+	LogicalVolumes map[string]*lvmSnapshot `json:"logicalVolumes,omitempty"`
+}
+
+type volumeSnapshot struct {
+	Name       string `json:"name"`
+	MountPoint string `json:"mountPoint"`
+	Kind       string `json:"kind"`
+	// Path is the snapshot folder for copy and btrfs snapshots
+	// or the path of the volume relative to the logical volume mount point for LVM snapshots.
+	Path string `json:"path"`
+	// LogicalVolume is the key of the LVM snapshot in the LogicalVolumes map.
+	LogicalVolume string `json:"logicalVolume,omitempty"`
+}
+
+type lvmSnapshot struct {
+	VolumeGroup string `json:"volumeGroup"`
+	Origin      string `json:"origin"`
+	FsType      string `json:"fsType"`
+	mountDir    string
+}
+
+// CreateUpgradeSnapshot takes a snapshot of the database and configuration volumes of the stopped server.
+//
+// Btrfs subvolumes and logical volumes are snapshotted, the other volumes are copied.
+func CreateUpgradeSnapshot() (*UpgradeSnapshot, error) {
+	// Old installations may still have the image in Service.conf
+	if err := podman.CleanSystemdConfFile(podman.ServerService); err != nil {
+		return nil, err
+	}
+
+	snapshot := UpgradeSnapshot{
+		Image:          podman.GetServiceImage(podman.ServerService),
+		LogicalVolumes: map[string]*lvmSnapshot{},
+	}
+	if snapshot.Image == "" {
+		return nil, fmt.Errorf(L("cannot find the image of the %s service"), podman.ServerService)
+	}
+
+	log.Info().Msg(L("Taking a snapshot of the server volumes before the upgrade…"))
+	for _, volume := range snapshotVolumeNames() {
+		if err := snapshot.addVolume(volume); err != nil {
+			if removeErr := snapshot.Remove(); removeErr != nil {
+				log.Error().Err(removeErr).Msg(L("failed to remove the partial snapshot"))
+			}
+			return nil, utils.Errorf(err, L("failed to snapshot volume %s"), volume)
+		}
+	}
+	return &snapshot, nil
+}
+
+// Exists checks that all the parts of the snapshot are still available.
+func (s *UpgradeSnapshot) Exists() bool {
+	for _, volume := range s.Volumes {
+		if volume.Kind != snapshotLvm && !utils.HostFileExists(volume.Path) {
+			return false
+		}
+	}
+	for _, lvm := range s.LogicalVolumes {
+		if _, err := utils.RunCmdOutput(zerolog.DebugLevel, "lvs", lvm.snapshotName()); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Rollback restores the volumes and the server image from the snapshot and removes the snapshot.
+//
+// The server service needs to be stopped.
+func (s *UpgradeSnapshot) Rollback() error {
+	log.Info().Msg(L("Rolling back the server to its state before the upgrade…"))
+	for _, volume := range s.Volumes {
+		log.Info().Msgf(L("Restoring volume %s"), volume.Name)
+		if err := s.restoreVolume(volume); err != nil {
+			return utils.Errorf(err, L("failed to restore volume %s"), volume.Name)
+		}
+	}
+
+	if err := podman.SetServiceImage(podman.ServerService, s.Image); err != nil {
+		return err
+	}
+	if err := podman.ReloadDaemon(false); err != nil {
+		return err
+	}
+
+	return s.Remove()
+}
+
+// Remove deletes the snapshot.
+func (s *UpgradeSnapshot) Remove() error {
+	var errs error
+	for _, volume := range s.Volumes {
+		var err error
+		switch volume.Kind {
+		case snapshotCopy:
+			err = utils.RemoveHostAll(volume.Path)
+		case snapshotBtrfs:
+			err = utils.RunCmdStdMapping(zerolog.DebugLevel, "btrfs", "subvolume", "delete", volume.Path)
+		}
+		if err != nil {
+			errs = utils.JoinErrors(errs, utils.Errorf(err, L("failed to remove %s"), volume.Path))
+		}
+	}
+
+	for _, lvm := range s.LogicalVolumes {
+		if lvm.mountDir != "" {
+			if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "umount", lvm.mountDir); err != nil {
+				errs = utils.JoinErrors(errs, utils.Errorf(err, L("failed to unmount %s"), lvm.mountDir))
+				continue
+			}
+			_ = utils.RemoveHostFile(lvm.mountDir)
+			lvm.mountDir = ""
+		}
+		lv := lvm.snapshotName()
+		if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "lvremove", "-y", lv); err != nil {
+			errs = utils.JoinErrors(errs, utils.Errorf(err, L("failed to remove logical volume %s"), lv))
+		}
+	}
+	s.Volumes = nil
+	s.LogicalVolumes = map[string]*lvmSnapshot{}
+	return errs
+}
+
+func (s *UpgradeSnapshot) addVolume(name string) error {
+	mountPoint, err := GetMountPoint(name)
+	if err != nil {
+		return err
+	}
+	volume := volumeSnapshot{
+		Name:       name,
+		MountPoint: mountPoint,
+		Path:       filepath.Join(filepath.Dir(mountPoint), snapshotDirName),
+	}
+
+	if isBtrfsSubvolume(mountPoint) {
+		log.Debug().Msgf("Creating btrfs snapshot of volume %s", name)
+		if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "btrfs", "subvolume", "snapshot", "-r",
+			mountPoint, volume.Path,
+		); err != nil {
+			return err
+		}
+		volume.Kind = snapshotBtrfs
+		s.Volumes = append(s.Volumes, &volume)
+		return nil
+	}
+
+	if lvm, relPath := findLogicalVolume(mountPoint); lvm != nil {
+		err := s.createLvmSnapshot(lvm)
+		if err == nil {
+			volume.Kind = snapshotLvm
+			volume.LogicalVolume = lvm.key()
+			volume.Path = relPath
+			s.Volumes = append(s.Volumes, &volume)
+			return nil
+		}
+		log.Warn().Err(err).Msgf(L("Cannot snapshot logical volume %[1]s, copying volume %[2]s instead"),
+			lvm.key(), name)
+	}
+
+	log.Debug().Msgf("Copying volume %s", name)
+	if err := utils.RemoveHostAll(volume.Path); err != nil {
+		return err
+	}
+	if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "cp", "-a", "--reflink=auto",
+		mountPoint, volume.Path,
+	); err != nil {
+		return err
+	}
+	volume.Kind = snapshotCopy
+	s.Volumes = append(s.Volumes, &volume)
+	return nil
+}
+
+func (s *UpgradeSnapshot) restoreVolume(volume *volumeSnapshot) error {
+	source := volume.Path
+	if volume.Kind == snapshotLvm {
+		lvm, ok := s.LogicalVolumes[volume.LogicalVolume]
+		if !ok {
+			return fmt.Errorf(L("no snapshot of logical volume %s"), volume.LogicalVolume)
+		}
+		if err := lvm.mount(); err != nil {
+			return err
+		}
+		source = filepath.Join(lvm.mountDir, volume.Path)
+	}
+
+	if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "find", volume.MountPoint, "-mindepth", "1", "-delete"); err != nil {
+		return err
+	}
+	return utils.RunCmdStdMapping(zerolog.DebugLevel, "cp", "-a", "--reflink=auto",
+		source+"/.", volume.MountPoint,
+	)
+}
+
+// createLvmSnapshot creates the snapshot of a logical volume if not already done.
+func (s *UpgradeSnapshot) createLvmSnapshot(lvm *lvmSnapshot) error {
+	if _, ok := s.LogicalVolumes[lvm.key()]; ok {
+		return nil
+	}
+
+	log.Debug().Msgf("Creating LVM snapshot of %s", lvm.key())
+	if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "lvcreate", "--snapshot", "--extents", "100%ORIGIN",
+		"--name", lvm.Origin+lvmSnapshotSuffix, lvm.key(),
+	); err != nil {
+		return err
+	}
+	s.LogicalVolumes[lvm.key()] = lvm
+	return nil
+}
+
+// findLogicalVolume returns the logical volume holding path and the path relative to its mount point.
+// The returned logical volume is nil if path isn't on a logical volume.
+func findLogicalVolume(path string) (*lvmSnapshot, string) {
+	if !utils.IsInstalled("lvs") {
+		return nil, ""
+	}
+
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "findmnt", "-n", "-o", "SOURCE,TARGET,FSTYPE", "--target", path)
+	if err != nil {
+		return nil, ""
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 3 {
+		return nil, ""
+	}
+	source, target, fsType := fields[0], fields[1], fields[2]
+
+	out, err = utils.RunCmdOutput(zerolog.DebugLevel, "lvs", "--noheadings", "-o", "vg_name,lv_name", source)
+	if err != nil {
+		return nil, ""
+	}
+	vg, lv, found := parseLvsOutput(string(out))
+	if !found {
+		return nil, ""
+	}
+
+	relPath, err := filepath.Rel(target, path)
+	if err != nil {
+		return nil, ""
+	}
+	return &lvmSnapshot{VolumeGroup: vg, Origin: lv, FsType: fsType}, relPath
+}
+
+func (lvm *lvmSnapshot) key() string {
+	return lvm.VolumeGroup + "/" + lvm.Origin
+}
+
+func (lvm *lvmSnapshot) snapshotName() string {
+	return lvm.key() + lvmSnapshotSuffix
+}
+
+func (lvm *lvmSnapshot) mount() error {
+	if lvm.mountDir != "" {
+		return nil
+	}
+
+	mountDir, err := utils.MkdirHostTemp("mgradm-snapshot-*")
+	if err != nil {
+		return utils.Errorf(err, L("failed to create temporary directory"))
+	}
+
+	options := "ro"
+	if lvm.FsType == "xfs" {
+		// The snapshot has the same UUID than the mounted origin
+		options += ",nouuid"
+	}
+	device := "/dev/" + lvm.snapshotName()
+	if err := utils.RunCmdStdMapping(zerolog.DebugLevel, "mount", "-o", options, device, mountDir); err != nil {
+		_ = utils.RemoveHostFile(mountDir)
+		return utils.Errorf(err, L("failed to mount %s"), device)
+	}
+	lvm.mountDir = mountDir
+	return nil
+}
+
+func isBtrfsSubvolume(path string) bool {
+	if !utils.IsInstalled("btrfs") {
+		return false
+	}
+	_, err := utils.RunCmdOutput(zerolog.DebugLevel, "btrfs", "subvolume", "show", path)
+	return err == nil
+}
+
+// parseLvsOutput extracts the volume group and logical volume names from lvs output.
+func parseLvsOutput(out string) (vg string, lv string, found bool) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// snapshotVolumeNames returns the names of the volumes modified by an upgrade: the database and the configuration.
+func snapshotVolumeNames() []string {
+	names := []string{}
+	for _, volume := range utils.ServerVolumeMounts {
+		if volume.Name != podman.InstanceVolume("var-pgsql") && !strings.HasPrefix(volume.Name, podman.InstanceVolume("etc-")) {
+			continue
+		}
+		if !utils.Contains(names, volume.Name) {
+			names = append(names, volume.Name)
+		}
+	}
+	return names
+}
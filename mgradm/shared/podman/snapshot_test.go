@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestSnapshotVolumeNames(t *testing.T) {
+	names := snapshotVolumeNames()
+
+	test_utils.AssertTrue(t, "var-pgsql is missing", utils.Contains(names, "var-pgsql"))
+	seen := map[string]bool{}
+	for _, name := range names {
+		test_utils.AssertTrue(t, "unexpected volume "+name, name == "var-pgsql" || strings.HasPrefix(name, "etc-"))
+		test_utils.AssertTrue(t, "duplicated volume "+name, !seen[name])
+		seen[name] = true
+	}
+}
+
+func TestParseLvsOutput(t *testing.T) {
+	data := []struct {
+		out   string
+		vg    string
+		lv    string
+		found bool
+	}{
+		{"  system   containers\n", "system", "containers", true},
+		{"", "", "", false},
+		{"  Failed to find logical volume\n", "", "", false},
+	}
+
+	for i, testCase := range data {
+		vg, lv, found := parseLvsOutput(testCase.out)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong found", i), testCase.found, found)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong volume group", i), testCase.vg, vg)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong logical volume", i), testCase.lv, lv)
+	}
+}
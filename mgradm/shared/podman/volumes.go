@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// VolumeInfo describes the usage and health of a server volume on the host.
+type VolumeInfo struct {
+	Name string `json:"name"`
+	// MountPath is the path of the volume in the server container.
+	MountPath string `json:"mountPath"`
+	// MountPoint is the path of the volume on the host.
+	MountPoint     string `json:"mountPoint,omitempty"`
+	Size           int64  `json:"size"`
+	Inodes         int64  `json:"inodes"`
+	Device         string `json:"device,omitempty"`
+	Filesystem     string `json:"filesystem,omitempty"`
+	FsSize         uint64 `json:"filesystemSize"`
+	FsAvailable    uint64 `json:"filesystemAvailable"`
+	FsInodes       uint64 `json:"filesystemInodes"`
+	FsInodesFree   uint64 `json:"filesystemInodesFree"`
+	SELinuxContext string `json:"selinuxContext,omitempty"`
+	// OnRootFs is true if the volume is stored on the same filesystem than /.
+	OnRootFs bool   `json:"onRootFilesystem"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GetVolumesInfo inspects all the server volumes.
+//
+// Errors for a volume are reported in its Error field to still get the other volumes data.
+func GetVolumesInfo() []VolumeInfo {
+	rootFsID := ""
+	if rootFs, err := findFilesystem("/"); err == nil {
+		rootFsID = rootFs.id()
+	}
+
+	volumes := []VolumeInfo{}
+	names := []string{}
+	for _, mount := range utils.ServerVolumeMounts {
+		if utils.Contains(names, mount.Name) {
+			continue
+		}
+		names = append(names, mount.Name)
+
+		info := VolumeInfo{Name: mount.Name, MountPath: mount.MountPath}
+		if err := info.inspect(rootFsID); err != nil {
+			info.Error = err.Error()
+		}
+		volumes = append(volumes, info)
+	}
+	return volumes
+}
+
+func (v *VolumeInfo) inspect(rootFsID string) error {
+	mountPoint, err := GetMountPoint(v.Name)
+	if err != nil {
+		return err
+	}
+	v.MountPoint = mountPoint
+
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &fsStat); err != nil {
+		return err
+	}
+	v.FsSize = fsStat.Blocks * uint64(fsStat.Bsize)
+	v.FsAvailable = fsStat.Bavail * uint64(fsStat.Bsize)
+	v.FsInodes = fsStat.Files
+	v.FsInodesFree = fsStat.Ffree
+
+	fs, err := findFilesystem(mountPoint)
+	if err != nil {
+		return err
+	}
+	v.Device, v.Filesystem = fs.source, fs.fsType
+	v.OnRootFs = rootFsID != "" && fs.id() == rootFsID
+
+	if v.Size, err = duTotal(mountPoint, "--block-size=1"); err != nil {
+		return err
+	}
+	if v.Inodes, err = duTotal(mountPoint, "--inodes"); err != nil {
+		return err
+	}
+
+	v.SELinuxContext = selinuxContext(mountPoint)
+	return nil
+}
+
+// filesystem describes the filesystem containing a path as reported by findmnt.
+type filesystem struct {
+	source string
+	fsType string
+	uuid   string
+}
+
+// findmntPairRegexp matches the KEY="value" pairs of the findmnt -P output.
+var findmntPairRegexp = regexp.MustCompile(`([A-Z]+)="([^"]*)"`)
+
+// findFilesystem returns the filesystem containing path.
+func findFilesystem(path string) (*filesystem, error) {
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "findmnt", "-n", "-P", "-o", "SOURCE,FSTYPE,UUID",
+		"--target", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFindmntOutput(string(out)), nil
+}
+
+func parseFindmntOutput(out string) *filesystem {
+	fs := filesystem{}
+	for _, match := range findmntPairRegexp.FindAllStringSubmatch(out, -1) {
+		switch match[1] {
+		case "SOURCE":
+			fs.source = match[2]
+		case "FSTYPE":
+			fs.fsType = match[2]
+		case "UUID":
+			fs.uuid = match[2]
+		}
+	}
+	return &fs
+}
+
+// id identifies the filesystem: the subvolumes of a btrfs filesystem have the same id.
+//
+// The UUID is used if available, otherwise the source device without the btrfs subvolume suffix.
+func (fs *filesystem) id() string {
+	if fs.uuid != "" {
+		return fs.uuid
+	}
+	source, _, _ := strings.Cut(fs.source, "[")
+	return source
+}
+
+// duTotal returns the total computed by du for path.
+func duTotal(path string, option string) (int64, error) {
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "du", "-s", "-x", option, path)
+	if err != nil {
+		return 0, err
+	}
+	return parseDuOutput(string(out))
+}
+
+func parseDuOutput(out string) (int64, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(fields[0], 10, 64)
+}
+
+// selinuxContext returns the SELinux context of path or an empty string if SELinux is not used.
+func selinuxContext(path string) string {
+	buf := make([]byte, 256)
+	size, err := syscall.Getxattr(path, "security.selinux", buf)
+	if err != nil || size <= 0 {
+		return ""
+	}
+	return strings.TrimRight(string(buf[:size]), "\x00")
+}
+
+// VolumesAvailableSpace returns the space available for the podman volumes in bytes or -1 if unknown.
+func VolumesAvailableSpace() int64 {
+	volumesPath := "/var/lib/containers/storage/volumes"
+	if out, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", "info", "--format", "{{.Store.VolumePath}}"); err == nil {
+		if path := strings.TrimSpace(string(out)); path != "" {
+			volumesPath = path
+		}
+	}
+
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(volumesPath, &fsStat); err != nil {
+		return -1
+	}
+	return int64(fsStat.Bavail * uint64(fsStat.Bsize))
+}
+
+// VolumeUsage returns the space used by a volume or 0 if the volume doesn't exist.
+func VolumeUsage(name string) int64 {
+	mountPoint, err := GetMountPoint(name)
+	if err != nil {
+		return 0
+	}
+	size, err := duTotal(mountPoint, "--block-size=1")
+	if err != nil {
+		return 0
+	}
+	return size
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestParseDuOutput(t *testing.T) {
+	data := []struct {
+		out      string
+		expected int64
+		fails    bool
+	}{
+		{"123456\t/var/lib/containers/storage/volumes/var-pgsql/_data\n", 123456, false},
+		{"", 0, true},
+		{"du: cannot access", 0, true},
+	}
+
+	for i, testCase := range data {
+		actual, err := parseDuOutput(testCase.out)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong size", i), testCase.expected, actual)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong error", i), testCase.fails, err != nil)
+	}
+}
+
+func TestParseFindmntOutput(t *testing.T) {
+	data := []struct {
+		out    string
+		source string
+		fsType string
+		id     string
+	}{
+		{
+			`SOURCE="/dev/vda3[/@/var]" FSTYPE="btrfs" UUID="0d5e3c1a-4c6f-4b9e-9d7c-2b2f4e3a1c10"` + "\n",
+			"/dev/vda3[/@/var]", "btrfs", "0d5e3c1a-4c6f-4b9e-9d7c-2b2f4e3a1c10",
+		},
+		{`SOURCE="/dev/vda3[/@/var]" FSTYPE="btrfs" UUID=""`, "/dev/vda3[/@/var]", "btrfs", "/dev/vda3"},
+		{`SOURCE="/dev/mapper/data" FSTYPE="xfs" UUID=""`, "/dev/mapper/data", "xfs", "/dev/mapper/data"},
+		{"", "", "", ""},
+	}
+
+	for i, testCase := range data {
+		fs := parseFindmntOutput(testCase.out)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong source", i), testCase.source, fs.source)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong filesystem", i), testCase.fsType, fs.fsType)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: wrong id", i), testCase.id, fs.id())
+	}
+}
@@ -10,8 +10,8 @@ import (
 )
 
 const attestationServiceTemplate = `
-# uyuni-server-attestation.service, generated by mgradm
-# Use an uyuni-server-attestation.service.d/local.conf file to override
+# {{ .NamePrefix }}-server-attestation.service, generated by mgradm
+# Use an {{ .NamePrefix }}-server-attestation.service.d/local.conf file to override
 [Unit]
 Description=Uyuni server attestation container service
 Wants=network.target
@@ -19,10 +19,10 @@ After=network-online.target
 [Service]
 Environment=PODMAN_SYSTEMD_UNIT=%n
 Restart=on-failure
-ExecStartPre=/bin/rm -f %t/uyuni-server-attestation-%i.pid %t/%n.ctr-id
+ExecStartPre=/bin/rm -f %t/{{ .NamePrefix }}-server-attestation-%i.pid %t/%n.ctr-id
 ExecStartPre=/usr/bin/podman rm --ignore --force -t 10 {{ .NamePrefix }}-server-attestation-%i
 ExecStart=/bin/sh -c '/usr/bin/podman run \
-	--conmon-pidfile %t/uyuni-server-attestation-%i.pid \
+	--conmon-pidfile %t/{{ .NamePrefix }}-server-attestation-%i.pid \
 	--cidfile=%t/%n-%i.ctr-id \
 	--cgroups=no-conmon \
 	--sdnotify=conmon \
@@ -37,7 +37,7 @@ ExecStart=/bin/sh -c '/usr/bin/podman run \
 	${UYUNI_IMAGE}'
 ExecStop=/usr/bin/podman stop --ignore -t 10 --cidfile=%t/%n-%i.ctr-id
 ExecStopPost=/usr/bin/podman rm -f --ignore -t 10 --cidfile=%t/%n-%i.ctr-id
-PIDFile=%t/uyuni-server-attestation-%i.pid
+PIDFile=%t/{{ .NamePrefix }}-server-attestation-%i.pid
 TimeoutStopSec=60
 TimeoutStartSec=60
 Type=forking
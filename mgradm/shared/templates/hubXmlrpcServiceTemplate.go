@@ -11,8 +11,8 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 )
 
-const hubXmlrpcServiceTemplate = `# uyuni-uyuni-hub-xmlrpc.service, generated by mgradm
-# Use an uyuni-hub-xmlrpc.service.d/local.conf file to override
+const hubXmlrpcServiceTemplate = `# {{ .NamePrefix }}-hub-xmlrpc.service, generated by mgradm
+# Use an {{ .NamePrefix }}-hub-xmlrpc.service.d/local.conf file to override
 
 [Unit]
 Description=Uyuni Hub XMLRPC API container service
@@ -24,10 +24,10 @@ Environment=PODMAN_SYSTEMD_UNIT=%n
 Environment=HUB_API_URL=http://{{ .NamePrefix }}-server.mgr.internal:80/rpc/api
 Environment=HUB_CONNECT_USING_SSL=true
 Restart=on-failure
-ExecStartPre=/bin/rm -f %t/uyuni-hub-xmlrpc-%i.pid %t/%n.ctr-id
+ExecStartPre=/bin/rm -f %t/{{ .NamePrefix }}-hub-xmlrpc-%i.pid %t/%n.ctr-id
 ExecStartPre=/usr/bin/podman rm --ignore --force -t 10 {{ .NamePrefix }}-hub-xmlrpc-%i
 ExecStart=/usr/bin/podman run \
-	--conmon-pidfile %t/uyuni-hub-xmlrpc-%i.pid \
+	--conmon-pidfile %t/{{ .NamePrefix }}-hub-xmlrpc-%i.pid \
 	--cidfile=%t/%n-%i.ctr-id \
 	--cgroups=no-conmon \
 	--sdnotify=conmon \
@@ -50,7 +50,7 @@ ExecStart=/usr/bin/podman run \
 
 ExecStop=/usr/bin/podman stop --ignore -t 10 --cidfile=%t/%n-%i.ctr-id
 ExecStopPost=/usr/bin/podman rm -f --ignore -t 10 --cidfile=%t/%n-%i.ctr-id
-PIDFile=%t/uyuni-hub-xmlrpc-%i.pid
+PIDFile=%t/{{ .NamePrefix }}-hub-xmlrpc-%i.pid
 TimeoutStopSec=60
 TimeoutStartSec=60
 Type=forking
@@ -19,6 +19,23 @@ if test -e /tmp/ssh_config; then
 fi
 SSH="ssh -o User={{ .User }} -A $SSH_CONFIG "
 
+# The state file records the synchronized folders to resume an interrupted migration.
+# The steps of the preparation and of the final synchronization are recorded separately
+# since the final synchronization needs to copy what changed since the preparation.
+STATE_FILE=/var/lib/uyuni-tools/migration-state
+PHASE={{ if .Prepare }}prepare{{ else }}final{{ end }}
+{{ if .Resume }}
+touch $STATE_FILE
+{{ else }}
+rm -f $STATE_FILE
+{{ end }}
+is_synced() {
+  grep -qxF "$PHASE:$1" $STATE_FILE 2>/dev/null
+}
+mark_synced() {
+  echo "$PHASE:$1" >> $STATE_FILE
+}
+
 {{ if .Prepare }}
 echo "Preparing migration..."
 $SSH {{ .SourceFqdn }} "sudo systemctl start postgresql.service"
@@ -64,9 +81,12 @@ echo "-/ /var/log/lastlog" >> exclude_list
 
 for folder in {{ range .Volumes }}{{ .MountPath }} {{ end }};
 do
-  if $SSH {{ .SourceFqdn }} test -e $folder; then
+  if is_synced "volume:$folder"; then
+    echo "Skipping already copied $folder..."
+  elif $SSH {{ .SourceFqdn }} test -e $folder; then
     echo "Copying $folder..."
-    rsync -e "$SSH" --rsync-path='sudo rsync' -avz --trust-sender -f "merge exclude_list" {{ .SourceFqdn }}:$folder/ $folder;
+    rsync -e "$SSH" --rsync-path='sudo rsync' -avz --partial --trust-sender -f "merge exclude_list" {{ .SourceFqdn }}:$folder/ $folder;
+    mark_synced "volume:$folder"
   else
     echo "Skipping missing $folder..."
   fi
@@ -78,10 +98,13 @@ sed -i -e 's|DocumentRoot\s*"/srv/www/htdocs"|DocumentRoot "/usr/share/susemanag
 echo "Migrating auto-installable distributions..."
 
 while IFS="," read -r target path ; do
-  if $SSH -n {{ .SourceFqdn }} test -e $path ; then
+  if is_synced "distribution:$target" ; then
+    echo "Skipping already copied distribution $target..."
+  elif $SSH -n {{ .SourceFqdn }} test -e $path ; then
     echo "Copying distribution $target from $path"
     mkdir -p "/srv/www/distributions/$target"
-    rsync -e "$SSH" --rsync-path='sudo rsync' -avz "{{ .SourceFqdn }}:$path/" "/srv/www/distributions/$target"
+    rsync -e "$SSH" --rsync-path='sudo rsync' -avz --partial "{{ .SourceFqdn }}:$path/" "/srv/www/distributions/$target"
+    mark_synced "distribution:$target"
   else
     echo "Skipping missing distribution $path..."
   fi
@@ -126,7 +149,7 @@ sed 's/-XX:-UseConcMarkSweepGC//' -i /etc/tomcat/conf.d/*
 test -f /etc/tomcat/conf.d/remote_debug.conf && sed 's/address=[^:]*:/address=*:/' -i /etc/tomcat/conf.d/remote_debug.conf
 
 {{ if .Kubernetes }}
-echo 'server.no_ssl = 1' >> /etc/rhn/rhn.conf;
+grep -q '^server.no_ssl' /etc/rhn/rhn.conf || echo 'server.no_ssl = 1' >> /etc/rhn/rhn.conf;
 echo "Extracting SSL certificate and authority"
 extractedSSL=
 if test -d /root/ssl-build; then
@@ -161,7 +184,7 @@ echo "Removing useless ssl-build folder..."
 rm -rf /root/ssl-build
 
 # The content of this folder will be a RO mount from a configmap
-rm /etc/pki/trust/anchors/*
+rm -f /etc/pki/trust/anchors/*
 {{ end }}
 
 echo "DONE"`
@@ -173,6 +196,7 @@ type MigrateScriptTemplateData struct {
 	User       string
 	Kubernetes bool
 	Prepare    bool
+	Resume     bool
 }
 
 // Render will create migration script.
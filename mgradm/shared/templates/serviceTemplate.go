@@ -11,8 +11,8 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 )
 
-const serviceTemplate = `# uyuni-server.service, generated by mgradm
-# Use an uyuni-server.service.d/local.conf file to override
+const serviceTemplate = `# {{ .NamePrefix }}-server.service, generated by mgradm
+# Use an {{ .NamePrefix }}-server.service.d/local.conf file to override
 
 [Unit]
 Description=Uyuni server image container service
@@ -23,10 +23,10 @@ RequiresMountsFor=%t/containers
 [Service]
 Environment=PODMAN_SYSTEMD_UNIT=%n
 Restart=on-failure
-ExecStartPre=/bin/rm -f %t/uyuni-server.pid %t/%n.ctr-id
+ExecStartPre=/bin/rm -f %t/{{ .NamePrefix }}-server.pid %t/%n.ctr-id
 ExecStartPre=/usr/bin/podman rm --ignore --force -t 10 {{ .NamePrefix }}-server
 ExecStart=/bin/sh -c '/usr/bin/podman run \
-	--conmon-pidfile %t/uyuni-server.pid \
+	--conmon-pidfile %t/{{ .NamePrefix }}-server.pid \
 	--cidfile=%t/%n.ctr-id \
 	--cgroups=no-conmon \
 	--shm-size=0 \
@@ -49,7 +49,7 @@ ExecStart=/bin/sh -c '/usr/bin/podman run \
 	--network {{ .Network }} \
 	${PODMAN_EXTRA_ARGS} ${UYUNI_IMAGE}'
 ExecStop=/usr/bin/podman exec \
-    uyuni-server \
+    {{ .NamePrefix }}-server \
     /bin/bash -c 'spacewalk-service stop && systemctl stop postgresql'
 ExecStop=/usr/bin/podman stop \
 	--ignore -t 10 \
@@ -59,7 +59,7 @@ ExecStopPost=/usr/bin/podman rm \
 	--ignore -t 10 \
 	--cidfile=%t/%n.ctr-id
 
-PIDFile=%t/uyuni-server.pid
+PIDFile=%t/{{ .NamePrefix }}-server.pid
 TimeoutStopSec=180
 TimeoutStartSec=900
 Type=forking
@@ -98,10 +98,12 @@ func AddContainerImageFlags(
 func AddSCCFlag(cmd *cobra.Command) {
 	cmd.Flags().String("scc-user", "", L("SUSE Customer Center username. It will be used as SCC credentials for products synchronization and to pull images from registry.suse.com"))
 	cmd.Flags().String("scc-password", "", L("SUSE Customer Center password. It will be used as SCC credentials for products synchronization and to pull images from registry.suse.com"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "scc-password")
 
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "scc", Title: L("SUSE Customer Center Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "scc-user", "scc")
 	_ = utils.AddFlagToHelpGroupID(cmd, "scc-password", "scc")
+	_ = utils.AddFlagToHelpGroupID(cmd, "scc-password-file", "scc")
 }
 
 // AddImageFlag add Image flags to a command.
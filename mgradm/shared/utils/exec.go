@@ -7,8 +7,10 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared"
 	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
@@ -108,11 +111,30 @@ func RunMigration(cnx *shared.Connection, tmpPath string, scriptName string) err
 	return nil
 }
 
+// MigrationDataDir returns the folder holding the migration script, its state and the extracted data
+// of the server instance.
+//
+// The folder is kept if the migration fails to allow resuming it.
+func MigrationDataDir() string {
+	return path.Join(podman.InstanceStateFolder(), "migration")
+}
+
 // GenerateMigrationScript generates the script that perform migration.
-func GenerateMigrationScript(sourceFqdn string, user string, kubernetes bool, prepare bool) (string, error) {
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	if err != nil {
-		return "", utils.Errorf(err, L("failed to create temporary directory"))
+//
+// Unless resuming, the state of a previous migration is removed.
+func GenerateMigrationScript(sourceFqdn string, user string, kubernetes bool, prepare bool, resume bool) (string, error) {
+	scriptDir := MigrationDataDir()
+	if resume {
+		if !utils.FileExists(path.Join(scriptDir, "migration-state")) {
+			return "", errors.New(L("no interrupted migration to resume"))
+		}
+		log.Info().Msg(L("Resuming the previous migration"))
+	} else if err := os.RemoveAll(scriptDir); err != nil {
+		return "", utils.Errorf(err, L("failed to remove the previous migration data"))
+	}
+
+	if err := os.MkdirAll(scriptDir, 0700); err != nil {
+		return "", utils.Errorf(err, L("failed to create %s folder"), scriptDir)
 	}
 
 	data := templates.MigrateScriptTemplateData{
@@ -121,16 +143,30 @@ func GenerateMigrationScript(sourceFqdn string, user string, kubernetes bool, pr
 		User:       user,
 		Kubernetes: kubernetes,
 		Prepare:    prepare,
+		Resume:     resume,
 	}
 
 	scriptPath := filepath.Join(scriptDir, "migrate.sh")
-	if err = utils.WriteTemplateToFile(data, scriptPath, 0555, true); err != nil {
+	if err := utils.WriteTemplateToFile(data, scriptPath, 0555, true); err != nil {
 		return "", utils.Errorf(err, L("failed to generate migration script"))
 	}
 
 	return scriptDir, nil
 }
 
+// CleanMigrationData removes the migration data folder if the migration succeeded.
+//
+// The folder is kept on failure to resume the migration later.
+func CleanMigrationData(scriptDir string, err error) {
+	if err != nil {
+		log.Warn().Msgf(L("Migration data kept in %s, use --resume to continue the migration"), scriptDir)
+		return
+	}
+	if err := os.RemoveAll(scriptDir); err != nil {
+		log.Error().Err(err).Msgf(L("failed to remove %s"), scriptDir)
+	}
+}
+
 // RunningImage returns the image running in the current system.
 func RunningImage(cnx *shared.Connection, containerName string) (string, error) {
 	command, err := cnx.GetCommand()
@@ -145,6 +181,15 @@ func RunningImage(cnx *shared.Connection, containerName string) (string, error)
 		return strings.Trim(string(image), "\n"), nil
 
 	case "kubectl":
+		if kubernetes.HasAPIClient() {
+			pod, err := kubernetes.FindRunningPod(kubernetes.ServerFilter)
+			if err != nil {
+				return "", err
+			}
+			image := pod.Images[containerName]
+			log.Info().Msgf(L("Image is: %s"), image)
+			return image, nil
+		}
 
 		//FIXME this will work until containers 0 is uyuni. Then jsonpath should be something like
 		// {.items[0].spec.containers[?(@.name=="` + containerName + `")].image but there are problems
@@ -246,3 +291,46 @@ func isUyuni(cnx *shared.Connection) (bool, error) {
 	}
 	return true, nil
 }
+
+// InspectRunningServer runs the server inspector in the running server container.
+//
+// The inspection script is passed through the standard input of the container and its data
+// are read back from the container and removed once parsed.
+func InspectRunningServer(cnx *shared.Connection) (*utils.ServerInspectData, error) {
+	scriptDir, err := os.MkdirTemp("", "mgradm-*")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to create temporary directory"))
+	}
+	defer os.RemoveAll(scriptDir)
+
+	inspector := utils.NewServerInspector(scriptDir)
+	if err := inspector.GenerateScript(); err != nil {
+		return nil, err
+	}
+
+	script, err := os.Open(inspector.GetScriptPath())
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to open %s"), inspector.GetScriptPath())
+	}
+	defer script.Close()
+
+	// The script appends to the data file, make sure no previous data remain
+	dataPath := inspector.GetDataPath()
+	prepareCmd := fmt.Sprintf("mkdir -p %s && rm -f %s && bash -s", utils.InspectContainerDirectory, dataPath)
+	if err := cnx.ExecStream(script, io.Discard, "sh", "-c", prepareCmd); err != nil {
+		return nil, utils.Errorf(err, L("failed to run the inspection script in the server container"))
+	}
+
+	data, err := cnx.Exec("cat", dataPath)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to read the inspection data"))
+	}
+	if _, err := cnx.Exec("rm", "-f", dataPath); err != nil {
+		log.Warn().Err(err).Msgf(L("failed to remove %s from the server container"), dataPath)
+	}
+
+	if err := os.WriteFile(path.Join(scriptDir, path.Base(dataPath)), data, 0600); err != nil {
+		return nil, utils.Errorf(err, L("failed to write the inspection data"))
+	}
+	return inspector.ReadInspectData()
+}
@@ -5,15 +5,22 @@
 package api
 
 import (
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared"
 	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
 type apiFlags struct {
 	api.ConnectionDetails `mapstructure:"api"`
+	OutputFlags           `mapstructure:",squash"`
+	Data                  string
+	Form                  bool
 }
 
 // NewCommand generates a JSON over HTTP API helper tool command.
@@ -28,26 +35,86 @@ func NewCommand(globalFlags *types.GlobalFlags) (*cobra.Command, error) {
 	apiGet := &cobra.Command{
 		Use:   "get path [parameters]...",
 		Short: L("Call API GET request"),
-		Long:  L("Takes an API path and optional parameters and then issues GET request with them. If user and password are provided, calls login before API call"),
+		Long:  L("Takes an API path and optional parameters and then issues GET request with them. If user and password are provided, calls login before API call, otherwise the session stored by the login command is used"),
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return utils.CommandHelper(globalFlags, cmd, args, &flags, runGet)
 		},
+		ValidArgsFunction: completePath,
 	}
 
 	apiPost := &cobra.Command{
-		Use:   "post path parameters...",
+		Use:   "post path [parameters]...",
 		Short: L("Call API POST request"),
-		Long:  L("Takes an API path and parameters and then issues POST request with them. User and password are mandatory. Parameters can be either JSON encoded string or one or more key=value pairs."),
+		Long:  L("Takes an API path and parameters and then issues POST request with them. User and password are mandatory unless a session has been stored by the login command. Parameters can be either JSON encoded string or one or more key=value pairs. Large JSON data can be read from a file with --data @file.json."),
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return utils.CommandHelper(globalFlags, cmd, args, &flags, runPost)
 		},
+		ValidArgsFunction: completePath,
 	}
 
+	apiLogin := &cobra.Command{
+		Use:   "login",
+		Short: L("Login to the API server and store the session"),
+		Long: L(`Login to the API server and store the session in a file only readable by the current user.
+
+The following API calls without password reuse the stored session until it expires.
+One session is stored per server: the calls without server use the session of the local server instance
+or, if there is no local server, the only stored session.`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, runLogin)
+		},
+	}
+
+	apiLogout := &cobra.Command{
+		Use:   "logout",
+		Short: L("Logout from the API server and remove the stored session"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, runLogout)
+		},
+	}
+
+	addOutputFlags(apiGet)
+	addOutputFlags(apiPost)
+	apiPost.Flags().String("data", "",
+		L("JSON data to send, @path to read it from a file or @- to read it from the standard input"))
+	apiPost.Flags().Bool("form", false, L("Send the key=value parameters form-encoded instead of as a JSON object"))
+
 	apiCmd.AddCommand(apiGet)
 	apiCmd.AddCommand(apiPost)
+	apiCmd.AddCommand(apiLogin)
+	apiCmd.AddCommand(apiLogout)
+	apiCmd.AddCommand(newBatchCommand(globalFlags))
+	apiCmd.AddCommand(newDescribeCommands(globalFlags)...)
 
-	if err := api.AddAPIFlags(apiCmd, false); err != nil {
+	if err := api.AddAPIFlags(apiCmd, true); err != nil {
 		return apiCmd, err
 	}
 	return apiCmd, nil
 }
+
+// initClient connects to the API server, using the local server if no server is provided.
+//
+// The local server is not discovered if a session is stored for it.
+// Without local server, the stored session is used as explained for api.FindSession.
+// The CA certificate of the local server is used if the given server is the local one and has no CA certificate.
+func initClient(conn *api.ConnectionDetails) (*api.HTTPClient, error) {
+	if conn.Server == "" && !api.HasLocalSession(conn) {
+		cnx := shared.NewConnection("", podman.ServerContainerName, kubernetes.ServerFilter)
+		if err := api.DiscoverLocalServer(conn, cnx); err != nil {
+			if !api.HasSession(conn) {
+				return nil, utils.Errorf(err, L("no API server provided and no local server found"))
+			}
+			log.Debug().Err(err).Msg("No local server found, using the stored session")
+		}
+	} else if conn.Server != "" && conn.CAcert == "" && !conn.Insecure {
+		cnx := shared.NewConnection("", podman.ServerContainerName, kubernetes.ServerFilter)
+		if err := api.DiscoverLocalServer(conn, cnx); err != nil {
+			log.Debug().Err(err).Msg("No local server found to read the CA certificate from")
+		}
+	}
+	return api.Init(conn)
+}
@@ -0,0 +1,324 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+const (
+	stepPassed  = "ok"
+	stepFailed  = "failed"
+	stepSkipped = "skipped"
+)
+
+type batchFlags struct {
+	api.ConnectionDetails `mapstructure:"api"`
+	Continue              struct {
+		On struct {
+			Error bool
+		}
+	}
+}
+
+// batchFile is the content of a batch file.
+type batchFile struct {
+	Steps []batchStep `yaml:"steps"`
+}
+
+// batchStep is an API call of a batch file.
+type batchStep struct {
+	// Name is used to reference the result of the step in the following ones.
+	Name   string                 `yaml:"name"`
+	Method string                 `yaml:"method"`
+	Path   string                 `yaml:"path"`
+	Params map[string]interface{} `yaml:"params"`
+	Data   map[string]interface{} `yaml:"data"`
+}
+
+// stepResult is the outcome of a step for the summary.
+type stepResult struct {
+	step    *batchStep
+	status  string
+	message string
+}
+
+// referenceRegex matches values only made of a reference to a previous result like {{ .channel.id }}.
+var referenceRegex = regexp.MustCompile(`^\{\{\s*(\.[^\s{}]+)\s*\}\}$`)
+
+func newBatchCommand(globalFlags *types.GlobalFlags) *cobra.Command {
+	batchCmd := &cobra.Command{
+		Use:   "batch file",
+		Short: L("Run a sequence of API calls from a file"),
+		Long: L(`Run the API calls listed in a YAML file using a single session.
+
+The file contains a list of steps, each with a method (get or post), an API path,
+and either params for GET calls or data for POST calls:
+
+  steps:
+    - name: channel
+      method: post
+      path: channel/software/create
+      data:
+        label: my-channel
+        name: My channel
+        summary: My channel
+        archLabel: channel-x86_64
+        parentLabel: ""
+    - name: details
+      method: get
+      path: channel/software/getDetails
+      params:
+        channelLabel: my-channel
+    - method: post
+      path: activationkey/create
+      data:
+        key: my-key
+        description: Key for {{ .details.name }}
+        baseChannelLabel: "{{ .details.label }}"
+        entitlements: []
+        universalDefault: false
+
+The results of named steps can be referenced in the following steps with Go templates.
+A value only made of a reference like {{ .details.id }} keeps the type of the referenced result.
+
+The batch stops at the first failed step unless --continue-on-error is set.`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags batchFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, runBatch)
+		},
+	}
+	batchCmd.Flags().Bool("continue-on-error", false, L("Run the remaining steps even if one fails"))
+	return batchCmd
+}
+
+func runBatch(globalFlags *types.GlobalFlags, flags *batchFlags, cmd *cobra.Command, args []string) error {
+	batch, err := readBatchFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := initClient(&flags.ConnectionDetails)
+	if err != nil {
+		return utils.Errorf(err, L("unable to login to the server"))
+	}
+
+	results := runSteps(client, batch.Steps, flags.Continue.On.Error)
+	if err := printSummary(os.Stdout, results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.status == stepFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf(NL("%d step failed", "%d steps failed", failed), failed)
+	}
+	return nil
+}
+
+func readBatchFile(path string) (*batchFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to read %s"), path)
+	}
+
+	var batch batchFile
+	if err := yaml.Unmarshal(content, &batch); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse %s"), path)
+	}
+
+	names := []string{}
+	for i, step := range batch.Steps {
+		step.Method = strings.ToLower(step.Method)
+		if step.Method != "get" && step.Method != "post" {
+			return nil, fmt.Errorf(L("step %[1]d: unsupported method %[2]s"), i+1, step.Method)
+		}
+		if step.Path == "" {
+			return nil, fmt.Errorf(L("step %d: missing path"), i+1)
+		}
+		if step.Name != "" {
+			if utils.Contains(names, step.Name) {
+				return nil, fmt.Errorf(L("step %[1]d: duplicate name %[2]s"), i+1, step.Name)
+			}
+			names = append(names, step.Name)
+		}
+		batch.Steps[i] = step
+	}
+	return &batch, nil
+}
+
+// runSteps calls the API for each step and returns their outcome.
+func runSteps(client *api.HTTPClient, steps []batchStep, continueOnError bool) []stepResult {
+	results := []stepResult{}
+	values := map[string]interface{}{}
+	stop := false
+	for i := range steps {
+		step := &steps[i]
+		if stop {
+			results = append(results, stepResult{step: step, status: stepSkipped})
+			continue
+		}
+
+		log.Info().Msgf(L("Running step %[1]d: %[2]s %[3]s"), i+1, strings.ToUpper(step.Method), step.Path)
+		result, err := runStep(client, step, values)
+		if err != nil {
+			log.Error().Err(err).Msgf(L("Step %d failed"), i+1)
+			results = append(results, stepResult{step: step, status: stepFailed, message: err.Error()})
+			stop = !continueOnError
+			continue
+		}
+		if step.Name != "" {
+			values[step.Name] = result
+		}
+		results = append(results, stepResult{step: step, status: stepPassed})
+	}
+	return results
+}
+
+func runStep(client *api.HTTPClient, step *batchStep, values map[string]interface{}) (interface{}, error) {
+	path, err := renderValue(step.Path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *api.ApiResponse[json.RawMessage]
+	if step.Method == "get" {
+		params, err := renderValue(convertYAML(step.Params), values)
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values{}
+		for key, value := range params.(map[string]interface{}) {
+			query.Set(key, formatValue(value))
+		}
+		res, err = api.Get[json.RawMessage](client, fmt.Sprintf("%s?%s", path, query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := renderValue(convertYAML(step.Data), values)
+		if err != nil {
+			return nil, err
+		}
+		res, err = api.Post[json.RawMessage](client, fmt.Sprint(path), data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+	return decodeResult(res.Result)
+}
+
+// convertYAML converts the maps parsed by YAML into JSON compatible ones.
+func convertYAML(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = convertYAML(item)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := map[string]interface{}{}
+		for key, item := range value {
+			converted[key] = convertYAML(item)
+		}
+		return converted
+	case []interface{}:
+		converted := []interface{}{}
+		for _, item := range value {
+			converted = append(converted, convertYAML(item))
+		}
+		return converted
+	}
+	return data
+}
+
+// renderValue replaces the references to previous results in the strings of data.
+func renderValue(data interface{}, values map[string]interface{}) (interface{}, error) {
+	switch value := data.(type) {
+	case string:
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+		if matches := referenceRegex.FindStringSubmatch(value); matches != nil {
+			referenced, err := evalJSONPath(values, matches[1])
+			if err != nil {
+				return nil, err
+			}
+			if len(referenced) != 1 {
+				return nil, fmt.Errorf(L("reference %s doesn't match a single value"), value)
+			}
+			return referenced[0], nil
+		}
+		tpl, err := template.New("value").Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, utils.Errorf(err, L("failed to parse %s"), value)
+		}
+		var out bytes.Buffer
+		if err := tpl.Execute(&out, values); err != nil {
+			return nil, utils.Errorf(err, L("failed to render %s"), value)
+		}
+		return out.String(), nil
+	case map[string]interface{}:
+		rendered := map[string]interface{}{}
+		for key, item := range value {
+			renderedItem, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = renderedItem
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := []interface{}{}
+		for _, item := range value {
+			renderedItem, err := renderValue(item, values)
+			if err != nil {
+				return nil, err
+			}
+			rendered = append(rendered, renderedItem)
+		}
+		return rendered, nil
+	}
+	return data, nil
+}
+
+func printSummary(out io.Writer, results []stepResult) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, L("STEP\tNAME\tMETHOD\tPATH\tSTATUS\tMESSAGE"))
+	for i, result := range results {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, result.step.Name,
+			strings.ToUpper(result.step.Method), result.step.Path, result.status, result.message)
+	}
+	return writer.Flush()
+}
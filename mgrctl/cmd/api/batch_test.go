@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+const batchContent = `steps:
+  - name: channel
+    method: post
+    path: channel/software/create
+    data:
+      label: my-channel
+  - name: details
+    method: GET
+    path: channel/software/getDetails
+    params:
+      id: "{{ .channel.id }}"
+  - method: post
+    path: fail
+  - method: post
+    path: activationkey/create
+    data:
+      channelId: "{{ .details.id }}"
+      description: Key for {{ .details.label }}
+      nested:
+        labels: ["{{ .channel.label }}"]
+`
+
+func writeBatchFile(t *testing.T, content string) string {
+	batchPath := path.Join(t.TempDir(), "batch.yaml")
+	if err := os.WriteFile(batchPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return batchPath
+}
+
+func TestRunSteps(t *testing.T) {
+	// Make sure no stored session is used
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	server.HandleResult("channel/software/create", map[string]interface{}{"id": 42, "label": "my-channel"})
+	server.Handle("channel/software/getDetails", func(call *test_utils.APICall) (interface{}, error) {
+		test_utils.AssertEquals(t, "wrong referenced parameter", "42", call.Query.Get("id"))
+		return map[string]interface{}{"id": 42, "label": "my-channel"}, nil
+	})
+	server.Handle("fail", func(*test_utils.APICall) (interface{}, error) {
+		return nil, errors.New("expected failure")
+	})
+	server.HandleResult("activationkey/create", "1-key")
+
+	client, err := api.Init(&api.ConnectionDetails{
+		Server: server.Host(), User: "admin", Password: "secret", Insecure: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := readBatchFile(writeBatchFile(t, batchContent))
+	if err != nil {
+		t.Fatalf("Failed to read batch file: %s", err)
+	}
+
+	results := runSteps(client, batch.Steps, false)
+	test_utils.AssertEquals(t, "wrong number of results", 4, len(results))
+	test_utils.AssertEquals(t, "step 1 should pass", stepPassed, results[0].status)
+	test_utils.AssertEquals(t, "step 2 should pass", stepPassed, results[1].status)
+	test_utils.AssertEquals(t, "step 3 should fail", stepFailed, results[2].status)
+	test_utils.AssertEquals(t, "wrong failure message", "expected failure", results[2].message)
+	test_utils.AssertEquals(t, "step 4 should be skipped", stepSkipped, results[3].status)
+
+	results = runSteps(client, batch.Steps, true)
+	test_utils.AssertEquals(t, "step 4 should pass", stepPassed, results[3].status)
+	keyCalls := server.Calls("activationkey/create")
+	test_utils.AssertEquals(t, "wrong number of activation key calls", 1, len(keyCalls))
+	var keyData map[string]interface{}
+	if err := keyCalls[0].Decode(&keyData); err != nil {
+		t.Fatalf("failed to decode data: %s", err)
+	}
+	test_utils.AssertEquals(t, "reference should keep the type", float64(42), keyData["channelId"].(float64))
+	test_utils.AssertEquals(t, "wrong rendered string", "Key for my-channel", keyData["description"].(string))
+	labels := keyData["nested"].(map[string]interface{})["labels"].([]interface{})
+	test_utils.AssertEquals(t, "wrong nested value", "my-channel", labels[0].(string))
+}
+
+func TestReadBatchFileErrors(t *testing.T) {
+	data := []string{
+		"steps:\n  - method: delete\n    path: user/delete\n",
+		"steps:\n  - method: get\n",
+		"steps:\n  - name: a\n    method: get\n    path: a\n  - name: a\n    method: get\n    path: b\n",
+		"steps: [",
+	}
+
+	for i, content := range data {
+		if _, err := readBatchFile(writeBatchFile(t, content)); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
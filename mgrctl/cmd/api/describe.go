@@ -0,0 +1,364 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// sessionKeyType is the type of the session parameter, provided by the session cookie with the HTTP API.
+const sessionKeyType = "sessionKey"
+
+type describeFlags struct {
+	api.ConnectionDetails `mapstructure:"api"`
+	Refresh               bool
+}
+
+// apiCallList is the description of the API methods, grouped by namespace, stored in the cache.
+type apiCallList struct {
+	Server     string
+	Version    string
+	Namespaces map[string][]apiMethod
+}
+
+// apiMethod describes an API method as returned by the api.getApiNamespaceCallList call.
+//
+// The server only provides the types of the parameters, not their names.
+type apiMethod struct {
+	Name       string   `json:"name"`
+	Parameters []string `json:"parameters"`
+	Exceptions []string `json:"exceptions"`
+	Return     string   `json:"return"`
+}
+
+func newDescribeCommands(globalFlags *types.GlobalFlags) []*cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list [namespace]",
+		Short: L("List the API namespaces or the methods of a namespace"),
+		Long: L(`List the API namespaces or the methods of a namespace.
+
+The description of the API is cached per server version and used for the completion of the get and post commands.`),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags describeFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, runList)
+		},
+		ValidArgsFunction: completeNamespace,
+	}
+
+	describeCmd := &cobra.Command{
+		Use:   "describe path",
+		Short: L("Describe the parameters and result of an API method"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var flags describeFlags
+			return utils.CommandHelper(globalFlags, cmd, args, &flags, runDescribe)
+		},
+		ValidArgsFunction: completePath,
+	}
+
+	for _, cmd := range []*cobra.Command{listCmd, describeCmd} {
+		cmd.Flags().Bool("refresh", false, L("Fetch the API description from the server even if it is cached"))
+	}
+	return []*cobra.Command{listCmd, describeCmd}
+}
+
+func runList(globalFlags *types.GlobalFlags, flags *describeFlags, cmd *cobra.Command, args []string) error {
+	callList, err := loadCallList(&flags.ConnectionDetails, flags.Refresh)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		for _, namespace := range callList.namespaces() {
+			fmt.Println(namespace)
+		}
+		return nil
+	}
+	return printMethods(os.Stdout, callList, args[0])
+}
+
+func runDescribe(globalFlags *types.GlobalFlags, flags *describeFlags, cmd *cobra.Command, args []string) error {
+	callList, err := loadCallList(&flags.ConnectionDetails, flags.Refresh)
+	if err != nil {
+		return err
+	}
+	return printDescription(os.Stdout, callList, args[0])
+}
+
+func printMethods(out io.Writer, callList *apiCallList, namespace string) error {
+	methods, exists := callList.Namespaces[namespace]
+	if !exists {
+		return fmt.Errorf(L("unknown API namespace: %s"), namespace)
+	}
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, L("PATH\tPARAMETERS"))
+	for _, method := range methods {
+		fmt.Fprintf(writer, "%s\t%s\n", methodPath(namespace, method.Name), strings.Join(method.parameters(), ", "))
+	}
+	return writer.Flush()
+}
+
+func printDescription(out io.Writer, callList *apiCallList, apiPath string) error {
+	methods := callList.methods(apiPath)
+	if len(methods) == 0 {
+		return fmt.Errorf(L("unknown API method: %s"), apiPath)
+	}
+	for i, method := range methods {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, apiPath)
+		fmt.Fprintf(out, "  %s %s\n", L("Parameters:"), strings.Join(method.parameters(), ", "))
+		fmt.Fprintf(out, "  %s %s\n", L("Returns:"), method.Return)
+		if len(method.Exceptions) > 0 {
+			fmt.Fprintf(out, "  %s %s\n", L("Exceptions:"), strings.Join(method.Exceptions, ", "))
+		}
+	}
+	return nil
+}
+
+// methodPath returns the API path of a method.
+func methodPath(namespace string, method string) string {
+	return strings.ReplaceAll(namespace, ".", "/") + "/" + method
+}
+
+// parameters returns the description of the parameters to pass with the HTTP API.
+func (m *apiMethod) parameters() []string {
+	parameters := []string{}
+	for _, parameter := range m.Parameters {
+		if parameter != sessionKeyType {
+			parameters = append(parameters, parameter)
+		}
+	}
+	return parameters
+}
+
+// namespaces returns the sorted list of the namespaces.
+func (l *apiCallList) namespaces() []string {
+	namespaces := []string{}
+	for namespace := range l.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// methods returns the overloads of the method with the given API path.
+func (l *apiCallList) methods(apiPath string) []apiMethod {
+	index := strings.LastIndex(apiPath, "/")
+	if index < 0 {
+		return nil
+	}
+	namespace := strings.ReplaceAll(apiPath[:index], "/", ".")
+	methods := []apiMethod{}
+	for _, method := range l.Namespaces[namespace] {
+		if method.Name == apiPath[index+1:] {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// paths returns the sorted API paths of all the methods.
+func (l *apiCallList) paths() []string {
+	paths := []string{}
+	for namespace, methods := range l.Namespaces {
+		for _, method := range methods {
+			apiPath := methodPath(namespace, method.Name)
+			if !utils.Contains(paths, apiPath) {
+				paths = append(paths, apiPath)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// callListCachePath returns the path of the cached API description for a server and version.
+func callListCachePath(server string, version string) (string, error) {
+	cacheFolder, err := api.CacheFolder()
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(server)
+	return path.Join(cacheFolder, fmt.Sprintf("api-%s-%s.json", name, version)), nil
+}
+
+// loadCallList returns the API description of the server, fetching it if it is not cached for its version.
+func loadCallList(conn *api.ConnectionDetails, refresh bool) (*apiCallList, error) {
+	client, err := initClient(conn)
+	if err != nil {
+		return nil, utils.Errorf(err, L("unable to login to the server"))
+	}
+	version, err := getResult[string](client, "api/systemVersion")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to get the server version"))
+	}
+
+	cachePath, err := callListCachePath(conn.Server, version)
+	if err != nil {
+		return nil, err
+	}
+	if !refresh {
+		if callList, err := readCallList(cachePath); err == nil {
+			return callList, nil
+		} else if !os.IsNotExist(err) {
+			log.Warn().Err(err).Msgf(L("Ignoring the cached API description %s"), cachePath)
+		}
+	}
+
+	callList, err := fetchCallList(client)
+	if err != nil {
+		return nil, err
+	}
+	callList.Server = conn.Server
+	callList.Version = version
+
+	data, err := json.Marshal(callList)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to serialize the API description"))
+	}
+	if err := os.MkdirAll(path.Dir(cachePath), 0700); err != nil {
+		return nil, utils.Errorf(err, L("failed to create folder %s"), path.Dir(cachePath))
+	}
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		log.Warn().Err(err).Msgf(L("Failed to cache the API description in %s"), cachePath)
+	}
+	return callList, nil
+}
+
+// fetchCallList gets the description of all the API methods from the server.
+func fetchCallList(client *api.HTTPClient) (*apiCallList, error) {
+	namespaces, err := getResult[map[string]string](client, "api/getApiNamespaces")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the API namespaces"))
+	}
+
+	callList := apiCallList{Namespaces: map[string][]apiMethod{}}
+	for namespace := range namespaces {
+		log.Debug().Msgf("Getting the call list of the %s API namespace", namespace)
+		methods, err := getResult[map[string]apiMethod](client, "api/getApiNamespaceCallList?namespace="+namespace)
+		if err != nil {
+			return nil, utils.Errorf(err, L("failed to list the methods of the %s API namespace"), namespace)
+		}
+		// The methods are indexed by their name and parameter types, sort them to keep a stable order
+		keys := []string{}
+		for key := range methods {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			callList.Namespaces[namespace] = append(callList.Namespaces[namespace], methods[key])
+		}
+	}
+	return &callList, nil
+}
+
+func getResult[T interface{}](client *api.HTTPClient, query string) (T, error) {
+	var result T
+	res, err := api.Get[T](client, query)
+	if err != nil {
+		return result, err
+	}
+	if !res.Success {
+		return result, errors.New(res.Message)
+	}
+	return res.Result, nil
+}
+
+func readCallList(cachePath string) (*apiCallList, error) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	var callList apiCallList
+	if err := json.Unmarshal(data, &callList); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse %s"), cachePath)
+	}
+	return &callList, nil
+}
+
+// readCachedCallList returns the most recently cached API description of the server used by the command.
+//
+// The server isn't contacted to keep the completion fast and to avoid asking for a password.
+func readCachedCallList(cmd *cobra.Command) *apiCallList {
+	server := ""
+	if flag := cmd.Flag("api-server"); flag != nil {
+		server = flag.Value.String()
+	}
+	if server == "" {
+		if session, err := api.FindSession(&api.ConnectionDetails{}); err == nil && session != nil {
+			server = session.Server
+		}
+	}
+	if server == "" {
+		return nil
+	}
+
+	pattern, err := callListCachePath(server, "*")
+	if err != nil {
+		return nil
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil
+	}
+	var newest *apiCallList
+	var newestTime int64
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || (newest != nil && info.ModTime().UnixNano() <= newestTime) {
+			continue
+		}
+		if callList, err := readCallList(match); err == nil && callList.Server == server {
+			newest = callList
+			newestTime = info.ModTime().UnixNano()
+		}
+	}
+	return newest
+}
+
+func completeNamespace(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	callList := readCachedCallList(cmd)
+	if len(args) > 0 || callList == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterPrefix(callList.namespaces(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completePath(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	callList := readCachedCallList(cmd)
+	if len(args) > 0 || callList == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return filterPrefix(callList.paths(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func filterPrefix(values []string, prefix string) []string {
+	filtered := []string{}
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+const systemCallList = `{
+	"getDetails_sessionKey_int": {
+		"name": "getDetails", "parameters": ["sessionKey", "int"],
+		"exceptions": ["NoSuchSystemException"], "return": "struct"
+	},
+	"getDetails_sessionKey_string": {"name": "getDetails", "parameters": ["sessionKey", "string"], "return": "struct"},
+	"listSystems_sessionKey": {"name": "listSystems", "parameters": ["sessionKey"], "return": "array"}
+}`
+
+func TestLoadCallList(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	server.HandleResult("api/systemVersion", "2024.05")
+	server.HandleResult("api/getApiNamespaces", map[string]string{"system": "SystemHandler"})
+	server.Handle("api/getApiNamespaceCallList", func(call *test_utils.APICall) (interface{}, error) {
+		test_utils.AssertEquals(t, "wrong namespace", "system", call.Query.Get("namespace"))
+		return json.RawMessage(systemCallList), nil
+	})
+	serverName := server.Host()
+	conn := api.ConnectionDetails{Server: serverName, User: "admin", Password: "secret", Insecure: true}
+
+	for i := 0; i < 2; i++ {
+		callList, err := loadCallList(&conn, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong version", "2024.05", callList.Version)
+		test_utils.AssertEquals(t, "wrong number of methods", 3, len(callList.Namespaces["system"]))
+	}
+	test_utils.AssertEquals(t, "the cached description should be reused", 1,
+		len(server.Calls("api/getApiNamespaces")))
+
+	var out bytes.Buffer
+	callList := readCachedCallList(newCompletionCommand(serverName))
+	if callList == nil {
+		t.Fatal("Cached API description not found")
+	}
+	if err := printDescription(&out, callList, "system/getDetails"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := `system/getDetails
+  Parameters: int
+  Returns: struct
+  Exceptions: NoSuchSystemException
+
+system/getDetails
+  Parameters: string
+  Returns: struct
+`
+	test_utils.AssertEquals(t, "wrong description", expected, out.String())
+	if err := printDescription(&out, callList, "system/missing"); err == nil {
+		t.Error("Expected an error for an unknown method")
+	}
+}
+
+func newCompletionCommand(server string) *cobra.Command {
+	cmd := &cobra.Command{Use: "get"}
+	cmd.Flags().String("api-server", "", "")
+	_ = cmd.Flags().Set("api-server", server)
+	return cmd
+}
+
+func TestCompletePath(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cmd := newCompletionCommand("server.lab")
+
+	completions, _ := completePath(cmd, []string{}, "sys")
+	test_utils.AssertEquals(t, "completion without cache", 0, len(completions))
+
+	callList := apiCallList{
+		Server:  "server.lab",
+		Version: "2024.05",
+		Namespaces: map[string][]apiMethod{
+			"system": {
+				{Name: "getDetails", Parameters: []string{sessionKeyType, "int"}},
+				{Name: "listSystems", Parameters: []string{sessionKeyType}},
+			},
+			"channel.software": {
+				{Name: "create", Parameters: []string{sessionKeyType, "string", "string"}},
+			},
+		},
+	}
+	writeCallList(t, &callList)
+
+	completions, _ = completePath(cmd, []string{}, "sys")
+	test_utils.AssertEquals(t, "wrong paths", "system/getDetails,system/listSystems", strings.Join(completions, ","))
+	completions, _ = completePath(cmd, []string{}, "")
+	test_utils.AssertEquals(t, "wrong number of paths", 3, len(completions))
+
+	// The parameters names are not provided by the server
+	completions, _ = completePath(cmd, []string{"channel/software/create"}, "")
+	test_utils.AssertEquals(t, "unexpected parameters completion", 0, len(completions))
+}
+
+func writeCallList(t *testing.T, callList *apiCallList) {
+	cachePath, err := callListCachePath(callList.Server, callList.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(callList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(path.Dir(cachePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,14 +5,13 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/uyuni-project/uyuni-tools/shared/api"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
@@ -20,26 +19,22 @@ import (
 
 func runGet(globalFlags *types.GlobalFlags, flags *apiFlags, cmd *cobra.Command, args []string) error {
 	log.Debug().Msgf("Running GET command %s", args[0])
-	client, err := api.Init(&flags.ConnectionDetails)
+	readOutputFile(cmd, &flags.OutputFlags)
+	if err := checkOutputFlags(&flags.OutputFlags); err != nil {
+		return err
+	}
+	client, err := initClient(&flags.ConnectionDetails)
 
 	if err != nil {
 		return utils.Errorf(err, L("unable to login to the server"))
 	}
 	path := args[0]
 	options := args[1:]
+	query := fmt.Sprintf("%s?%s", path, strings.Join(options, "&"))
 
-	res, err := api.Get[interface{}](client, fmt.Sprintf("%s?%s", path, strings.Join(options, "&")))
+	res, err := client.Get(query)
 	if err != nil {
 		return utils.Errorf(err, L("error in query %s"), path)
 	}
-
-	// TODO do this only when result is JSON or TEXT. Watchout for binary data
-	// Decode JSON to the string and pretty print it
-	out, err := json.MarshalIndent(res.Result, "", "  ")
-	if err != nil {
-		return err
-	}
-	fmt.Print(string(out))
-
-	return nil
+	return writeResponse(os.Stdout, res, &flags.OutputFlags)
 }
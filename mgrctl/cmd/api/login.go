@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func runLogin(globalFlags *types.GlobalFlags, flags *apiFlags, cmd *cobra.Command, args []string) error {
+	if flags.User == "" {
+		return errors.New(L("API user is required to login"))
+	}
+	// Always ask for the password to avoid reusing the stored session
+	utils.AskPasswordIfMissing(&flags.Password, L("API server password"), 0, 0)
+
+	discovered := flags.Server == ""
+	client, err := initClient(&flags.ConnectionDetails)
+	if err != nil {
+		return utils.Errorf(err, L("unable to login to the server"))
+	}
+	if err := api.SaveSession(client, &flags.ConnectionDetails, discovered); err != nil {
+		return utils.Errorf(err, L("failed to store the API session"))
+	}
+	log.Info().Msgf(L("Logged in as %[1]s on %[2]s"), flags.User, flags.Server)
+	return nil
+}
+
+func runLogout(globalFlags *types.GlobalFlags, flags *apiFlags, cmd *cobra.Command, args []string) error {
+	session, err := api.FindSession(&api.ConnectionDetails{Server: flags.Server, User: flags.User})
+	if err != nil {
+		log.Warn().Err(err).Msg(L("Ignoring the stored API session"))
+	}
+	if session == nil {
+		log.Info().Msg(L("No active API session"))
+		return nil
+	}
+
+	client, err := api.Init(&api.ConnectionDetails{
+		Server: session.Server, User: session.User, Retries: flags.Retries,
+	})
+	if err == nil {
+		err = client.Logout()
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf(L("Failed to logout from %s"), session.Server)
+	} else {
+		log.Info().Msgf(L("Logged out from %s"), session.Server)
+	}
+	return api.RemoveSession(session.Server)
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+func TestLoginSession(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	server.HandleResult("system/listSystems", []interface{}{})
+
+	var globalFlags types.GlobalFlags
+	flags := apiFlags{ConnectionDetails: api.ConnectionDetails{
+		Server: server.Host(), User: "admin", Password: "wrong", Insecure: true,
+	}}
+	if err := runLogin(&globalFlags, &flags, nil, nil); err == nil {
+		t.Error("Expected an error for wrong credentials")
+	}
+
+	flags.Password = "secret"
+	if err := runLogin(&globalFlags, &flags, nil, nil); err != nil {
+		t.Fatalf("Unexpected login error: %s", err)
+	}
+
+	// The stored session is used without credentials
+	client, err := initClient(&api.ConnectionDetails{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "wrong server from the session", strings.Contains(client.BaseURL, server.Host()))
+	res, err := api.Get[[]interface{}](client, "system/listSystems")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "call with the stored session failed", res.Success)
+
+	if err := runLogout(&globalFlags, &apiFlags{}, nil, nil); err != nil {
+		t.Fatalf("Unexpected logout error: %s", err)
+	}
+	test_utils.AssertEquals(t, "logout not called", 1, len(server.Calls("auth/logout")))
+	if _, err := api.Get[[]interface{}](client, "system/listSystems"); err == nil {
+		t.Error("Expected an error with the closed session")
+	}
+	if session, _ := api.LoadSession(server.Host()); session != nil {
+		t.Error("Session not removed by logout")
+	}
+}
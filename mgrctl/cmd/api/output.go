@@ -0,0 +1,416 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputTable      = "table"
+	outputJSONPath   = "jsonpath="
+	outputGoTemplate = "go-template="
+)
+
+// OutputFlags are the flags controlling how the API results are printed.
+type OutputFlags struct {
+	Output  string
+	Columns []string
+	Raw     bool
+	// File is read from the output-file flag as the output.file key would conflict with the output one.
+	File string `mapstructure:"-"`
+}
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputJSON,
+		L("Output format: json, yaml, table, jsonpath=<expression> or go-template=<template>"))
+	cmd.Flags().StringSlice("columns", []string{},
+		L("Comma-separated list of the fields to show in table output. Nested fields can be separated by dots"))
+	cmd.Flags().Bool("raw", false, L("Print the response body unchanged, for non-JSON or binary responses"))
+	cmd.Flags().String("output-file", "",
+		L("Write the result to a file instead of printing it. String results and non-JSON responses are written as is"))
+}
+
+// readOutputFile sets the output file from the command flag.
+func readOutputFile(cmd *cobra.Command, flags *OutputFlags) {
+	if cmd != nil {
+		flags.File, _ = cmd.Flags().GetString("output-file")
+	}
+}
+
+// checkOutputFlags validates the output flags before calling the API.
+func checkOutputFlags(flags *OutputFlags) error {
+	if flags.Raw && flags.Output != outputJSON {
+		return errors.New(L("--raw and --output cannot be used together"))
+	}
+	switch {
+	case flags.Output == outputJSON, flags.Output == outputYAML, flags.Output == outputTable:
+	case strings.HasPrefix(flags.Output, outputJSONPath), strings.HasPrefix(flags.Output, outputGoTemplate):
+	default:
+		return fmt.Errorf(L("unsupported output format: %s"), flags.Output)
+	}
+	if len(flags.Columns) > 0 && flags.Output != outputTable {
+		return errors.New(L("--columns can only be used with table output"))
+	}
+	if flags.File != "" && flags.Output != outputJSON {
+		return errors.New(L("--output-file and --output cannot be used together"))
+	}
+	return nil
+}
+
+// printResult decodes the JSON result of an API call and writes it in the requested format.
+func printResult(out io.Writer, result json.RawMessage, flags *OutputFlags) error {
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	return writeResult(out, decoder, flags)
+}
+
+// writeResult reads the next JSON value of decoder and writes it in the requested format.
+//
+// The decoder needs to use numbers to keep the integers as such.
+func writeResult(out io.Writer, decoder *json.Decoder, flags *OutputFlags) error {
+	if flags.Output == outputJSON {
+		// Indent the result while reading it rather than decoding it, results can be large
+		writer := bufio.NewWriter(out)
+		token, err := decoder.Token()
+		if err == nil {
+			err = copyJSON(writer, decoder, token, "  ")
+		}
+		if err != nil {
+			return utils.Errorf(err, L("failed to parse the result"))
+		}
+		writer.WriteString("\n")
+		return writer.Flush()
+	}
+
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return utils.Errorf(err, L("failed to parse the result"))
+	}
+	data = normalizeNumbers(data)
+
+	switch {
+	case flags.Output == outputYAML:
+		content, err := yaml.Marshal(data)
+		if err != nil {
+			return utils.Errorf(err, L("failed to convert the result to YAML"))
+		}
+		_, err = out.Write(content)
+		return err
+	case flags.Output == outputTable:
+		return writeTable(out, data, flags.Columns)
+	case strings.HasPrefix(flags.Output, outputJSONPath):
+		values, err := evalJSONPath(data, strings.TrimPrefix(flags.Output, outputJSONPath))
+		if err != nil {
+			return err
+		}
+		formatted := []string{}
+		for _, value := range values {
+			formatted = append(formatted, formatValue(value))
+		}
+		_, err = fmt.Fprintln(out, strings.Join(formatted, " "))
+		return err
+	case strings.HasPrefix(flags.Output, outputGoTemplate):
+		tpl, err := template.New("output").Parse(strings.TrimPrefix(flags.Output, outputGoTemplate))
+		if err != nil {
+			return utils.Errorf(err, L("failed to parse the output template"))
+		}
+		return tpl.Execute(out, data)
+	}
+	return fmt.Errorf(L("unsupported output format: %s"), flags.Output)
+}
+
+// copyJSON writes a JSON value read from decoder token by token, starting with the already read token.
+//
+// The value is indented with indent or written compact if indent is empty.
+func copyJSON(out *bufio.Writer, decoder *json.Decoder, token json.Token, indent string) error {
+	type level struct {
+		object bool
+		// count is the number of values in an array or keys and values in an object.
+		count int
+	}
+	levels := []level{}
+	newline := func() {
+		if indent != "" {
+			out.WriteByte('\n')
+			out.WriteString(strings.Repeat(indent, len(levels)))
+		}
+	}
+
+	for {
+		if delim, isDelim := token.(json.Delim); isDelim && (delim == '}' || delim == ']') {
+			closed := levels[len(levels)-1]
+			levels = levels[:len(levels)-1]
+			if closed.count > 0 {
+				newline()
+			}
+			out.WriteByte(byte(delim))
+		} else {
+			if len(levels) > 0 {
+				current := &levels[len(levels)-1]
+				if current.object && current.count%2 == 1 {
+					out.WriteByte(':')
+					if indent != "" {
+						out.WriteByte(' ')
+					}
+				} else {
+					if current.count > 0 {
+						out.WriteByte(',')
+					}
+					newline()
+				}
+				current.count++
+			}
+			if isDelim {
+				out.WriteByte(byte(delim))
+				levels = append(levels, level{object: delim == '{'})
+			} else if err := writeJSONValue(out, token); err != nil {
+				return err
+			}
+		}
+
+		if len(levels) == 0 {
+			return nil
+		}
+		var err error
+		if token, err = decoder.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// writeJSONValue writes a string, number, boolean or null token.
+func writeJSONValue(out io.Writer, value json.Token) error {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	_, err := out.Write(bytes.TrimSuffix(content.Bytes(), []byte("\n")))
+	return err
+}
+
+// decodeResult parses a JSON result keeping the integers as such.
+func decodeResult(result json.RawMessage) (interface{}, error) {
+	if len(result) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse the result"))
+	}
+	return normalizeNumbers(data), nil
+}
+
+func normalizeNumbers(data interface{}) interface{} {
+	switch value := data.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = normalizeNumbers(item)
+		}
+	}
+	return data
+}
+
+// writeTable writes arrays of objects with one column per field.
+//
+// A single object is written as a one row table and scalar values are written as is.
+func writeTable(out io.Writer, data interface{}, columns []string) error {
+	rows := []interface{}{}
+	switch value := data.(type) {
+	case []interface{}:
+		rows = value
+	case map[string]interface{}:
+		rows = append(rows, value)
+	default:
+		_, err := fmt.Fprintln(out, formatValue(data))
+		return err
+	}
+
+	if len(columns) == 0 {
+		columns = tableColumns(rows)
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	headers := []string{}
+	for _, column := range columns {
+		headers = append(headers, strings.ToUpper(column))
+	}
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+
+	for _, row := range rows {
+		cells := []string{}
+		for _, column := range columns {
+			cell := ""
+			if _, isObject := row.(map[string]interface{}); !isObject && column == "value" {
+				cell = formatValue(row)
+			} else if values, err := evalJSONPath(row, "."+column); err == nil && len(values) == 1 {
+				cell = formatValue(values[0])
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	return writer.Flush()
+}
+
+// tableColumns returns the sorted list of fields of the objects or value if there is no object.
+func tableColumns(rows []interface{}) []string {
+	columns := []string{}
+	for _, row := range rows {
+		object, isObject := row.(map[string]interface{})
+		if !isObject {
+			continue
+		}
+		for key := range object {
+			if !utils.Contains(columns, key) {
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+	if len(columns) == 0 {
+		columns = append(columns, "value")
+	}
+	return columns
+}
+
+// formatValue returns strings unchanged and the compact JSON of any other value.
+func formatValue(value interface{}) string {
+	if str, isString := value.(string); isString {
+		return str
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
+
+// evalJSONPath evaluates a simple JSONPath expression like {.items[*].name}.
+//
+// Only fields, indexes and wildcards are supported. Missing fields are ignored.
+func evalJSONPath(data interface{}, expression string) ([]interface{}, error) {
+	expr := strings.TrimSpace(expression)
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}")
+	expr = strings.TrimPrefix(expr, "$")
+
+	nodes := []interface{}{data}
+	for len(expr) > 0 {
+		var selected []interface{}
+		switch expr[0] {
+		case '.':
+			end := strings.IndexAny(expr[1:], ".[")
+			if end < 0 {
+				end = len(expr) - 1
+			}
+			name := expr[1 : end+1]
+			expr = expr[end+1:]
+			if name == "" {
+				continue
+			}
+			selected = selectField(nodes, name)
+		case '[':
+			end := strings.Index(expr, "]")
+			if end < 0 {
+				return nil, fmt.Errorf(L("unclosed bracket in JSONPath expression: %s"), expression)
+			}
+			selector := expr[1:end]
+			expr = expr[end+1:]
+			if strings.HasPrefix(selector, "'") || strings.HasPrefix(selector, "\"") {
+				selected = selectField(nodes, strings.Trim(selector, "'\""))
+			} else if selector == "*" {
+				selected = selectField(nodes, "*")
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf(L("invalid index in JSONPath expression: %s"), selector)
+				}
+				selected = selectIndex(nodes, index)
+			}
+		default:
+			return nil, fmt.Errorf(L("invalid JSONPath expression: %s"), expression)
+		}
+		nodes = selected
+	}
+	return nodes, nil
+}
+
+func selectField(nodes []interface{}, name string) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		switch value := node.(type) {
+		case map[string]interface{}:
+			if name != "*" {
+				if child, found := value[name]; found {
+					selected = append(selected, child)
+				}
+				continue
+			}
+			keys := []string{}
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				selected = append(selected, value[key])
+			}
+		case []interface{}:
+			if name == "*" {
+				selected = append(selected, value...)
+			}
+		}
+	}
+	return selected
+}
+
+func selectIndex(nodes []interface{}, index int) []interface{} {
+	selected := []interface{}{}
+	for _, node := range nodes {
+		if array, isArray := node.([]interface{}); isArray {
+			i := index
+			if i < 0 {
+				i += len(array)
+			}
+			if i >= 0 && i < len(array) {
+				selected = append(selected, array[i])
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+const systemsResult = `[
+	{"id": 1000010000, "name": "minion1", "details": {"arch": "x86_64"}},
+	{"id": 1000010001, "name": "minion2", "details": {"arch": "aarch64"}}
+]`
+
+func TestPrintResult(t *testing.T) {
+	data := [][]string{
+		{outputJSON, `{"id": 1000010000}`, "{\n  \"id\": 1000010000\n}\n"},
+		{outputYAML, `{"id": 1000010000, "name": "minion1"}`, "id: 1000010000\nname: minion1\n"},
+		{outputJSONPath + "{[*].name}", systemsResult, "minion1 minion2\n"},
+		{outputJSONPath + "{$[1].details.arch}", systemsResult, "aarch64\n"},
+		{outputJSONPath + "{[0].details}", systemsResult, "{\"arch\":\"x86_64\"}\n"},
+		{outputGoTemplate + "{{range .}}{{.id}} {{.name}}\n{{end}}", systemsResult,
+			"1000010000 minion1\n1000010001 minion2\n"},
+		{outputTable, systemsResult,
+			"DETAILS             ID          NAME\n" +
+				"{\"arch\":\"x86_64\"}   1000010000  minion1\n" +
+				"{\"arch\":\"aarch64\"}  1000010001  minion2\n"},
+		{outputTable, `["a", "b"]`, "VALUE\na\nb\n"},
+		{outputTable, `42`, "42\n"},
+	}
+
+	for i, testCase := range data {
+		var out bytes.Buffer
+		flags := OutputFlags{Output: testCase[0]}
+		if err := printResult(&out, json.RawMessage(testCase[1]), &flags); err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+		}
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected output", i), testCase[2], out.String())
+	}
+}
+
+func TestPrintTableColumns(t *testing.T) {
+	var out bytes.Buffer
+	flags := OutputFlags{Output: outputTable, Columns: []string{"name", "details.arch", "missing"}}
+	if err := printResult(&out, json.RawMessage(systemsResult), &flags); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "NAME     DETAILS.ARCH  MISSING\n" +
+		"minion1  x86_64        \n" +
+		"minion2  aarch64       \n"
+	test_utils.AssertEquals(t, "unexpected table", expected, out.String())
+}
+
+func TestCheckOutputFlags(t *testing.T) {
+	type testCase struct {
+		flags OutputFlags
+		valid bool
+	}
+	data := []testCase{
+		{OutputFlags{Output: outputJSON}, true},
+		{OutputFlags{Output: outputJSON, Raw: true}, true},
+		{OutputFlags{Output: outputYAML, Raw: true}, false},
+		{OutputFlags{Output: "xml"}, false},
+		{OutputFlags{Output: outputJSONPath + "{.id}"}, true},
+		{OutputFlags{Output: outputTable, Columns: []string{"id"}}, true},
+		{OutputFlags{Output: outputYAML, Columns: []string{"id"}}, false},
+	}
+
+	for i, test := range data {
+		err := checkOutputFlags(&test.flags)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected validation result", i), test.valid, err == nil)
+	}
+}
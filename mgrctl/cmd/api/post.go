@@ -5,14 +5,11 @@
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"os"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
-	"github.com/uyuni-project/uyuni-tools/shared/api"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
@@ -20,43 +17,26 @@ import (
 
 func runPost(globalFlags *types.GlobalFlags, flags *apiFlags, cmd *cobra.Command, args []string) error {
 	log.Debug().Msgf("Running POST command %s", args[0])
-	client, err := api.Init(&flags.ConnectionDetails)
-
+	readOutputFile(cmd, &flags.OutputFlags)
+	if err := checkOutputFlags(&flags.OutputFlags); err != nil {
+		return err
+	}
+	body, err := readPostBody(flags.Data, flags.Form, args[1:])
 	if err != nil {
-		return utils.Errorf(err, L("unable to login to the server"))
+		return err
 	}
+	defer body.Close()
 
-	path := args[0]
-	options := args[1:]
-
-	var data map[string]interface{}
-
-	if len(options) > 1 {
-		log.Debug().Msg("Multiple options specified, assuming non JSON data")
-		data = map[string]interface{}{}
-		for _, o := range options {
-			s := strings.SplitN(o, "=", 2)
-			data[s[0]] = s[1]
-		}
-	} else {
-		if err := json.NewDecoder(strings.NewReader(args[1])).Decode(&data); err != nil {
-			log.Debug().Msg("Failed to decode parameters as JSON, assuming key=value pairs")
-		}
-	}
+	client, err := initClient(&flags.ConnectionDetails)
 
-	res, err := api.Post[interface{}](client, path, data)
 	if err != nil {
-		return utils.Errorf(err, L("error in query %s"), path)
+		return utils.Errorf(err, L("unable to login to the server"))
 	}
 
-	if !res.Success {
-		log.Error().Msg(res.Message)
-	}
-	out, err := json.MarshalIndent(res.Result, "", "  ")
+	path := args[0]
+	res, err := client.PostBody(path, body.contentType, body.reader)
 	if err != nil {
-		log.Fatal().Err(err)
+		return utils.Errorf(err, L("error in query %s"), path)
 	}
-	fmt.Print(string(out))
-
-	return nil
+	return writeResponse(os.Stdout, res, &flags.OutputFlags)
 }
@@ -0,0 +1,245 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+const formContentType = "application/x-www-form-urlencoded"
+
+// postBody is the body of a POST request.
+type postBody struct {
+	contentType string
+	reader      io.Reader
+	closer      io.Closer
+}
+
+// Close closes the file the body is read from, if any.
+func (b *postBody) Close() {
+	if b.closer != nil {
+		b.closer.Close()
+	}
+}
+
+// readPostBody prepares the body of a POST request from the data flag or the parameters.
+//
+// The data can be inline JSON, @path to read a JSON file or @- to read the standard input.
+// Files are streamed to the server rather than loaded in memory.
+// The parameters are either a single JSON object or key=value pairs,
+// sent as a JSON object or form-encoded if form is true.
+func readPostBody(data string, form bool, params []string) (*postBody, error) {
+	if data != "" {
+		if len(params) > 0 || form {
+			return nil, errors.New(L("--data cannot be used with parameters or --form"))
+		}
+		switch {
+		case data == "@-":
+			return &postBody{contentType: api.JSONContentType, reader: os.Stdin}, nil
+		case strings.HasPrefix(data, "@"):
+			file, err := os.Open(strings.TrimPrefix(data, "@"))
+			if err != nil {
+				return nil, utils.Errorf(err, L("failed to open %s"), strings.TrimPrefix(data, "@"))
+			}
+			return &postBody{contentType: api.JSONContentType, reader: file, closer: file}, nil
+		}
+		if !json.Valid([]byte(data)) {
+			return nil, errors.New(L("--data is not valid JSON"))
+		}
+		return &postBody{contentType: api.JSONContentType, reader: strings.NewReader(data)}, nil
+	}
+
+	if len(params) == 1 && !form && json.Valid([]byte(params[0])) {
+		return &postBody{contentType: api.JSONContentType, reader: strings.NewReader(params[0])}, nil
+	}
+
+	values := url.Values{}
+	for _, param := range params {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf(L("invalid parameter %s: expected key=value or a JSON object"), param)
+		}
+		values.Add(parts[0], parts[1])
+	}
+	if form {
+		return &postBody{contentType: formContentType, reader: strings.NewReader(values.Encode())}, nil
+	}
+
+	fields := map[string]interface{}{}
+	for key := range values {
+		fields[key] = values.Get(key)
+	}
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return &postBody{contentType: api.JSONContentType, reader: bytes.NewReader(content)}, nil
+}
+
+// writeResponse prints or saves the response of an API call according to the output flags.
+func writeResponse(out io.Writer, res *http.Response, flags *OutputFlags) error {
+	defer res.Body.Close()
+
+	if flags.File != "" {
+		return saveResponse(res, flags)
+	}
+	if flags.Raw {
+		_, err := io.Copy(out, res.Body)
+		return err
+	}
+	if !api.IsJSON(res) {
+		return fmt.Errorf(L("the server replied with %s content, use --raw or --output-file to get it"),
+			res.Header.Get("Content-Type"))
+	}
+
+	status, err := readResponse(res.Body, func(decoder *json.Decoder) error {
+		return writeResult(out, decoder, flags)
+	})
+	if err != nil {
+		return err
+	}
+	if err := status.err(); err != nil {
+		return err
+	}
+	if !status.hasResult {
+		return printResult(out, nil, flags)
+	}
+	return nil
+}
+
+// responseStatus is the content of an API response apart from its result.
+type responseStatus struct {
+	success   bool
+	message   string
+	hasResult bool
+}
+
+// err returns the error reported by a failed call or nil if the call succeeded.
+func (s *responseStatus) err() error {
+	if s.success {
+		return nil
+	}
+	if s.message == "" {
+		return errors.New(L("the API call failed"))
+	}
+	return errors.New(s.message)
+}
+
+// readResponse reads a JSON API response, passing the decoder to writeResult when reaching the result.
+//
+// The result is processed while it is received rather than loaded in memory, results can be large.
+func readResponse(body io.Reader, writeResult func(decoder *json.Decoder) error) (*responseStatus, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	status := responseStatus{}
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		if err == nil {
+			err = errors.New(L("not a JSON object"))
+		}
+		return nil, utils.Errorf(err, L("failed to parse the response"))
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, utils.Errorf(err, L("failed to parse the response"))
+		}
+		key, _ := token.(string)
+		switch {
+		case strings.EqualFold(key, "result"):
+			status.hasResult = true
+			if err := writeResult(decoder); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.EqualFold(key, "success"):
+			err = decoder.Decode(&status.success)
+		case strings.EqualFold(key, "message"):
+			err = decoder.Decode(&status.message)
+		default:
+			var ignored json.RawMessage
+			err = decoder.Decode(&ignored)
+		}
+		if err != nil {
+			return nil, utils.Errorf(err, L("failed to parse the response"))
+		}
+	}
+	return &status, nil
+}
+
+// saveResponse writes the response body to the output file.
+//
+// The result of JSON responses is extracted: strings like exported files are written without quotes.
+func saveResponse(res *http.Response, flags *OutputFlags) error {
+	if !api.IsJSON(res) || flags.Raw {
+		return writeFile(flags.File, func(out *bufio.Writer) error {
+			_, err := io.Copy(out, res.Body)
+			return err
+		})
+	}
+
+	status, err := readResponse(res.Body, func(decoder *json.Decoder) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return utils.Errorf(err, L("failed to parse the response"))
+		}
+		return writeFile(flags.File, func(out *bufio.Writer) error {
+			if text, isString := token.(string); isString {
+				_, err := out.WriteString(text)
+				return err
+			}
+			return copyJSON(out, decoder, token, "")
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if err := status.err(); err != nil {
+		if status.hasResult {
+			os.Remove(flags.File)
+		}
+		return err
+	}
+	if !status.hasResult {
+		return writeFile(flags.File, func(out *bufio.Writer) error { return nil })
+	}
+	return nil
+}
+
+// writeFile creates a file with the content written by the write function.
+func writeFile(path string, write func(out *bufio.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return utils.Errorf(err, L("failed to create %s"), path)
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	if err = write(out); err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		return utils.Errorf(err, L("failed to write %s"), path)
+	}
+	if info, err := file.Stat(); err == nil {
+		log.Info().Msgf(L("%[1]d bytes written to %[2]s"), info.Size(), path)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+)
+
+func TestReadPostBody(t *testing.T) {
+	dataPath := path.Join(t.TempDir(), "data.json")
+	test_utils.WriteFile(t, dataPath, `{"label": "from-file"}`)
+
+	type testCase struct {
+		data        string
+		form        bool
+		params      []string
+		contentType string
+		expected    string
+	}
+	data := []testCase{
+		{"", false, []string{`{"label": "inline"}`}, api.JSONContentType, `{"label": "inline"}`},
+		{"", false, []string{"label=test"}, api.JSONContentType, `{"label":"test"}`},
+		{"", false, []string{"label=test", "name=a=b"}, api.JSONContentType, `{"label":"test","name":"a=b"}`},
+		{"", false, []string{}, api.JSONContentType, `{}`},
+		{"", true, []string{"label=test", "name=a b"}, formContentType, "label=test&name=a+b"},
+		{`{"label": "data"}`, false, []string{}, api.JSONContentType, `{"label": "data"}`},
+		{"@" + dataPath, false, []string{}, api.JSONContentType, `{"label": "from-file"}`},
+	}
+
+	for i, test := range data {
+		body, err := readPostBody(test.data, test.form, test.params)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %s", i, err)
+		}
+		content, err := io.ReadAll(body.reader)
+		body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		test_utils.AssertEquals(t, "wrong content type", test.contentType, body.contentType)
+		test_utils.AssertEquals(t, "wrong body", test.expected, string(content))
+	}
+
+	for _, params := range [][]string{{"invalid"}, {"label=test", "{}"}} {
+		if _, err := readPostBody("", false, params); err == nil {
+			t.Errorf("Expected an error for parameters %v", params)
+		}
+	}
+	if _, err := readPostBody("not json", false, []string{}); err == nil {
+		t.Error("Expected an error for invalid JSON data")
+	}
+	if _, err := readPostBody("@"+dataPath, false, []string{"label=test"}); err == nil {
+		t.Error("Expected an error for data and parameters")
+	}
+	if _, err := readPostBody("@"+path.Join(t.TempDir(), "missing"), false, []string{}); err == nil {
+		t.Error("Expected an error for a missing data file")
+	}
+}
+
+func TestDownloads(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	server.HandleResult("kickstart/profile/downloadKickstart", "#kickstart\ntext\n")
+	server.HandleResult("system/getDetails", map[string]interface{}{"id": 1})
+	server.HandleResult("file/download", &test_utils.APIRawResponse{
+		ContentType: "application/octet-stream",
+		Body:        []byte{0, 1, 2},
+	})
+	server.Handle("channel/software/create", func(call *test_utils.APICall) (interface{}, error) {
+		return string(call.Body), nil
+	})
+	server.Handle("form/post", func(call *test_utils.APICall) (interface{}, error) {
+		values, err := url.ParseQuery(string(call.Body))
+		return values.Get("label"), err
+	})
+
+	var globalFlags types.GlobalFlags
+	newFlags := func(file string) *apiFlags {
+		return &apiFlags{
+			ConnectionDetails: api.ConnectionDetails{
+				Server: server.Host(), User: "admin", Password: "secret", Insecure: true,
+			},
+			OutputFlags: OutputFlags{Output: outputJSON, File: file},
+		}
+	}
+	outPath := path.Join(t.TempDir(), "out")
+
+	type testCase struct {
+		method   string
+		args     []string
+		data     string
+		form     bool
+		expected string
+	}
+	dataPath := path.Join(t.TempDir(), "data.json")
+	test_utils.WriteFile(t, dataPath, `{"label": "big"}`)
+	data := []testCase{
+		{"get", []string{"kickstart/profile/downloadKickstart"}, "", false, "#kickstart\ntext\n"},
+		{"get", []string{"system/getDetails", "sid=1"}, "", false, `{"id":1}`},
+		{"get", []string{"file/download"}, "", false, "\x00\x01\x02"},
+		{"post", []string{"channel/software/create"}, "@" + dataPath, false, `{"label": "big"}`},
+		{"post", []string{"form/post", "label=formed"}, "", true, "formed"},
+	}
+	for i, test := range data {
+		flags := newFlags(outPath)
+		flags.Data = test.data
+		flags.Form = test.form
+		var err error
+		if test.method == "get" {
+			err = runGet(&globalFlags, flags, nil, test.args)
+		} else {
+			err = runPost(&globalFlags, flags, nil, test.args)
+		}
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %s", i, err)
+		}
+		test_utils.AssertEquals(t, "wrong file content", test.expected, test_utils.ReadFile(t, outPath))
+	}
+
+	// Non JSON responses are not printed
+	if err := runGet(&globalFlags, newFlags(""), nil, []string{"file/download"}); err == nil {
+		t.Error("Expected an error for a binary response without output file")
+	}
+	os.Remove(outPath)
+	if err := runGet(&globalFlags, newFlags(outPath), nil, []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unknown path")
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	newResponse := func(body string) *http.Response {
+		return &http.Response{
+			Header: http.Header{"Content-Type": []string{api.JSONContentType}},
+			Body:   io.NopCloser(strings.NewReader(body)),
+		}
+	}
+	result := `{"systems": [{"id": 1000010000, "name": "<minion>", "tags": []}, 1.5e3], "empty": {}, "none": null}`
+	var expected bytes.Buffer
+	if err := json.Indent(&expected, []byte(result), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	expected.WriteString("\n")
+
+	// The result is written as is even if the success field comes after it
+	for _, body := range []string{
+		`{"success": true, "result": ` + result + `}`,
+		`{"result": ` + result + `, "success": true}`,
+	} {
+		var out bytes.Buffer
+		if err := writeResponse(&out, newResponse(body), &OutputFlags{Output: outputJSON}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong indented result", expected.String(), out.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeResponse(&out, newResponse(`{"success": false, "message": "failed"}`),
+		&OutputFlags{Output: outputJSON}); err == nil || err.Error() != "failed" {
+		t.Errorf("Expected the failure message as error, got %v", err)
+	}
+	test_utils.AssertEquals(t, "unexpected output for a failed call", "", out.String())
+
+	if err := writeResponse(&out, newResponse(`{"success": true}`), &OutputFlags{Output: outputJSON}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong missing result", "null\n", out.String())
+
+	if err := writeResponse(&out, newResponse(`{"success": true, "result": [1,`),
+		&OutputFlags{Output: outputJSON}); err == nil {
+		t.Error("Expected an error for a truncated response")
+	}
+
+	outPath := path.Join(t.TempDir(), "out")
+	err := writeResponse(&out, newResponse(`{"success": true, "result": `+result+`}`),
+		&OutputFlags{Output: outputJSON, File: outPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(result)); err != nil {
+		t.Fatal(err)
+	}
+	test_utils.AssertEquals(t, "wrong saved result", compact.String(), test_utils.ReadFile(t, outPath))
+
+	err = writeResponse(&out, newResponse(`{"result": "partial", "success": false, "message": "failed"}`),
+		&OutputFlags{Output: outputJSON, File: outPath})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Expected the failure message, got %v", err)
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Error("The output file of a failed call should be removed")
+	}
+}
@@ -92,7 +92,7 @@ func run(globalFlags *types.GlobalFlags, flags *flagpole, cmd *cobra.Command, ar
 		commandArgs = append(commandArgs, newEnv...)
 	}
 	commandArgs = append(commandArgs, "sh", "-c", strings.Join(args, " "))
-	err = RunRawCmd(command, commandArgs)
+	err = RunRawCmd(cnx.HostCommand(flags.Tty, command, commandArgs...))
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			log.Info().Err(err).Msg(L("Command failed"))
@@ -101,7 +101,7 @@ func Deploy(imageFlags *utils.ProxyImageFlags, helmFlags *HelmFlags, configDir s
 }
 
 func getSSHYaml(directory string) (string, error) {
-	sshPayload, err := kubernetes.GetSecret("proxy-secret", "-o=jsonpath={.data.ssh\\.yaml}")
+	sshPayload, err := kubernetes.GetSecret("proxy-secret", "ssh.yaml")
 	if err != nil {
 		return "", err
 	}
@@ -116,7 +116,7 @@ func getSSHYaml(directory string) (string, error) {
 }
 
 func getHTTPDYaml(directory string) (string, error) {
-	httpdPayload, err := kubernetes.GetSecret("proxy-secret", "-o=jsonpath={.data.httpd\\.yaml}")
+	httpdPayload, err := kubernetes.GetSecret("proxy-secret", "httpd.yaml")
 	if err != nil {
 		return "", err
 	}
@@ -131,7 +131,7 @@ func getHTTPDYaml(directory string) (string, error) {
 }
 
 func getConfigYaml(directory string) (string, error) {
-	configPayload, err := kubernetes.GetConfigMap("proxy-configMap", "-o=jsonpath={.data.config\\.yaml}")
+	configPayload, err := kubernetes.GetConfigMap("proxy-configMap", "config.yaml")
 	if err != nil {
 		return "", err
 	}
@@ -166,15 +166,15 @@ func Upgrade(flags *KubernetesProxyUpgradeFlags, cmd *cobra.Command, args []stri
 		return err
 	}
 
-	err = kubernetes.ReplicasTo(kubernetes.ProxyApp, 0)
+	err = kubernetes.ReplicasTo("", kubernetes.ProxyApp, 0)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		// if something is running, we don't need to set replicas to 1
-		if _, err = kubernetes.GetNode("uyuni"); err != nil {
-			err = kubernetes.ReplicasTo(kubernetes.ProxyApp, 1)
+		if _, err = kubernetes.GetNode("", "uyuni"); err != nil {
+			err = kubernetes.ReplicasTo("", kubernetes.ProxyApp, 1)
 		}
 	}()
 
@@ -63,10 +63,12 @@ func (f *ProxyImageFlags) GetContainerImage(name string) string {
 func AddSCCFlag(cmd *cobra.Command) {
 	cmd.Flags().String("scc-user", "", L("SUSE Customer Center username. It will be used to pull images from registry.suse.com"))
 	cmd.Flags().String("scc-password", "", L("SUSE Customer Center password. It will be used to pull images from registry.suse.com"))
+	utils.AddPasswordFileFlag(cmd.Flags(), "scc-password")
 
 	_ = utils.AddFlagHelpGroup(cmd, &utils.Group{ID: "scc", Title: L("SUSE Customer Center Flags")})
 	_ = utils.AddFlagToHelpGroupID(cmd, "scc-user", "scc")
 	_ = utils.AddFlagToHelpGroupID(cmd, "scc-password", "scc")
+	_ = utils.AddFlagToHelpGroupID(cmd, "scc-password-file", "scc")
 }
 
 // AddImageFlags will add the proxy install flags to a command.
@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activationkey
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Create creates an activation key and returns it.
+func Create(client *api.HTTPClient, key *types.CreateActivationKeyRequest) (string, error) {
+	res, err := api.Post[string](client, "activationkey/create", key)
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to create the activation key"))
+	}
+	if !res.Success {
+		return "", errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activationkey
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Delete deletes an activation key.
+func Delete(client *api.HTTPClient, key string) error {
+	res, err := api.Post[int](client, "activationkey/delete", map[string]interface{}{"key": key})
+	if err != nil {
+		return utils.Errorf(err, L("failed to delete the activation key"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
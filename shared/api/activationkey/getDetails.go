@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activationkey
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// GetDetails returns the details of an activation key.
+func GetDetails(client *api.HTTPClient, key string) (*types.ActivationKey, error) {
+	res, err := api.Get[types.ActivationKey](client, "activationkey/getDetails?key="+url.QueryEscape(key))
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to get the activation key details"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return &res.Result, nil
+}
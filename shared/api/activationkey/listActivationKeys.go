@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activationkey
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ListActivationKeys returns the activation keys visible to the user.
+func ListActivationKeys(client *api.HTTPClient) ([]types.ActivationKey, error) {
+	res, err := api.Get[[]types.ActivationKey](client, "activationkey/listActivationKeys")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the activation keys"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
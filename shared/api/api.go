@@ -9,7 +9,10 @@ import (
 	"crypto/x509"
 	"errors"
 	"io"
+	"mime"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -25,6 +28,24 @@ import (
 
 const root_path_apiv1 = "/rhn/manager/api"
 
+// sessionCookieName is the name of the authentication cookie.
+const sessionCookieName = "pxt-session-cookie"
+
+// defaultTimeout is the connection and response headers timeout of the requests if none is configured.
+const defaultTimeout = time.Minute
+
+// DefaultRetries is the default number of times a failed request is retried.
+const DefaultRetries = 3
+
+// defaultBackoff is the delay before the first retry if none is configured.
+const defaultBackoff = time.Second
+
+// JSONContentType is the content type of the API requests and responses.
+const JSONContentType = "application/json; charset=utf-8"
+
+// maxErrorBodySize is the maximum size of a non JSON error body to show.
+const maxErrorBodySize = 512
+
 // HTTP Client is an API entrypoint.
 type HTTPClient struct {
 
@@ -36,6 +57,12 @@ type HTTPClient struct {
 
 	// Authentication cookie storage
 	AuthCookie *http.Cookie
+
+	// Number of times a failed request is retried
+	Retries int
+
+	// Delay before the first retry, doubled for each following retry
+	Backoff time.Duration
 }
 
 // Connection details for initial API connection.
@@ -56,6 +83,17 @@ type ConnectionDetails struct {
 
 	// Disable certificate validation, unsecure and not recommended.
 	Insecure bool
+
+	// Timeout to connect and to receive the response headers of each request, one minute if not set.
+	// Reading the response body is not limited.
+	Timeout time.Duration
+
+	// Number of times a request is retried on connection errors or if the server is unavailable.
+	// No retry if not set.
+	Retries int
+
+	// Delay before the first retry, doubled for each following retry. One second if not set.
+	Backoff time.Duration
 }
 
 // API response where T is the type of the result.
@@ -69,11 +107,23 @@ type ApiResponse[T interface{}] struct {
 //
 // If the api support is only optional for the command, set optional parameter to true.
 func AddAPIFlags(cmd *cobra.Command, optional bool) error {
-	cmd.PersistentFlags().String("api-server", "", L("FQDN of the server to connect to"))
+	serverHelp := L("FQDN of the server to connect to")
+	cacertHelp := L("Path to a cert file of the CA")
+	if optional {
+		serverHelp = L("FQDN of the server to connect to, read from the local server if not set")
+		cacertHelp = L("Path to a cert file of the CA, read from the local server if the server FQDN is not set")
+	}
+	cmd.PersistentFlags().String("api-server", "", serverHelp)
 	cmd.PersistentFlags().String("api-user", "", L("API user username"))
 	cmd.PersistentFlags().String("api-password", "", L("Password for the API user"))
-	cmd.PersistentFlags().String("api-cacert", "", L("Path to a cert file of the CA"))
+	utils.AddPasswordFileFlag(cmd.PersistentFlags(), "api-password")
+	cmd.PersistentFlags().String("api-cacert", "", cacertHelp)
 	cmd.PersistentFlags().Bool("api-insecure", false, L("If set, server certificate will not be checked for validity"))
+	cmd.PersistentFlags().Duration("api-timeout", defaultTimeout, L("Timeout to connect to the API server and to get the reply of each request, excluding the downloads"))
+	cmd.PersistentFlags().Int("api-retries", DefaultRetries,
+		L("Number of times an API request is retried on connection errors or if the server is unavailable, 0 for none"))
+	cmd.PersistentFlags().Duration("api-backoff", defaultBackoff,
+		L("Delay before retrying a failed API request, doubled for each following retry"))
 
 	if !optional {
 		if err := cmd.MarkPersistentFlagRequired("api-server"); err != nil {
@@ -82,9 +132,7 @@ func AddAPIFlags(cmd *cobra.Command, optional bool) error {
 		if err := cmd.MarkPersistentFlagRequired("api-user"); err != nil {
 			return err
 		}
-		if err := cmd.MarkPersistentFlagRequired("api-password"); err != nil {
-			return err
-		}
+		cmd.MarkFlagsOneRequired("api-password", "api-password-file")
 	}
 	return nil
 }
@@ -99,8 +147,11 @@ func prettyPrint(v interface{}) string {
 
 func (c *HTTPClient) sendRequest(req *http.Request) (*http.Response, error) {
 	log.Debug().Msgf("Sending %s request %s", req.Method, req.URL)
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Accept", "application/json; charset=utf-8")
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", JSONContentType)
+	}
+	// Some calls return files, accept them with a lower priority
+	req.Header.Set("Accept", JSONContentType+", */*;q=0.5")
 	if c.AuthCookie != nil {
 		req.AddCookie(c.AuthCookie)
 	}
@@ -108,7 +159,35 @@ func (c *HTTPClient) sendRequest(req *http.Request) (*http.Response, error) {
 	log.Trace().Msg(prettyPrint(req.Header))
 	log.Trace().Msg(prettyPrint(req.Body))
 
-	res, err := c.Client.Do(req)
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	var res *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = c.Client.Do(req)
+		// Bodies streamed from files or the standard input cannot be sent again
+		rewindable := req.Body == nil || req.GetBody != nil
+		if attempt >= c.Retries || !rewindable || !shouldRetry(req, res, err) {
+			break
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf(L("API request failed, retrying in %s"), backoff)
+		} else {
+			log.Warn().Msgf(L("API server replied with code %[1]d, retrying in %[2]s"), res.StatusCode, backoff)
+			res.Body.Close()
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
 	if err != nil {
 		log.Trace().Err(err).Msgf("Request failed")
 		return nil, err
@@ -118,9 +197,17 @@ func (c *HTTPClient) sendRequest(req *http.Request) (*http.Response, error) {
 	log.Trace().Msg(prettyPrint(res.Body))
 
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		var errResponse map[string]string
-		if err = json.NewDecoder(res.Body).Decode(&errResponse); err == nil {
-			return nil, fmt.Errorf(errResponse["message"])
+		defer res.Body.Close()
+		if IsJSON(res) {
+			var errResponse ApiResponse[interface{}]
+			if err = json.NewDecoder(res.Body).Decode(&errResponse); err == nil && errResponse.Message != "" {
+				return nil, errors.New(errResponse.Message)
+			}
+			return nil, fmt.Errorf(L("unknown error: %d"), res.StatusCode)
+		}
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+		if message := strings.TrimSpace(string(body)); message != "" {
+			return nil, fmt.Errorf(L("server replied with code %[1]d: %[2]s"), res.StatusCode, message)
 		}
 		return nil, fmt.Errorf(L("unknown error: %d"), res.StatusCode)
 	}
@@ -129,6 +216,31 @@ func (c *HTTPClient) sendRequest(req *http.Request) (*http.Response, error) {
 	return res, nil
 }
 
+// IsJSON returns whether the content type of a response is JSON.
+func IsJSON(res *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// shouldRetry returns whether a failed request can safely be sent again.
+//
+// GET requests are idempotent and are retried on any connection error or gateway error.
+// Other requests are only retried if they could not reach the server.
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	idempotent := req.Method == http.MethodGet
+	if err != nil {
+		var opErr *net.OpError
+		return idempotent || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+	switch res.StatusCode {
+	case http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
 // Init returns a HTTPClient object for further API use.
 //
 // Provided connectionDetails must have Server specified with FQDN to the
@@ -137,7 +249,44 @@ func (c *HTTPClient) sendRequest(req *http.Request) (*http.Response, error) {
 // Optionaly connectionDetails can have user name and password set and Init
 // will try to login to the host.
 // caCert can be set to use custom CA certificate to validate target host.
+//
+// If no password is provided and a stored session matches the server and user,
+// the session is reused and the missing connection details are taken from it.
+// See FindSession for the session used when no server is provided.
 func Init(conn *ConnectionDetails) (*HTTPClient, error) {
+	if conn.Password == "" {
+		if session, err := FindSession(conn); err != nil {
+			log.Warn().Err(err).Msg(L("Ignoring the stored API session"))
+		} else if session != nil && session.matches(conn) {
+			log.Debug().Msgf("Reusing API session for %s on %s", session.User, session.Server)
+			conn.Server = session.Server
+			conn.User = session.User
+			if conn.CAcert == "" {
+				conn.CAcert = session.CAcert
+			}
+			conn.Insecure = conn.Insecure || session.Insecure
+			client := newClient(conn)
+			client.AuthCookie = session.cookie()
+			return client, nil
+		}
+	}
+
+	if conn.Server == "" {
+		return nil, errors.New(L("no API server provided and no stored session"))
+	}
+
+	client := newClient(conn)
+	var err error
+	if len(conn.User) > 0 {
+		if len(conn.Password) == 0 {
+			utils.AskPasswordIfMissing(&conn.Password, L("API server password"), 0, 0)
+		}
+		err = client.login(conn)
+	}
+	return client, err
+}
+
+func newClient(conn *ConnectionDetails) *HTTPClient {
 	caCertPool, err := x509.SystemCertPool()
 	if err != nil {
 		log.Warn().Msg(err.Error())
@@ -149,11 +298,24 @@ func Init(conn *ConnectionDetails) (*HTTPClient, error) {
 		}
 		caCertPool.AppendCertsFromPEM(caCert)
 	}
-	client := &HTTPClient{
+	timeout := conn.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	retries := conn.Retries
+	if retries < 0 {
+		retries = 0
+	}
+	return &HTTPClient{
 		BaseURL: fmt.Sprintf("https://%s%s", conn.Server, root_path_apiv1),
+		Retries: retries,
+		Backoff: conn.Backoff,
+		// The timeout doesn't apply to the whole request to not interrupt the large responses being streamed
 		Client: &http.Client{
-			Timeout: time.Minute,
 			Transport: &http.Transport{
+				DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
 				TLSClientConfig: &tls.Config{
 					RootCAs:            caCertPool,
 					InsecureSkipVerify: conn.Insecure,
@@ -161,14 +323,6 @@ func Init(conn *ConnectionDetails) (*HTTPClient, error) {
 			},
 		},
 	}
-
-	if len(conn.User) > 0 {
-		if len(conn.Password) == 0 {
-			utils.AskPasswordIfMissing(&conn.Password, L("API server password"), 0, 0)
-		}
-		err = client.login(conn)
-	}
-	return client, err
 }
 
 func (c *HTTPClient) login(conn *ConnectionDetails) error {
@@ -192,17 +346,18 @@ func (c *HTTPClient) login(conn *ConnectionDetails) error {
 		return err
 	}
 
-	var response map[string]interface{}
+	defer res.Body.Close()
+	var response ApiResponse[interface{}]
 	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
 		return err
 	}
-	if !response["success"].(bool) {
-		return fmt.Errorf(response["messages"].(string))
+	if !response.Success {
+		return errors.New(response.Message)
 	}
 
 	cookies := res.Cookies()
 	for _, cookie := range cookies {
-		if cookie.Name == "pxt-session-cookie" && cookie.MaxAge > 0 {
+		if cookie.Name == sessionCookieName && cookie.MaxAge > 0 {
 			c.AuthCookie = cookie
 			break
 		}
@@ -215,13 +370,24 @@ func (c *HTTPClient) login(conn *ConnectionDetails) error {
 	return nil
 }
 
+// Logout ends the API session on the server.
+func (c *HTTPClient) Logout() error {
+	res, err := c.Post("auth/logout", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	c.AuthCookie = nil
+	return nil
+}
+
 // Post issues a POST HTTP request to the API target
 //
 // `path` specifies an API endpoint
-// `data` contains a map of values to add to the POST query. `data` are serialized to the JSON
+// `data` contains a map or a struct of values to add to the POST query. `data` are serialized to the JSON
 //
 // returns a raw HTTP Response.
-func (c *HTTPClient) Post(path string, data map[string]interface{}) (*http.Response, error) {
+func (c *HTTPClient) Post(path string, data interface{}) (*http.Response, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, path)
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -244,6 +410,22 @@ func (c *HTTPClient) Post(path string, data map[string]interface{}) (*http.Respo
 	return res, nil
 }
 
+// PostBody issues a POST HTTP request to the API target with a body of the given content type
+//
+// `path` specifies an API endpoint
+// `body` is sent as is: requests with a body read from a file or a pipe are not retried.
+//
+// returns a raw HTTP Response.
+func (c *HTTPClient) PostBody(path string, contentType string, body io.Reader) (*http.Response, error) {
+	url := fmt.Sprintf("%s/%s", c.BaseURL, path)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.sendRequest(req)
+}
+
 // Get issues GET HTTP request to the API target
 //
 // `path` specifies API endpoint together with query options
@@ -267,10 +449,10 @@ func (c *HTTPClient) Get(path string) (*http.Response, error) {
 // Post issues a POST HTTP request to the API target using the client and decodes the response.
 //
 // `path` specifies an API endpoint
-// `data` contains a map of values to add to the POST query. `data` are serialized to the JSON
+// `data` contains a map or a struct of values to add to the POST query. `data` are serialized to the JSON
 //
 // returns a deserialized JSON data to the map.
-func Post[T interface{}](client *HTTPClient, path string, data map[string]interface{}) (*ApiResponse[T], error) {
+func Post[T interface{}](client *HTTPClient, path string, data interface{}) (*ApiResponse[T], error) {
 	res, err := client.Post(path, data)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+// newFlakyServer returns a server replying with the given status codes before succeeding.
+func newFlakyServer(t *testing.T, codes []int) (*test_utils.FakeAPIServer, *ConnectionDetails) {
+	server := test_utils.NewFakeAPIServer(t, "", "")
+	server.HandlePublic("test", func(call *test_utils.APICall) (interface{}, error) {
+		calls := len(server.Calls("test"))
+		if call.Method == http.MethodPost {
+			var data map[string]interface{}
+			if err := call.Decode(&data); err != nil || data["key"] != "value" {
+				t.Errorf("Unexpected body in call %d: %v, %v", calls, data, err)
+			}
+		}
+		if calls <= len(codes) {
+			return nil, &test_utils.APIError{Status: codes[calls-1], Message: http.StatusText(codes[calls-1])}
+		}
+		return 1, nil
+	})
+
+	return server, &ConnectionDetails{
+		Server:   server.Host(),
+		Insecure: true,
+		Retries:  2,
+		Backoff:  time.Millisecond,
+	}
+}
+
+func TestRetries(t *testing.T) {
+	// Make sure no stored session is used
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	type testCase struct {
+		method        string
+		codes         []int
+		expectedCalls int
+		success       bool
+	}
+	data := []testCase{
+		{http.MethodGet, []int{}, 1, true},
+		{http.MethodGet, []int{503, 502}, 3, true},
+		{http.MethodGet, []int{503, 503, 503}, 3, false},
+		{http.MethodGet, []int{500}, 1, false},
+		{http.MethodPost, []int{503}, 2, true},
+		{http.MethodPost, []int{502}, 1, false},
+	}
+
+	for i, test := range data {
+		server, conn := newFlakyServer(t, test.codes)
+		client, err := Init(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.method == http.MethodGet {
+			_, err = Get[int](client, "test")
+		} else {
+			_, err = Post[int](client, "test", map[string]interface{}{"key": "value"})
+		}
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected success", i), test.success, err == nil)
+		test_utils.AssertEquals(t, fmt.Sprintf("case %d: unexpected number of calls", i), test.expectedCalls,
+			len(server.Calls("test")))
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	for _, retries := range []int{0, -1} {
+		server, conn := newFlakyServer(t, []int{503})
+		conn.Retries = retries
+		client, err := Init(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Get[int](client, "test"); err == nil {
+			t.Errorf("Expected an error with %d retries", retries)
+		}
+		test_utils.AssertEquals(t, fmt.Sprintf("unexpected retry with %d retries", retries), 1,
+			len(server.Calls("test")))
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/hanging") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		// Stream the body slower than the timeout
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "chunk%d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	client := newClient(&ConnectionDetails{
+		Server:   strings.TrimPrefix(server.URL, "https://"),
+		Insecure: true,
+		Timeout:  100 * time.Millisecond,
+	})
+
+	res, err := client.Get("download")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Streamed response interrupted: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong streamed body", "chunk0\nchunk1\nchunk2\nchunk3\n", string(body))
+
+	if _, err := client.Get("hanging"); err == nil {
+		t.Error("Expected a timeout error when the server doesn't reply")
+	}
+}
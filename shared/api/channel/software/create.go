@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package software
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Create creates a software channel.
+func Create(client *api.HTTPClient, channel *types.CreateChannelRequest) error {
+	res, err := api.Post[int](client, "channel/software/create", channel)
+	if err != nil {
+		return utils.Errorf(err, L("failed to create the software channel"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package software
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// List returns the software channels visible to the user.
+// The list is provided by the channel.listSoftwareChannels API method.
+func List(client *api.HTTPClient) ([]types.SoftwareChannel, error) {
+	res, err := api.Get[[]types.SoftwareChannel](client, "channel/listSoftwareChannels")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the software channels"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package software
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// SyncRepo triggers the synchronization of the repositories of a software channel.
+func SyncRepo(client *api.HTTPClient, channelLabel string) error {
+	res, err := api.Post[int](client, "channel/software/syncRepo", map[string]interface{}{"channelLabel": channelLabel})
+	if err != nil {
+		return utils.Errorf(err, L("failed to schedule the repositories synchronization"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
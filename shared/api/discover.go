@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/uyuni-project/uyuni-tools/shared"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// serverCaPath is the path of the server CA certificate in the server container.
+const serverCaPath = "/etc/pki/trust/anchors/LOCAL-RHN-ORG-TRUSTED-SSL-CERT"
+
+// DiscoverLocalServer fills the missing server FQDN and CA certificate from the server running on the host.
+//
+// The FQDN is read from the rhn.conf file of the server and its CA certificate is stored in the user cache folder
+// to be reused by the stored sessions. If the server is already set, only the CA certificate is discovered
+// and only if the server is the local one.
+func DiscoverLocalServer(conn *ConnectionDetails, cnx *shared.Connection) error {
+	if conn.Server != "" && (conn.CAcert != "" || conn.Insecure) {
+		return nil
+	}
+
+	fqdn, err := GetServerFqdn(cnx)
+	if err != nil {
+		return err
+	}
+	// The server instances with shifted ports are reached on their HTTPS port
+	if offset := podman.GetPortsOffset(); offset != 0 {
+		fqdn = fmt.Sprintf("%s:%d", fqdn, 443+offset)
+	}
+	if conn.Server == "" {
+		conn.Server = fqdn
+		log.Debug().Msgf("Using API server FQDN %s", fqdn)
+	} else if !isSameServer(conn.Server, fqdn) {
+		log.Debug().Msgf("API server %[1]s is not the local server %[2]s", conn.Server, fqdn)
+		return nil
+	}
+
+	if conn.CAcert != "" || conn.Insecure {
+		return nil
+	}
+	caCert, err := cnx.Exec("cat", serverCaPath)
+	if err != nil {
+		return utils.Errorf(err, L("failed to read the server CA certificate"))
+	}
+	cacheFolder, err := CacheFolder()
+	if err != nil {
+		return err
+	}
+	caPath := path.Join(cacheFolder, fqdn+"-ca.crt")
+	if err := os.MkdirAll(path.Dir(caPath), 0700); err != nil {
+		return utils.Errorf(err, L("failed to create folder %s"), path.Dir(caPath))
+	}
+	if err := os.WriteFile(caPath, caCert, 0600); err != nil {
+		return utils.Errorf(err, L("failed to write %s"), caPath)
+	}
+	conn.CAcert = caPath
+	return nil
+}
+
+// isSameServer returns whether two servers FQDN, with optional ports, designate the same host.
+func isSameServer(server string, other string) bool {
+	host := func(server string) string {
+		if hostname, _, err := net.SplitHostPort(server); err == nil {
+			return hostname
+		}
+		return server
+	}
+	return strings.EqualFold(host(server), host(other))
+}
+
+// GetServerFqdn returns the FQDN of the server configured in its rhn.conf file.
+func GetServerFqdn(cnx *shared.Connection) (string, error) {
+	out, err := cnx.Exec("sh", "-c", "grep '^java.hostname' /etc/rhn/rhn.conf | cut -d= -f2")
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to read the server FQDN from the server configuration"))
+	}
+	fqdn := strings.TrimSpace(string(out))
+	if fqdn == "" {
+		return "", errors.New(L("no server FQDN in the server configuration"))
+	}
+	return fqdn, nil
+}
+
+// HasSession returns whether Init would reuse a stored session for the connection details.
+func HasSession(conn *ConnectionDetails) bool {
+	if conn.Password != "" {
+		return false
+	}
+	session, err := FindSession(conn)
+	return err == nil && session != nil
+}
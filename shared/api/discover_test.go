@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"testing"
+)
+
+func TestIsSameServer(t *testing.T) {
+	data := []struct {
+		server   string
+		other    string
+		expected bool
+	}{
+		{"server.lab", "server.lab", true},
+		{"Server.Lab", "server.lab", true},
+		{"server.lab:8443", "server.lab", true},
+		{"server.lab", "server.lab:8443", true},
+		{"other.lab", "server.lab", false},
+		{"other.lab:443", "server.lab:443", false},
+	}
+
+	for i, testCase := range data {
+		if actual := isSameServer(testCase.server, testCase.other); actual != testCase.expected {
+			t.Errorf("Testcase %d: Expected %v got %v comparing %s and %s",
+				i, testCase.expected, actual, testCase.server, testCase.other)
+		}
+	}
+}
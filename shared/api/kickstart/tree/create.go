@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package tree
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Create registers a kickstartable tree.
+func Create(client *api.HTTPClient, tree *types.CreateKickstartTreeRequest) error {
+	res, err := api.Post[int](client, "kickstart/tree/create", tree)
+	if err != nil {
+		return utils.Errorf(err, L("failed to register the kickstartable tree"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// cacheFolderName is the name of the tools folder in the user cache folder.
+const cacheFolderName = "uyuni-tools"
+
+// sessionsFolderName is the name of the folder containing one session file per server in the cache folder.
+const sessionsFolderName = "api-sessions"
+
+// Session is an API session stored between calls to avoid logging in each time.
+type Session struct {
+	Server   string
+	User     string
+	CAcert   string `json:",omitempty"`
+	Insecure bool
+	Cookie   string
+	Expires  time.Time
+	// Local is true if the server has been discovered from the server running on the host.
+	Local bool `json:",omitempty"`
+	// Instance is the name of the local server instance the server has been discovered from.
+	Instance string `json:",omitempty"`
+}
+
+// CacheFolder returns the path of the tools folder in the current user's cache folder.
+func CacheFolder() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to find the user cache folder"))
+	}
+	return path.Join(cacheDir, cacheFolderName), nil
+}
+
+// SessionPath returns the path of the current user's session file for a server.
+func SessionPath(server string) (string, error) {
+	cacheFolder, err := CacheFolder()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(cacheFolder, sessionsFolderName, url.PathEscape(server)+".json"), nil
+}
+
+// SaveSession stores the authentication cookie of a logged in client and its connection details.
+//
+// local needs to be true if the server has been discovered from the server instance running on the host.
+// The session file is only readable by the current user.
+func SaveSession(client *HTTPClient, conn *ConnectionDetails, local bool) error {
+	if client.AuthCookie == nil {
+		return errors.New(L("not logged in"))
+	}
+	session := Session{
+		Server:   conn.Server,
+		User:     conn.User,
+		CAcert:   conn.CAcert,
+		Insecure: conn.Insecure,
+		Cookie:   client.AuthCookie.Value,
+		Expires:  time.Now().Add(time.Duration(client.AuthCookie.MaxAge) * time.Second),
+		Local:    local,
+	}
+	if local {
+		session.Instance = podman.Instance()
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return utils.Errorf(err, L("failed to serialize the API session"))
+	}
+
+	sessionPath, err := SessionPath(conn.Server)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(sessionPath), 0700); err != nil {
+		return utils.Errorf(err, L("failed to create folder %s"), path.Dir(sessionPath))
+	}
+	// Remove any previous file to make sure the permissions are correct
+	if err := os.Remove(sessionPath); err != nil && !os.IsNotExist(err) {
+		return utils.Errorf(err, L("failed to remove %s"), sessionPath)
+	}
+	if err := os.WriteFile(sessionPath, data, 0600); err != nil {
+		return utils.Errorf(err, L("failed to write %s"), sessionPath)
+	}
+	return nil
+}
+
+// LoadSession reads the stored API session of a server.
+//
+// nil is returned if there is no session or if it expired.
+func LoadSession(server string) (*Session, error) {
+	sessionPath, err := SessionPath(server)
+	if err != nil {
+		return nil, err
+	}
+	return readSession(sessionPath)
+}
+
+func readSession(sessionPath string) (*Session, error) {
+	data, err := os.ReadFile(sessionPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, utils.Errorf(err, L("failed to read %s"), sessionPath)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse %s"), sessionPath)
+	}
+	if time.Now().After(session.Expires) {
+		log.Debug().Msgf("API session for %s expired", session.Server)
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// loadSessions reads all the stored API sessions which are not expired.
+func loadSessions() ([]Session, error) {
+	cacheFolder, err := CacheFolder()
+	if err != nil {
+		return nil, err
+	}
+	sessionsFolder := path.Join(cacheFolder, sessionsFolderName)
+	entries, err := os.ReadDir(sessionsFolder)
+	if os.IsNotExist(err) {
+		return []Session{}, nil
+	} else if err != nil {
+		return nil, utils.Errorf(err, L("failed to read %s"), sessionsFolder)
+	}
+
+	sessions := []Session{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		session, err := readSession(path.Join(sessionsFolder, entry.Name()))
+		if err != nil {
+			log.Warn().Err(err).Msg(L("Ignoring the stored API session"))
+			continue
+		}
+		if session != nil {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+// FindSession returns the stored session to use for the connection details or nil if there is none.
+//
+// If no server is provided, the session of the server discovered from the current local server instance is used.
+// Without such a session, the only stored session is used if no instance is selected.
+func FindSession(conn *ConnectionDetails) (*Session, error) {
+	var session *Session
+	if conn.Server != "" {
+		var err error
+		if session, err = LoadSession(conn.Server); err != nil {
+			return nil, err
+		}
+	} else {
+		sessions, err := loadSessions()
+		if err != nil {
+			return nil, err
+		}
+		if local := localSession(sessions); local != nil {
+			session = local
+		} else if len(sessions) == 1 && podman.Instance() == "" {
+			session = &sessions[0]
+		}
+	}
+	if session == nil || !session.matches(conn) {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// HasLocalSession returns whether a session is stored for the server discovered from the current local
+// server instance.
+//
+// Such a session is used by Init without the need to discover the server.
+func HasLocalSession(conn *ConnectionDetails) bool {
+	if conn.Password != "" || conn.Server != "" {
+		return false
+	}
+	sessions, err := loadSessions()
+	if err != nil {
+		return false
+	}
+	session := localSession(sessions)
+	return session != nil && session.matches(conn)
+}
+
+// localSession returns the session of the server discovered from the current local server instance.
+func localSession(sessions []Session) *Session {
+	for i, session := range sessions {
+		if session.Local && session.Instance == podman.Instance() {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
+
+// RemoveSession deletes the stored API session of a server if any.
+func RemoveSession(server string) error {
+	sessionPath, err := SessionPath(server)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(sessionPath); err != nil && !os.IsNotExist(err) {
+		return utils.Errorf(err, L("failed to remove %s"), sessionPath)
+	}
+	return nil
+}
+
+// matches returns whether the session can be used for the connection details.
+//
+// Empty server or user connection details match any session.
+func (s *Session) matches(conn *ConnectionDetails) bool {
+	return (conn.Server == "" || conn.Server == s.Server) && (conn.User == "" || conn.User == s.User)
+}
+
+func (s *Session) cookie() *http.Cookie {
+	return &http.Cookie{Name: sessionCookieName, Value: s.Cookie, Expires: s.Expires}
+}
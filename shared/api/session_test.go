@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestSession(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	session, err := LoadSession("server.lab")
+	if err != nil || session != nil {
+		t.Fatalf("Expected no session, got %v, %s", session, err)
+	}
+
+	conn := ConnectionDetails{Server: "server.lab", User: "admin", Insecure: true}
+	client := HTTPClient{AuthCookie: &http.Cookie{Name: sessionCookieName, Value: "secret", MaxAge: 3600}}
+	if err := SaveSession(&client, &conn, false); err != nil {
+		t.Fatalf("Failed to save session: %s", err)
+	}
+
+	sessionPath, err := SessionPath("server.lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_utils.AssertEquals(t, "Wrong session file permissions", os.FileMode(0600), info.Mode().Perm())
+
+	// Reuse the session without password
+	reusedConn := ConnectionDetails{}
+	reused, err := Init(&reusedConn)
+	if err != nil {
+		t.Fatalf("Failed to reuse session: %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong server", "server.lab", reusedConn.Server)
+	test_utils.AssertEquals(t, "Wrong user", "admin", reusedConn.User)
+	test_utils.AssertTrue(t, "Insecure flag not restored", reusedConn.Insecure)
+	test_utils.AssertEquals(t, "Wrong cookie", "secret", reused.AuthCookie.Value)
+
+	session, err = LoadSession("server.lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test_utils.AssertTrue(t, "Session should match the same server", session.matches(&ConnectionDetails{Server: "server.lab"}))
+	test_utils.AssertTrue(t, "Session should not match another server", !session.matches(&ConnectionDetails{Server: "other.lab"}))
+	test_utils.AssertTrue(t, "Session should not match another user", !session.matches(&ConnectionDetails{User: "other"}))
+
+	if err := RemoveSession("server.lab"); err != nil {
+		t.Fatalf("Failed to remove session: %s", err)
+	}
+	if _, err := Init(&ConnectionDetails{}); err == nil {
+		t.Error("Expected an error without server and session")
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	conn := ConnectionDetails{Server: "server.lab", User: "admin"}
+	client := HTTPClient{AuthCookie: &http.Cookie{Name: sessionCookieName, Value: "secret", MaxAge: -1}}
+	if err := SaveSession(&client, &conn, false); err != nil {
+		t.Fatalf("Failed to save session: %s", err)
+	}
+
+	session, err := LoadSession("server.lab")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "Expired session should not be loaded", session == nil)
+}
+
+func TestFindSession(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	client := HTTPClient{AuthCookie: &http.Cookie{Name: sessionCookieName, Value: "secret", MaxAge: 3600}}
+	if err := SaveSession(&client, &ConnectionDetails{Server: "remote.lab", User: "admin"}, false); err != nil {
+		t.Fatalf("Failed to save session: %s", err)
+	}
+
+	// The only session is used without server and instance
+	session, err := FindSession(&ConnectionDetails{})
+	if err != nil || session == nil {
+		t.Fatalf("Expected a session, got %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong session", "remote.lab", session.Server)
+
+	restoreInstance, err := podman.SetInstance("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restoreInstance()
+
+	// Another instance never uses the session of another server
+	session, err = FindSession(&ConnectionDetails{})
+	if err != nil || session != nil {
+		t.Fatalf("Expected no session for the staging instance, got %v, %s", session, err)
+	}
+	test_utils.AssertTrue(t, "Unexpected local session", !HasLocalSession(&ConnectionDetails{}))
+
+	if err := SaveSession(&client, &ConnectionDetails{Server: "staging.lab:8443", User: "admin"}, true); err != nil {
+		t.Fatalf("Failed to save session: %s", err)
+	}
+	test_utils.AssertTrue(t, "Local session not found", HasLocalSession(&ConnectionDetails{}))
+	session, err = FindSession(&ConnectionDetails{})
+	if err != nil || session == nil {
+		t.Fatalf("Expected a session, got %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong instance session", "staging.lab:8443", session.Server)
+
+	// The sessions are still found by their server
+	session, err = FindSession(&ConnectionDetails{Server: "remote.lab"})
+	if err != nil || session == nil {
+		t.Fatalf("Expected a session, got %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong server session", "remote.lab", session.Server)
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// DeleteSystem deletes a system.
+// cleanupType is one of FAIL_ON_CLEANUP_ERR, NO_CLEANUP or FORCE_DELETE.
+func DeleteSystem(client *api.HTTPClient, sid int, cleanupType string) error {
+	res, err := api.Post[int](client, "system/deleteSystem", types.DeleteSystemRequest{Sid: sid, CleanupType: cleanupType})
+	if err != nil {
+		return utils.Errorf(err, L("failed to delete the system"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
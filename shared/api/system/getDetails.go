@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// GetDetails returns the details of the system with the sid ID.
+func GetDetails(client *api.HTTPClient, sid int) (*types.SystemDetails, error) {
+	res, err := api.Get[types.SystemDetails](client, fmt.Sprintf("system/getDetails?sid=%d", sid))
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to get the system details"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return &res.Result, nil
+}
@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ListSystems returns the systems visible to the user.
+func ListSystems(client *api.HTTPClient) ([]types.SystemOverview, error) {
+	res, err := api.Get[[]types.SystemOverview](client, "system/listSystems")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the systems"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// RegisterPeripheralServer registers a peripheral server to a hub and returns its system ID.
+func RegisterPeripheralServer(client *api.HTTPClient, fqdn string) (int, error) {
+	res, err := api.Post[int](client, "system/registerPeripheralServer", map[string]interface{}{"fqdn": fqdn})
+	if err != nil {
+		return 0, utils.Errorf(err, L("failed to register the peripheral server"))
+	}
+	if !res.Success {
+		return 0, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
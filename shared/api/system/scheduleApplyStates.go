@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ScheduleApplyStates schedules the application of states on systems and returns the action ID.
+// An empty list of state names applies the highstate.
+func ScheduleApplyStates(client *api.HTTPClient, request *types.ScheduleApplyStatesRequest) (int, error) {
+	res, err := api.Post[int](client, "system/scheduleApplyStates", request)
+	if err != nil {
+		return 0, utils.Errorf(err, L("failed to schedule the states application"))
+	}
+	if !res.Success {
+		return 0, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func newServer(t *testing.T) (*test_utils.FakeAPIServer, *api.HTTPClient) {
+	// Make sure no stored session is used
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := test_utils.NewFakeAPIServer(t, "admin", "secret")
+	cnx := api.ConnectionDetails{
+		Server:   server.Host(),
+		User:     "admin",
+		Password: "secret",
+		Insecure: true,
+	}
+	client, err := api.Init(&cnx)
+	if err != nil {
+		t.Fatalf("Unexpected login error: %s", err)
+	}
+	return server, client
+}
+
+func TestListSystems(t *testing.T) {
+	server, client := newServer(t)
+	server.HandleResult("system/listSystems", []map[string]interface{}{
+		{"id": 1000010000, "name": "minion1", "last_checkin": "2024-05-02T10:00:00Z", "outdated_pkg_count": 3},
+	})
+
+	systems, err := ListSystems(client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of systems", 1, len(systems))
+	test_utils.AssertEquals(t, "wrong system id", 1000010000, systems[0].Id)
+	test_utils.AssertEquals(t, "wrong system name", "minion1", systems[0].Name)
+	test_utils.AssertEquals(t, "wrong outdated packages count", 3, systems[0].OutdatedPkgCount)
+}
+
+func TestScheduleApplyStates(t *testing.T) {
+	server, client := newServer(t)
+	server.Handle("system/scheduleApplyStates", func(call *test_utils.APICall) (interface{}, error) {
+		var data map[string]interface{}
+		if err := call.Decode(&data); err != nil {
+			t.Errorf("Failed to decode payload: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong earliest occurrence", "2024-05-02T10:00:00Z", data["earliestOccurrence"].(string))
+		test_utils.AssertEquals(t, "wrong number of sids", 2, len(data["sids"].([]interface{})))
+		test_utils.AssertEquals(t, "wrong test flag", true, data["test"].(bool))
+		return 42, nil
+	})
+
+	request := types.ScheduleApplyStatesRequest{
+		Sids:               []int{1000010000, 1000010001},
+		StateNames:         []string{"certs"},
+		EarliestOccurrence: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+		Test:               true,
+	}
+	actionId, err := ScheduleApplyStates(client, &request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong action id", 42, actionId)
+}
+
+func TestDeleteSystemFailure(t *testing.T) {
+	server, client := newServer(t)
+	server.Handle("system/deleteSystem", func(*test_utils.APICall) (interface{}, error) {
+		return nil, errors.New("No such system")
+	})
+
+	err := DeleteSystem(client, 1, "NO_CLEANUP")
+	if err == nil || err.Error() != "No such system" {
+		t.Errorf("Expected API error message, got: %v", err)
+	}
+}
+
+func TestSingleLogin(t *testing.T) {
+	server, client := newServer(t)
+	server.HandleResult("system/listSystems", []interface{}{})
+	server.HandleResult("system/registerPeripheralServer", 1000010000)
+
+	if _, err := ListSystems(client); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := RegisterPeripheralServer(client, "peripheral.lab"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "the calls should share the login", 1, len(server.Calls("auth/login")))
+}
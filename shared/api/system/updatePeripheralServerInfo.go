@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package system
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// UpdatePeripheralServerInfo updates the reporting database details of a peripheral server registered to a hub.
+func UpdatePeripheralServerInfo(client *api.HTTPClient, info *types.PeripheralServerInfoRequest) error {
+	res, err := api.Post[int](client, "system/updatePeripheralServerInfo", info)
+	if err != nil {
+		return utils.Errorf(err, L("failed to update peripheral server info"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
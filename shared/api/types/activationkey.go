@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// ActivationKey describes an activation key in the API.
+type ActivationKey struct {
+	Key                string   `json:"key"`
+	Description        string   `json:"description"`
+	UsageLimit         int      `json:"usage_limit"`
+	BaseChannelLabel   string   `json:"base_channel_label"`
+	ChildChannelLabels []string `json:"child_channel_labels"`
+	Entitlements       []string `json:"entitlements"`
+	ServerGroupIds     []int    `json:"server_group_ids"`
+	PackageNames       []string `json:"package_names"`
+	UniversalDefault   bool     `json:"universal_default"`
+	Disabled           bool     `json:"disabled"`
+	ContactMethod      string   `json:"contact_method"`
+}
+
+// CreateActivationKeyRequest is the payload to create an activation key.
+type CreateActivationKeyRequest struct {
+	// Key is the key to create, a random one is generated if empty.
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	// BaseChannelLabel is the base channel of the key, the default one is used if empty.
+	BaseChannelLabel string   `json:"baseChannelLabel"`
+	Entitlements     []string `json:"entitlements"`
+	UniversalDefault bool     `json:"universalDefault"`
+}
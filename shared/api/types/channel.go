@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// SoftwareChannel describes a software channel as listed by the API.
+type SoftwareChannel struct {
+	Label       string `json:"label"`
+	Name        string `json:"name"`
+	ParentLabel string `json:"parent_label"`
+	EndOfLife   string `json:"end_of_life"`
+	Arch        string `json:"arch"`
+}
+
+// CreateChannelRequest is the payload to create a software channel.
+type CreateChannelRequest struct {
+	Label   string `json:"label"`
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+	// ArchLabel is the architecture of the channel like channel-x86_64.
+	ArchLabel string `json:"archLabel"`
+	// ParentLabel is the label of the parent channel, empty for a base channel.
+	ParentLabel string `json:"parentLabel"`
+	// ChecksumType is the checksum used by the channel like sha256, optional.
+	ChecksumType string `json:"checksumType,omitempty"`
+}
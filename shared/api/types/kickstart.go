@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// CreateKickstartTreeRequest is the payload to register a kickstartable tree.
+type CreateKickstartTreeRequest struct {
+	TreeLabel    string `json:"treeLabel"`
+	BasePath     string `json:"basePath"`
+	ChannelLabel string `json:"channelLabel"`
+	InstallType  string `json:"installType"`
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "time"
+
+// SystemOverview describes a system as listed by the API.
+type SystemOverview struct {
+	Id               int    `json:"id"`
+	Name             string `json:"name"`
+	LastCheckin      string `json:"last_checkin"`
+	Created          string `json:"created"`
+	LastBoot         string `json:"last_boot"`
+	ExtraPkgCount    int    `json:"extra_pkg_count"`
+	OutdatedPkgCount int    `json:"outdated_pkg_count"`
+}
+
+// SystemDetails describes the details of a system in the API.
+type SystemDetails struct {
+	Id                int      `json:"id"`
+	ProfileName       string   `json:"profile_name"`
+	MachineId         string   `json:"machine_id"`
+	MinionId          string   `json:"minion_id"`
+	BaseEntitlement   string   `json:"base_entitlement"`
+	AddonEntitlements []string `json:"addon_entitlements"`
+	AutoUpdate        bool     `json:"auto_update"`
+	Release           string   `json:"release"`
+	Description       string   `json:"description"`
+	Hostname          string   `json:"hostname"`
+	LastBoot          string   `json:"last_boot"`
+	LockStatus        bool     `json:"lock_status"`
+	Virtualization    string   `json:"virtualization"`
+	ContactMethod     string   `json:"contact_method"`
+	OsaStatus         string   `json:"osa_status,omitempty"`
+	Address1          string   `json:"address1,omitempty"`
+	Address2          string   `json:"address2,omitempty"`
+	City              string   `json:"city,omitempty"`
+	State             string   `json:"state,omitempty"`
+	Country           string   `json:"country,omitempty"`
+	Building          string   `json:"building,omitempty"`
+	Room              string   `json:"room,omitempty"`
+	Rack              string   `json:"rack,omitempty"`
+}
+
+// DeleteSystemRequest is the payload to delete a system.
+type DeleteSystemRequest struct {
+	Sid int `json:"sid"`
+	// CleanupType is one of FAIL_ON_CLEANUP_ERR, NO_CLEANUP or FORCE_DELETE.
+	CleanupType string `json:"cleanupType"`
+}
+
+// ScheduleApplyStatesRequest is the payload to schedule the application of states on systems.
+type ScheduleApplyStatesRequest struct {
+	Sids               []int     `json:"sids"`
+	StateNames         []string  `json:"stateNames"`
+	EarliestOccurrence time.Time `json:"earliestOccurrence"`
+	Test               bool      `json:"test"`
+}
+
+// PeripheralServerInfoRequest is the payload to update the reporting database details of a peripheral server.
+type PeripheralServerInfoRequest struct {
+	Sid              int    `json:"sid"`
+	ReportDbName     string `json:"reportDbName"`
+	ReportDbHost     string `json:"reportDbHost"`
+	ReportDbPort     string `json:"reportDbPort"`
+	ReportDbUser     string `json:"reportDbUser"`
+	ReportDbPassword string `json:"reportDbPassword"`
+}
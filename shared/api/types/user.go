@@ -12,3 +12,37 @@ type User struct {
 	LastName  string
 	Email     string
 }
+
+// UserOverview describes a user as listed by the API.
+type UserOverview struct {
+	Id      int    `json:"id"`
+	Login   string `json:"login"`
+	LoginUc string `json:"login_uc"`
+	Enabled bool   `json:"enabled"`
+}
+
+// UserDetails describes the details of a user in the API.
+type UserDetails struct {
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Email         string `json:"email"`
+	OrgId         int    `json:"org_id"`
+	OrgName       string `json:"org_name"`
+	Prefix        string `json:"prefix"`
+	LastLoginDate string `json:"last_login_date"`
+	CreatedDate   string `json:"created_date"`
+	Enabled       bool   `json:"enabled"`
+	UsePam        bool   `json:"use_pam"`
+	ReadOnly      bool   `json:"read_only"`
+}
+
+// CreateUserRequest is the payload to create a user.
+type CreateUserRequest struct {
+	Login     string `json:"login"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	// UsePamAuth is 1 to authenticate the user with PAM, 0 otherwise.
+	UsePamAuth int `json:"usePamAuth"`
+}
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// AddRole adds a role to a user.
+func AddRole(client *api.HTTPClient, login string, role string) error {
+	res, err := api.Post[int](client, "user/addRole", map[string]interface{}{"login": login, "role": role})
+	if err != nil {
+		return utils.Errorf(err, L("failed to add the role to the user"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
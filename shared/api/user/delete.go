@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Delete deletes a user.
+func Delete(client *api.HTTPClient, login string) error {
+	res, err := api.Post[int](client, "user/delete", map[string]interface{}{"login": login})
+	if err != nil {
+		return utils.Errorf(err, L("failed to delete the user"))
+	}
+	if !res.Success {
+		return errors.New(res.Message)
+	}
+
+	return nil
+}
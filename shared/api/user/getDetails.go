@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// GetDetails returns the details of a user.
+func GetDetails(client *api.HTTPClient, login string) (*types.UserDetails, error) {
+	res, err := api.Get[types.UserDetails](client, "user/getDetails?login="+url.QueryEscape(login))
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to get the user details"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return &res.Result, nil
+}
@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ListRoles returns the roles of a user.
+func ListRoles(client *api.HTTPClient, login string) ([]string, error) {
+	res, err := api.Get[[]string](client, "user/listRoles?login="+url.QueryEscape(login))
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the user roles"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
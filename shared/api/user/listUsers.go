@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package user
+
+import (
+	"errors"
+
+	"github.com/uyuni-project/uyuni-tools/shared/api"
+	"github.com/uyuni-project/uyuni-tools/shared/api/types"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// ListUsers returns the users of the organization.
+func ListUsers(client *api.HTTPClient) ([]types.UserOverview, error) {
+	res, err := api.Get[[]types.UserOverview](client, "user/listUsers")
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the users"))
+	}
+	if !res.Success {
+		return nil, errors.New(res.Message)
+	}
+
+	return res.Result, nil
+}
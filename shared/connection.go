@@ -6,11 +6,10 @@ package shared
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -18,10 +17,12 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/uyuni-project/uyuni-tools/shared/kubernetes"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
@@ -30,25 +31,63 @@ type Connection struct {
 	backend          string
 	command          string
 	podName          string
+	pod              *kubernetes.Pod
 	kubernetesFilter string
 	namespace        string
 	container        string
+	instance         string
+	exec             utils.Executor
+	remote           bool
+	err              error
 }
 
 // Create a new connection object.
 // The backend is either the command to use to connect to the container or the empty string.
 //
 // The empty strings means automatic detection of the backend where the uyuni container is running.
+// A ssh://[user@]host[:port] backend runs the commands on a remote host where the backend is detected.
 // container is the name of a container to look for when detecting the command.
 // kubernetesFilter is a filter parameter to use to match a pod.
+//
+// The connection targets the current server instance: named instances only run on podman.
 func NewConnection(backend string, container string, kubernetesFilter string) *Connection {
-	cnx := Connection{backend: backend, container: container, kubernetesFilter: kubernetesFilter}
+	cnx := Connection{
+		backend:          backend,
+		container:        container,
+		kubernetesFilter: kubernetesFilter,
+		instance:         podman.Instance(),
+	}
+	if utils.IsSSHBackend(backend) {
+		cnx.backend = ""
+		cnx.remote = true
+		executor, err := utils.NewSSHExecutor(backend)
+		if err != nil {
+			cnx.err = err
+		} else {
+			cnx.exec = executor
+		}
+	}
 
 	return &cnx
 }
 
+// SetExecutor sets the executor running the commands of the connection instead of the current one.
+func (c *Connection) SetExecutor(executor utils.Executor) {
+	c.exec = executor
+}
+
+func (c *Connection) executor() utils.Executor {
+	if c.exec != nil {
+		return c.exec
+	}
+	return utils.CurrentExecutor()
+}
+
 // GetCommand validates or guesses the connection backend command.
 func (c *Connection) GetCommand() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
 	var err error
 	if c.command == "" {
 		switch c.backend {
@@ -57,32 +96,49 @@ func (c *Connection) GetCommand() (string, error) {
 		case "podman-remote":
 			fallthrough
 		case "kubectl":
-			if _, err = exec.LookPath(c.backend); err != nil {
-				err = fmt.Errorf(L("backend command not found in PATH: %s"), c.backend)
+			if c.backend == "kubectl" && c.instance != "" {
+				return c.command, fmt.Errorf(L("server instance %s cannot be used with kubectl"), c.instance)
+			}
+			// kubectl is not needed when the Kubernetes API server can be used
+			if c.backend != "kubectl" || !c.hasKubernetesAPI() {
+				if _, err = c.executor().LookPath(c.backend); err != nil {
+					err = fmt.Errorf(L("backend command not found in PATH: %s"), c.backend)
+				}
 			}
 			c.command = c.backend
 		case "":
 			hasPodman := false
 			hasKubectl := false
 
-			// Check kubectl with a timeout in case the configured cluster is not responding
-			_, err = exec.LookPath("kubectl")
-			if err == nil {
+			// Named server instances are only running on podman
+			if c.instance == "" && c.hasKubernetesAPI() {
 				hasKubectl = true
-				if out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "--request-timeout=30s", "get", "pod", c.kubernetesFilter, "-A", "-o=jsonpath={.items[*].metadata.name}"); err != nil {
-					log.Info().Msg(L("kubectl not configured to connect to a cluster, ignoring"))
-				} else if len(bytes.TrimSpace(out)) != 0 {
+				if pods, err := kubernetes.FindPods(c.kubernetesFilter); err != nil {
+					log.Info().Err(err).Msg(L("failed to look for the pods on the Kubernetes cluster, ignoring"))
+				} else if len(pods) != 0 {
 					c.command = "kubectl"
-					return c.command, err
+					return c.command, nil
+				}
+			} else if c.instance == "" {
+				// Check kubectl with a timeout in case the configured cluster is not responding
+				_, err = c.executor().LookPath("kubectl")
+				if err == nil {
+					hasKubectl = true
+					if out, err := c.executor().RunOutput(zerolog.DebugLevel, "kubectl", "--request-timeout=30s", "get", "pod", c.kubernetesFilter, "-A", "-o=jsonpath={.items[*].metadata.name}"); err != nil {
+						log.Info().Msg(L("kubectl not configured to connect to a cluster, ignoring"))
+					} else if len(bytes.TrimSpace(out)) != 0 {
+						c.command = "kubectl"
+						return c.command, err
+					}
 				}
 			}
 
 			// Search for other backends
 			bins := []string{"podman", "podman-remote"}
 			for _, bin := range bins {
-				if _, err = exec.LookPath(bin); err == nil {
+				if _, err = c.executor().LookPath(bin); err == nil {
 					hasPodman = true
-					if checkErr := utils.RunCmd(bin, "inspect", c.container, "--format", "{{.Name}}"); checkErr == nil {
+					if checkErr := c.executor().Run(bin, "inspect", c.container, "--format", "{{.Name}}"); checkErr == nil {
 						c.command = bin
 						break
 					}
@@ -159,7 +215,7 @@ func (c *Connection) GetNamespace(appName string, filters ...string) (string, er
 	}
 
 	var namespaceErr error
-	c.namespace, namespaceErr = extractNamespaceFromConfig(appName, kubeconfig)
+	c.namespace, namespaceErr = kubernetes.GetReleaseNamespace(appName, kubeconfig)
 	if namespaceErr != nil {
 		return "", utils.Errorf(namespaceErr, L("failed to find the %s deployment namespace"), appName)
 	}
@@ -181,15 +237,25 @@ func (c *Connection) GetPodName() (string, error) {
 		case "podman-remote":
 			fallthrough
 		case "podman":
-			if out, _ := utils.RunCmdOutput(zerolog.DebugLevel, c.command, "ps", "-q", "-f", "name="+c.container); len(out) == 0 {
+			if out, _ := c.executor().RunOutput(zerolog.DebugLevel, c.command, "ps", "-q", "-f", "name="+c.container); len(out) == 0 {
 				err = fmt.Errorf(L("container %s is not running on podman"), c.container)
 			} else {
 				log.Trace().Msgf("Found container ID '%s'", out)
 				c.podName = c.container
 			}
 		case "kubectl":
+			if c.hasKubernetesAPI() {
+				c.pod, err = kubernetes.FindRunningPod(c.kubernetesFilter)
+				if err == nil {
+					c.podName = c.pod.Name
+					if c.namespace == "" {
+						c.namespace = c.pod.Namespace
+					}
+				}
+				break
+			}
 			// We try the first item on purpose to make the command fail if not available
-			if podName, _ := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "pod", c.kubernetesFilter, "-A",
+			if podName, _ := c.executor().RunOutput(zerolog.DebugLevel, "kubectl", "get", "pod", c.kubernetesFilter, "-A",
 				"-o=jsonpath={.items[0].metadata.name}"); len(podName) == 0 {
 				err = fmt.Errorf(L("container labeled %s is not running on kubectl"), c.kubernetesFilter)
 			} else {
@@ -201,24 +267,140 @@ func (c *Connection) GetPodName() (string, error) {
 	return c.podName, err
 }
 
+// hasKubernetesAPI returns whether the Kubernetes API server can be used instead of kubectl.
+//
+// The API server is not used for the connections running their commands with another executor.
+func (c *Connection) hasKubernetesAPI() bool {
+	return c.exec == nil && kubernetes.HasAPIClient()
+}
+
+// apiPod returns the pod to reach through the Kubernetes API server or nil if the command line tools are used.
+func (c *Connection) apiPod() *kubernetes.Pod {
+	if _, err := c.GetPodName(); err != nil {
+		return nil
+	}
+	return c.pod
+}
+
+// execAPI runs a command in the container through the Kubernetes API server.
+func (c *Connection) execAPI(pod *kubernetes.Pod, stdin io.Reader, stdout io.Writer, command ...string) error {
+	if c.container == "" {
+		c.container = "uyuni"
+	}
+	var stderr bytes.Buffer
+	if err := kubernetes.ExecInPod(pod, c.container, stdin, stdout, &stderr, command...); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return utils.Errorf(err, L("%[1]s failed in pod %[2]s: %[3]s"), command[0], pod.Name, message)
+		}
+		return utils.Errorf(err, L("%[1]s failed in pod %[2]s"), command[0], pod.Name)
+	}
+	return nil
+}
+
+// hasPodmanAPI returns whether the podman API can be used instead of the podman command.
+//
+// The API is not used for the connections running their commands with another executor.
+func (c *Connection) hasPodmanAPI() bool {
+	return c.exec == nil && podman.HasLibpod()
+}
+
+// libpodContainer returns the container to reach through the podman API
+// or an empty string if the command line tools are used.
+func (c *Connection) libpodContainer() string {
+	if !c.hasPodmanAPI() {
+		return ""
+	}
+	if podName, err := c.GetPodName(); err == nil && c.command == "podman" {
+		return podName
+	}
+	return ""
+}
+
+// execLibpod runs a command in the container through the podman API.
+func (c *Connection) execLibpod(container string, stdin io.Reader, stdout io.Writer, command ...string) error {
+	var stderr bytes.Buffer
+	if err := podman.ExecInContainer(container, stdin, stdout, &stderr, command...); err != nil {
+		if podman.IsLibpodFailure(err) {
+			return err
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return utils.Errorf(err, L("%[1]s failed in container %[2]s: %[3]s"), command[0], container, message)
+		}
+		return utils.Errorf(err, L("%[1]s failed in container %[2]s"), command[0], container)
+	}
+	return nil
+}
+
 // Exec runs command inside the container within an sh shell.
 func (c *Connection) Exec(command string, args ...string) ([]byte, error) {
+	if pod := c.apiPod(); pod != nil {
+		var stdout bytes.Buffer
+		err := c.execAPI(pod, nil, &stdout, append([]string{command}, args...)...)
+		return stdout.Bytes(), err
+	}
+	if container := c.libpodContainer(); container != "" {
+		var stdout bytes.Buffer
+		err := c.execLibpod(container, nil, &stdout, append([]string{command}, args...)...)
+		if !podman.IsLibpodFailure(err) {
+			return stdout.Bytes(), err
+		}
+		log.Warn().Err(err).Msgf(L("failed to run %s with the podman API, using the podman command"), command)
+	}
+
+	cmd, cmdArgs, err := c.getExecArgs(false, command, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.executor().RunOutput(zerolog.DebugLevel, cmd, cmdArgs...)
+}
+
+// ExecStream runs command inside the container, reading its input from stdin and writing its output to stdout.
+//
+// The data are streamed between the host and the container and never stored in the container.
+// stdin can be nil if the command doesn't need any input.
+func (c *Connection) ExecStream(stdin io.Reader, stdout io.Writer, command string, args ...string) error {
+	if pod := c.apiPod(); pod != nil {
+		return c.execAPI(pod, stdin, stdout, append([]string{command}, args...)...)
+	}
+	if container := c.libpodContainer(); container != "" {
+		err := c.execLibpod(container, stdin, stdout, append([]string{command}, args...)...)
+		if !podman.IsLibpodFailure(err) {
+			return err
+		}
+		log.Warn().Err(err).Msgf(L("failed to run %s with the podman API, using the podman command"), command)
+	}
+
+	cmd, cmdArgs, err := c.getExecArgs(stdin != nil, command, args...)
+	if err != nil {
+		return err
+	}
+
+	return c.executor().RunStream(stdin, stdout, cmd, cmdArgs...)
+}
+
+// getExecArgs computes the command and arguments to run a command in the container.
+func (c *Connection) getExecArgs(interactive bool, command string, args ...string) (string, []string, error) {
 	if c.podName == "" {
 		if _, err := c.GetPodName(); c.podName == "" {
 			commandStr := fmt.Sprintf("%s %s", command, strings.Join(args, " "))
-			return nil, utils.Errorf(err, L("%s command not executed:"), commandStr)
+			return "", nil, utils.Errorf(err, L("%s command not executed:"), commandStr)
 		}
 	}
 
 	cmd, cmdErr := c.GetCommand()
 	if cmdErr != nil {
-		return nil, cmdErr
+		return "", nil, cmdErr
 	}
 
-	cmdArgs := []string{"exec", c.podName}
+	cmdArgs := []string{"exec"}
+	if interactive {
+		cmdArgs = append(cmdArgs, "-i")
+	}
+	cmdArgs = append(cmdArgs, c.podName)
 	if cmd == "kubectl" {
 		if _, err := c.GetNamespace(""); c.namespace == "" {
-			return nil, utils.Errorf(err, L("failed to retrieve namespace "))
+			return "", nil, utils.Errorf(err, L("failed to retrieve namespace "))
 		}
 
 		if c.container == "" {
@@ -230,7 +412,7 @@ func (c *Connection) Exec(command string, args ...string) ([]byte, error) {
 	shellArgs := append([]string{command}, args...)
 	cmdArgs = append(cmdArgs, shellArgs...)
 
-	return utils.RunCmdOutput(zerolog.DebugLevel, cmd, cmdArgs...)
+	return cmd, cmdArgs, nil
 }
 
 // WaitForContainer waits up to 10 sec for the container to appear.
@@ -242,6 +424,14 @@ func (c *Connection) WaitForContainer() error {
 			time.Sleep(1 * time.Second)
 			continue
 		}
+		if c.pod != nil || c.libpodContainer() != "" {
+			if _, err := c.Exec("true"); err == nil {
+				return nil
+			}
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
 		args := []string{"exec", podName}
 		command, err := c.GetCommand()
 		if err != nil {
@@ -252,7 +442,7 @@ func (c *Connection) WaitForContainer() error {
 			args = append(args, "--")
 		}
 		args = append(args, "true")
-		err = utils.RunCmd(command, args...)
+		err = c.executor().Run(command, args...)
 		if err == nil {
 			return nil
 		}
@@ -272,18 +462,24 @@ func (c *Connection) WaitForServer() error {
 			continue
 		}
 
-		args := []string{"exec", podName}
-		command, err := c.GetCommand()
-		if err != nil {
-			return err
-		}
+		var isActive bool
+		if c.pod != nil || c.libpodContainer() != "" {
+			_, err := c.Exec("systemctl", "is-active", "-q", "multi-user.target")
+			isActive = err == nil
+		} else {
+			args := []string{"exec", podName}
+			command, err := c.GetCommand()
+			if err != nil {
+				return err
+			}
 
-		if command == "kubectl" {
-			args = append(args, "--")
+			if command == "kubectl" {
+				args = append(args, "--")
+			}
+			args = append(args, "systemctl", "is-active", "-q", "multi-user.target")
+			output := c.executor().Run(command, args...)
+			isActive = output == nil
 		}
-		args = append(args, "systemctl", "is-active", "-q", "multi-user.target")
-		output := utils.RunCmd(command, args...)
-		isActive := output == nil
 
 		if isActive {
 			return nil
@@ -293,10 +489,30 @@ func (c *Connection) WaitForServer() error {
 	return errors.New(L("server didn't start within 60s. Check for the service status"))
 }
 
+// HostCommand returns the command line to run a command on the host of the container.
+//
+// For remote hosts the command is wrapped in an ssh one and tty requests a terminal.
+func (c *Connection) HostCommand(tty bool, command string, args ...string) (string, []string) {
+	if wrapper, isWrapper := c.executor().(utils.CommandWrapper); isWrapper {
+		return wrapper.WrapCommand(tty, command, args...)
+	}
+	return command, args
+}
+
 // Copy transfers a file to or from the container.
 // Prefix one of src or dst parameters with `server:` to designate the path is in the container
 // user and group parameters are used to set the owner of a file transferred in the container.
 func (c *Connection) Copy(src string, dst string, user string, group string) error {
+	if c.remote || c.apiPod() != nil {
+		return c.copyStream(src, dst, user, group)
+	}
+	if container := c.libpodContainer(); container != "" {
+		err := c.copyLibpod(container, src, dst, user, group)
+		if !podman.IsLibpodFailure(err) {
+			return err
+		}
+		log.Warn().Err(err).Msgf(L("failed to copy %[1]s to %[2]s with the podman API, using the podman command"), src, dst)
+	}
 	podName, err := c.GetPodName()
 	if err != nil {
 		return err
@@ -323,7 +539,7 @@ func (c *Connection) Copy(src string, dst string, user string, group string) err
 		return fmt.Errorf(L("unknown container kind: %s"), command)
 	}
 
-	if err := utils.RunCmdStdMapping(zerolog.DebugLevel, command, commandArgs...); err != nil {
+	if err := c.executor().RunStdMapping(zerolog.DebugLevel, command, commandArgs...); err != nil {
 		return err
 	}
 
@@ -335,13 +551,90 @@ func (c *Connection) Copy(src string, dst string, user string, group string) err
 			owner = user + ":" + group
 		}
 		execArgs = append(execArgs, "chown", owner, strings.Replace(dst, "server:", "", 1))
-		return utils.RunCmdStdMapping(zerolog.DebugLevel, command, execArgs...)
+		return c.executor().RunStdMapping(zerolog.DebugLevel, command, execArgs...)
+	}
+	return nil
+}
+
+// copyStream transfers a file between the local host and the container by streaming its content.
+//
+// This is used for the containers on a remote host, as the container tools only see the files of the remote host,
+// and for the pods reached through the Kubernetes API server.
+func (c *Connection) copyStream(src string, dst string, user string, group string) error {
+	switch {
+	case strings.HasPrefix(dst, "server:"):
+		target := strings.TrimPrefix(dst, "server:")
+		file, err := os.Open(src)
+		if err != nil {
+			return utils.Errorf(err, L("failed to open %s"), src)
+		}
+		defer file.Close()
+		if info, err := file.Stat(); err != nil || info.IsDir() {
+			return fmt.Errorf(L("only files can be copied to the container: %s"), src)
+		}
+		if err := c.ExecStream(file, nil, "sh", "-c", "cat > "+utils.ShellQuote(target)); err != nil {
+			return utils.Errorf(err, L("failed to copy %[1]s to %[2]s"), src, dst)
+		}
+		return c.chown(target, user, group)
+	case strings.HasPrefix(src, "server:"):
+		file, err := os.Create(dst)
+		if err != nil {
+			return utils.Errorf(err, L("failed to create %s"), dst)
+		}
+		defer file.Close()
+		if err := c.ExecStream(nil, file, "cat", strings.TrimPrefix(src, "server:")); err != nil {
+			return utils.Errorf(err, L("failed to copy %[1]s to %[2]s"), src, dst)
+		}
+		return nil
+	}
+	return fmt.Errorf(L("one of %[1]s or %[2]s has to be prefixed with server:"), src, dst)
+}
+
+// copyLibpod transfers a file between the local host and the container through the podman API.
+func (c *Connection) copyLibpod(container string, src string, dst string, user string, group string) error {
+	switch {
+	case strings.HasPrefix(dst, "server:"):
+		target := strings.TrimPrefix(dst, "server:")
+		if err := podman.CopyToContainer(container, src, target); err != nil {
+			if podman.IsLibpodFailure(err) {
+				return err
+			}
+			return utils.Errorf(err, L("failed to copy %[1]s to %[2]s"), src, dst)
+		}
+		return c.chown(target, user, group)
+	case strings.HasPrefix(src, "server:"):
+		if err := podman.CopyFromContainer(container, strings.TrimPrefix(src, "server:"), dst); err != nil {
+			if podman.IsLibpodFailure(err) {
+				return err
+			}
+			return utils.Errorf(err, L("failed to copy %[1]s to %[2]s"), src, dst)
+		}
+		return nil
+	}
+	return fmt.Errorf(L("one of %[1]s or %[2]s has to be prefixed with server:"), src, dst)
+}
+
+// chown changes the owner of a file in the container, nothing is done if user is empty.
+func (c *Connection) chown(target string, user string, group string) error {
+	if user == "" {
+		return nil
+	}
+	owner := user
+	if group != "" {
+		owner = user + ":" + group
+	}
+	if _, err := c.Exec("chown", owner, target); err != nil {
+		return utils.Errorf(err, L("failed to change the owner of %s"), target)
 	}
 	return nil
 }
 
 // TestExistenceInPod returns true if dstpath exists in the pod.
 func (c *Connection) TestExistenceInPod(dstpath string) bool {
+	if c.apiPod() != nil || c.libpodContainer() != "" {
+		_, err := c.Exec("test", "-e", dstpath)
+		return err == nil
+	}
 	podName, err := c.GetPodName()
 	if err != nil {
 		log.Fatal().Err(err)
@@ -362,7 +655,7 @@ func (c *Connection) TestExistenceInPod(dstpath string) bool {
 		log.Fatal().Msgf(L("unknown container kind: %s"), command)
 	}
 
-	if _, err := utils.RunCmdOutput(zerolog.DebugLevel, command, commandArgs...); err != nil {
+	if _, err := c.executor().RunOutput(zerolog.DebugLevel, command, commandArgs...); err != nil {
 		return false
 	}
 	return true
@@ -387,36 +680,87 @@ func (c *Connection) CopyCaCertificate(fqdn string) error {
 	}
 
 	log.Info().Msg(L("Updating host trusted certificates"))
-	return utils.RunCmdStdMapping(zerolog.DebugLevel, "update-ca-certificates")
+	return c.executor().RunStdMapping(zerolog.DebugLevel, "update-ca-certificates")
 }
 
 // ChoosePodmanOrKubernetes selects either the podman or the kubernetes function based on the backend.
 // This function automatically detects the backend if compiled with kubernetes support and the backend flag is not passed.
+//
+// The commands using it access the files of the host: ssh:// backends are refused.
 func ChoosePodmanOrKubernetes[F interface{}](
 	flags *pflag.FlagSet,
 	podmanFn utils.CommandFunc[F],
 	kubernetesFn utils.CommandFunc[F],
 ) (utils.CommandFunc[F], error) {
-	backend := "podman"
-	runningBinary := filepath.Base(os.Args[0])
-	if utils.KubernetesBuilt || runningBinary == "mgrpxy" {
-		backend, _ = flags.GetString("backend")
+	backend := serverBackend(flags)
+	if utils.IsSSHBackend(backend) {
+		return nil, fmt.Errorf(L("this command cannot manage a remote host: %s backend is not supported"), backend)
 	}
 
 	cnx := NewConnection(backend, podman.ServerContainerName, kubernetes.ServerFilter)
 	return chooseBackend(cnx, podmanFn, kubernetesFn)
 }
 
+// ChooseRemotePodmanOrKubernetes is like ChoosePodmanOrKubernetes for the commands supporting ssh:// backends.
+//
+// The selected function runs the podman and systemd commands on the remote host.
+func ChooseRemotePodmanOrKubernetes[F interface{}](
+	flags *pflag.FlagSet,
+	podmanFn utils.CommandFunc[F],
+	kubernetesFn utils.CommandFunc[F],
+) (utils.CommandFunc[F], error) {
+	backend := serverBackend(flags)
+	cnx := NewConnection(backend, podman.ServerContainerName, kubernetes.ServerFilter)
+	fn, err := chooseBackend(cnx, podmanFn, kubernetesFn)
+	if err != nil {
+		return nil, err
+	}
+	return withSSHBackend(backend, fn), nil
+}
+
+// serverBackend returns the backend flag value to use for the server commands.
+func serverBackend(flags *pflag.FlagSet) string {
+	backend := "podman"
+	runningBinary := filepath.Base(os.Args[0])
+	flagBackend, _ := flags.GetString("backend")
+	if utils.KubernetesBuilt || runningBinary == "mgrpxy" || utils.IsSSHBackend(flagBackend) {
+		backend = flagBackend
+	}
+	return backend
+}
+
 // ChooseProxyPodmanOrKubernetes selects either the podman or the kubernetes function based on the backend for the proxy.
+//
+// With a ssh:// backend, the selected function runs the podman and systemd commands on the remote host.
 func ChooseProxyPodmanOrKubernetes[F interface{}](
 	flags *pflag.FlagSet,
 	podmanFn utils.CommandFunc[F],
 	kubernetesFn utils.CommandFunc[F],
 ) (utils.CommandFunc[F], error) {
 	backend, _ := flags.GetString("backend")
-
 	cnx := NewConnection(backend, podman.ProxyContainerNames[0], kubernetes.ProxyFilter)
-	return chooseBackend(cnx, podmanFn, kubernetesFn)
+	fn, err := chooseBackend(cnx, podmanFn, kubernetesFn)
+	if err != nil {
+		return nil, err
+	}
+	return withSSHBackend(backend, fn), nil
+}
+
+// withSSHBackend wraps fn to run all its host commands on the remote host of a ssh:// backend.
+//
+// The previous executor is restored once fn is done.
+func withSSHBackend[F interface{}](backend string, fn utils.CommandFunc[F]) utils.CommandFunc[F] {
+	if !utils.IsSSHBackend(backend) {
+		return fn
+	}
+	return func(globalFlags *types.GlobalFlags, flags *F, cmd *cobra.Command, args []string) error {
+		restore, err := utils.UseSSHBackend(backend)
+		if err != nil {
+			return err
+		}
+		defer restore()
+		return fn(globalFlags, flags, cmd, args)
+	}
 }
 
 func chooseBackend[F interface{}](
@@ -482,32 +826,3 @@ func (cnx *Connection) RunSupportConfig() ([]string, error) {
 	}
 	return files, nil
 }
-
-// extractNamespaceFromConfig extracts the namespace of a given application
-// from the Helm release information.
-func extractNamespaceFromConfig(appName string, kubeconfig string) (string, error) {
-	args := []string{}
-	if kubeconfig != "" {
-		args = append(args, "--kubeconfig", kubeconfig)
-	}
-	args = append(args, "list", "-aA", "-f", appName, "-o", "json")
-
-	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "helm", args...)
-	if err != nil {
-		return "", utils.Errorf(err, L("failed to detect %s's namespace using helm"), appName)
-	}
-
-	var data []releaseInfo
-	if err = json.Unmarshal(out, &data); err != nil {
-		return "", utils.Errorf(err, L("helm provided an invalid JSON output"))
-	}
-
-	if len(data) == 1 {
-		return data[0].Namespace, nil
-	}
-	return "", errors.New(L("found no or more than one deployment"))
-}
-
-type releaseInfo struct {
-	Namespace string `mapstructure:"namespace"`
-}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/uyuni-project/uyuni-tools/shared/podman"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestPodmanConnection(t *testing.T) {
+	executor := test_utils.NewFakeExecutor(t).Install("podman")
+	executor.
+		Expect("4c0ffee\n", nil, "podman", "ps", "-q", "-f", "name=uyuni-server").
+		Expect("server.lab\n", nil, "podman", "exec", "uyuni-server", "hostname", "-f").
+		Expect("", nil, "podman", "cp", "/tmp/setup.sh", "uyuni-server:/tmp/setup.sh").
+		Expect("", nil, "podman", "exec", "uyuni-server", "chown", "root:root", "/tmp/setup.sh").
+		Expect("content", nil, "podman", "exec", "-i", "uyuni-server", "cat", "-")
+
+	cnx := NewConnection("podman", "uyuni-server", "-lapp=uyuni")
+	cnx.SetExecutor(executor)
+
+	out, err := cnx.Exec("hostname", "-f")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong exec output", "server.lab\n", string(out))
+
+	if err := cnx.Copy("/tmp/setup.sh", "server:/tmp/setup.sh", "root", "root"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := cnx.ExecStream(bytes.NewBufferString("content"), &stdout, "cat", "-"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong streamed output", "content", stdout.String())
+}
+
+func TestMissingBackend(t *testing.T) {
+	cnx := NewConnection("kubectl", "", "-lapp=uyuni")
+	cnx.SetExecutor(test_utils.NewFakeExecutor(t))
+	if _, err := cnx.GetCommand(); err == nil {
+		t.Error("Expected an error for a backend not in the PATH")
+	}
+}
+
+func TestInstanceConnection(t *testing.T) {
+	restore, err := podman.SetInstance("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restore()
+
+	cnx := NewConnection("kubectl", podman.ServerContainerName, "-lapp=uyuni")
+	cnx.SetExecutor(test_utils.NewFakeExecutor(t).Install("kubectl"))
+	if _, err := cnx.GetCommand(); err == nil {
+		t.Error("Expected an error for an instance with kubectl")
+	}
+
+	// kubectl is not checked for instances
+	executor := test_utils.NewFakeExecutor(t).Install("kubectl", "podman")
+	executor.Expect("", nil, "podman", "inspect", "uyuni-staging-server", "--format", "{{.Name}}")
+	cnx = NewConnection("", podman.ServerContainerName, "-lapp=uyuni")
+	cnx.SetExecutor(executor)
+	command, err := cnx.GetCommand()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong backend", "podman", command)
+}
+
+func TestRemoteConnection(t *testing.T) {
+	executor := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(executor))
+	ssh := []string{"ssh", "--", "admin@server.lab"}
+	executor.
+		Expect("", test_utils.ErrCommandFailed, append(ssh, "command -v kubectl")...).
+		Expect("/usr/bin/podman\n", nil, append(ssh, "command -v podman")...).
+		Expect("", nil, append(ssh, "podman inspect uyuni-server --format '{{.Name}}'")...).
+		Expect("4c0ffee\n", nil, append(ssh, "podman ps -q -f name=uyuni-server")...).
+		Expect("", nil, append(ssh, "podman exec -i uyuni-server sh -c 'cat > /tmp/setup.sh'")...).
+		Expect("", nil, append(ssh, "podman exec uyuni-server chown root:root /tmp/setup.sh")...).
+		Expect("content", nil, append(ssh, "podman exec uyuni-server cat /etc/rhn/rhn.conf")...)
+
+	srcPath := path.Join(t.TempDir(), "setup.sh")
+	test_utils.WriteFile(t, srcPath, "#!/bin/sh\n")
+	dstPath := path.Join(t.TempDir(), "rhn.conf")
+
+	cnx := NewConnection("ssh://admin@server.lab", "uyuni-server", "-lapp=uyuni")
+	if err := cnx.Copy(srcPath, "server:/tmp/setup.sh", "root", "root"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := cnx.Copy("server:/etc/rhn/rhn.conf", dstPath, "", ""); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong copied content", "content", test_utils.ReadFile(t, dstPath))
+
+	command, args := cnx.HostCommand(true, "podman", "exec", "-it", "uyuni-server", "bash")
+	test_utils.AssertEquals(t, "wrong host command", "ssh -t -- admin@server.lab podman exec -it uyuni-server bash",
+		command+" "+strings.Join(args, " "))
+
+	if _, err := NewConnection("ssh://", "uyuni-server", "").GetCommand(); err == nil {
+		t.Error("Expected an error for an invalid SSH backend")
+	}
+}
+
+func TestChooseRemoteBackend(t *testing.T) {
+	executor := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(executor))
+	ssh := []string{"ssh", "--", "admin@server.lab"}
+	executor.
+		Stub("", test_utils.ErrCommandFailed, append(ssh, "command -v kubectl")...).
+		Stub("/usr/bin/podman\n", nil, append(ssh, "command -v podman")...).
+		Stub("", nil, append(ssh, "podman inspect uyuni-server --format '{{.Name}}'")...)
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("backend", "ssh://admin@server.lab", "")
+
+	remoteFn := func(*types.GlobalFlags, *struct{}, *cobra.Command, []string) error {
+		if !utils.IsRemoteExecutor() {
+			t.Error("Expected the commands to run on the remote host")
+		}
+		return nil
+	}
+	failFn := func(*types.GlobalFlags, *struct{}, *cobra.Command, []string) error {
+		t.Error("Unexpected kubernetes function call")
+		return nil
+	}
+
+	if _, err := ChoosePodmanOrKubernetes(flags, remoteFn, failFn); err == nil {
+		t.Error("Expected an error for a command not supporting SSH backends")
+	}
+
+	fn, err := ChooseRemotePodmanOrKubernetes(flags, remoteFn, failFn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := fn(nil, &struct{}{}, nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "remote executor not restored", !utils.IsRemoteExecutor())
+}
@@ -0,0 +1,470 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !nok8s
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/yaml"
+)
+
+// apiTimeout is the timeout of the requests to the API server, like kubectl --request-timeout.
+const apiTimeout = 30 * time.Second
+
+// defaultKubeconfigs are the kubeconfig files of the clusters distributions used if the user has none.
+var defaultKubeconfigs = []string{"/etc/rancher/k3s/k3s.yaml", "/etc/rancher/rke2/rke2.yaml"}
+
+// Client queries the Kubernetes API server of the cluster configured in the kubeconfig.
+type Client struct {
+	clientset k8s.Interface
+	config    *rest.Config
+	context   string
+	namespace string
+}
+
+func init() {
+	newAPIClient = func(kubeconfig string) (apiClient, error) {
+		client, err := NewClient(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		// Check the cluster is answering before using it
+		if _, err := client.clientset.Discovery().ServerVersion(); err != nil {
+			return nil, utils.Errorf(err, L("failed to connect to %s"), client)
+		}
+		return client, nil
+	}
+}
+
+// NewClient creates a Kubernetes API client like kubectl does.
+//
+// If kubeconfig is empty, the kubeconfig files are read from the KUBECONFIG environment variable
+// or ~/.kube/config and the current context is used. If none of those is defined, the k3s or rke2
+// kubeconfig is used.
+func NewClient(kubeconfig string) (*Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	} else if os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" &&
+		!utils.FileExists(clientcmd.RecommendedHomeFile) {
+		for _, kubeconfig := range defaultKubeconfigs {
+			if utils.FileExists(kubeconfig) {
+				rules.ExplicitPath = kubeconfig
+				break
+			}
+		}
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to read the kubeconfig"))
+	}
+	config.Timeout = apiTimeout
+
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to read the kubeconfig"))
+	}
+
+	clientset, err := k8s.NewForConfig(config)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to create the Kubernetes client"))
+	}
+
+	client := Client{clientset: clientset, config: config, namespace: namespace}
+	if rawConfig, err := clientConfig.RawConfig(); err == nil {
+		client.context = rawConfig.CurrentContext
+	}
+	return &client, nil
+}
+
+// String describes the cluster for the error messages.
+func (c *Client) String() string {
+	host := ""
+	if c.config != nil {
+		host = c.config.Host
+	}
+	if c.context == "" {
+		return host
+	}
+	return fmt.Sprintf(L("%[1]s (context %[2]s)"), host, c.context)
+}
+
+// Namespace returns the namespace of the kubeconfig context.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// GetKubeletVersion returns the kubelet version of the first node of the cluster.
+func (c *Client) GetKubeletVersion() (string, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return "", c.wrapError(err)
+	}
+	if len(nodes.Items) == 0 {
+		return "", fmt.Errorf(L("no node found on %s"), c)
+	}
+	return nodes.Items[0].Status.NodeInfo.KubeletVersion, nil
+}
+
+// HasResource returns whether a kind of resource like IngressRouteTCP is known by the cluster.
+func (c *Client) HasResource(kind string) (bool, error) {
+	resources, err := c.clientset.Discovery().ServerPreferredResources()
+	// Some API groups may fail while the others are listed
+	if err != nil && len(resources) == 0 {
+		return false, c.wrapError(err)
+	}
+	for _, list := range resources {
+		for _, resource := range list.APIResources {
+			if strings.EqualFold(resource.Kind, kind) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// HasContainerCommand returns whether a container of the cluster runs a command containing text.
+func (c *Client) HasContainerCommand(text string) (bool, error) {
+	list, err := c.clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return false, c.wrapError(err)
+	}
+	for _, pod := range list.Items {
+		for _, container := range pod.Spec.Containers {
+			command := strings.Join(append(container.Command, container.Args...), " ")
+			if strings.Contains(command, text) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// GetPods lists the pods matching a label selector, in all the namespaces if namespace is empty.
+func (c *Client) GetPods(namespace string, selector string) ([]Pod, error) {
+	if _, err := labels.Parse(selector); err != nil {
+		return nil, utils.Errorf(err, L("invalid label selector %s"), selector)
+	}
+	list, err := c.clientset.CoreV1().Pods(namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	pods := make([]Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = newPod(&list.Items[i])
+	}
+	return pods, nil
+}
+
+// GetPod returns a pod, nil if it doesn't exist.
+func (c *Client) GetPod(namespace string, name string) (*Pod, error) {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, c.wrapError(err)
+	}
+	result := newPod(pod)
+	return &result, nil
+}
+
+// GetPodLogs returns the logs of a pod.
+func (c *Client) GetPodLogs(namespace string, name string) ([]byte, error) {
+	out, err := c.clientset.CoreV1().Pods(c.namespaceOrDefault(namespace)).GetLogs(name, &corev1.PodLogOptions{}).
+		DoRaw(context.Background())
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	return out, nil
+}
+
+// CreatePod creates a pod running command like kubectl run does.
+//
+// The labels are a selector like app=uyuni and the overrides are strategic merge patches of the pod definition.
+func (c *Client) CreatePod(namespace string, name string, labelsSelector string, image string, pullPolicy string,
+	overrides []string, command []string) error {
+	podLabels, err := labels.ConvertSelectorToLabelsMap(labelsSelector)
+	if err != nil {
+		return utils.Errorf(err, L("invalid label selector %s"), labelsSelector)
+	}
+	pod := corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: podLabels},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:            name,
+				Image:           image,
+				ImagePullPolicy: corev1.PullPolicy(pullPolicy),
+				Command:         command,
+			}},
+		},
+	}
+	for _, override := range overrides {
+		original, err := json.Marshal(pod)
+		if err != nil {
+			return utils.Errorf(err, L("cannot serialize pod definition override"))
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, []byte(override), corev1.Pod{})
+		if err != nil {
+			return utils.Errorf(err, L("cannot apply the pod definition override"))
+		}
+		pod = corev1.Pod{}
+		if err := json.Unmarshal(patched, &pod); err != nil {
+			return utils.Errorf(err, L("cannot apply the pod definition override"))
+		}
+	}
+
+	_, err = c.clientset.CoreV1().Pods(c.namespaceOrDefault(namespace)).
+		Create(context.Background(), &pod, metav1.CreateOptions{})
+	if err != nil {
+		return c.wrapError(err)
+	}
+	return nil
+}
+
+// DeletePod deletes a pod without waiting for it to be removed.
+func (c *Client) DeletePod(namespace string, name string) error {
+	err := c.clientset.CoreV1().Pods(c.namespaceOrDefault(namespace)).
+		Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil {
+		return c.wrapError(err)
+	}
+	return nil
+}
+
+// GetDeploymentReplicas returns the number of replicas a deployment is scaled to.
+func (c *Client) GetDeploymentReplicas(namespace string, name string) (uint, error) {
+	deployment, err := c.clientset.AppsV1().Deployments(c.namespaceOrDefault(namespace)).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return 0, c.wrapError(err)
+	}
+	// The API server defaults to 1 replica
+	if deployment.Spec.Replicas == nil {
+		return 1, nil
+	}
+	return uint(*deployment.Spec.Replicas), nil
+}
+
+// ScaleDeployment sets the number of replicas of a deployment.
+func (c *Client) ScaleDeployment(namespace string, name string, replicas uint) error {
+	patch := fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)
+	_, err := c.clientset.AppsV1().Deployments(c.namespaceOrDefault(namespace)).
+		Patch(context.Background(), name, k8stypes.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return c.wrapError(err)
+	}
+	return nil
+}
+
+// GetConfigMapsYaml returns the config maps of a namespace in YAML like kubectl get configmap -o yaml.
+func (c *Client) GetConfigMapsYaml(namespace string) ([]byte, error) {
+	list, err := c.clientset.CoreV1().ConfigMaps(c.namespaceOrDefault(namespace)).
+		List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	items := make([]interface{}, len(list.Items))
+	for i := range list.Items {
+		item := list.Items[i]
+		item.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+		item.ManagedFields = nil
+		items[i] = item
+	}
+	return yaml.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+}
+
+// GetPodYaml returns the definition of a pod in YAML like kubectl get pod -o yaml.
+func (c *Client) GetPodYaml(namespace string, name string) ([]byte, error) {
+	pod, err := c.clientset.CoreV1().Pods(c.namespaceOrDefault(namespace)).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	pod.ManagedFields = nil
+	return yaml.Marshal(pod)
+}
+
+func newPod(pod *corev1.Pod) Pod {
+	result := Pod{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		NodeName:  pod.Spec.NodeName,
+		Phase:     string(pod.Status.Phase),
+		Images:    map[string]string{},
+	}
+	for _, container := range pod.Spec.Containers {
+		result.Images[container.Name] = container.Image
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			result.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	return result
+}
+
+// GetConfigMapValue returns the value of a config map key.
+func (c *Client) GetConfigMapValue(namespace string, name string, key string) (string, error) {
+	configMap, err := c.clientset.CoreV1().ConfigMaps(c.namespaceOrDefault(namespace)).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", c.wrapError(err)
+	}
+	value, found := configMap.Data[key]
+	if !found {
+		return "", fmt.Errorf(L("no %[1]s key in %[2]s config map"), key, name)
+	}
+	return value, nil
+}
+
+// GetSecretValue returns the decoded value of a secret key.
+func (c *Client) GetSecretValue(namespace string, name string, key string) ([]byte, error) {
+	secret, err := c.clientset.CoreV1().Secrets(c.namespaceOrDefault(namespace)).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	value, found := secret.Data[key]
+	if !found {
+		return nil, fmt.Errorf(L("no %[1]s key in %[2]s secret"), key, name)
+	}
+	return value, nil
+}
+
+// GetPersistentVolumeClaims lists the persistent volume claims of a namespace.
+func (c *Client) GetPersistentVolumeClaims(namespace string) ([]PersistentVolumeClaim, error) {
+	list, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespaceOrDefault(namespace)).
+		List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+	claims := make([]PersistentVolumeClaim, len(list.Items))
+	for i, item := range list.Items {
+		claims[i] = PersistentVolumeClaim{
+			Name:   item.Name,
+			Volume: item.Spec.VolumeName,
+			Phase:  string(item.Status.Phase),
+		}
+		if item.Spec.StorageClassName != nil {
+			claims[i].StorageClass = *item.Spec.StorageClassName
+		}
+		if requested, found := item.Spec.Resources.Requests[corev1.ResourceStorage]; found {
+			claims[i].Requested = requested.String()
+		}
+		if capacity, found := item.Status.Capacity[corev1.ResourceStorage]; found {
+			claims[i].Capacity = capacity.String()
+		}
+	}
+	return claims, nil
+}
+
+// GetReleaseNamespaces returns the namespaces where a helm release is installed.
+//
+// The releases are read with the helm storage driver selected by HELM_DRIVER: this works without helm.
+func (c *Client) GetReleaseNamespaces(release string) ([]string, error) {
+	var storage driver.Driver
+	switch os.Getenv("HELM_DRIVER") {
+	case "configmap", "configmaps":
+		configMaps := driver.NewConfigMaps(c.clientset.CoreV1().ConfigMaps(""))
+		configMaps.Log = logHelmDriver
+		storage = configMaps
+	default:
+		secrets := driver.NewSecrets(c.clientset.CoreV1().Secrets(""))
+		secrets.Log = logHelmDriver
+		storage = secrets
+	}
+
+	releases, err := storage.Query(map[string]string{"owner": "helm", "name": release})
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, c.wrapError(err)
+	}
+	namespaces := []string{}
+	for _, item := range releases {
+		if !utils.Contains(namespaces, item.Namespace) {
+			namespaces = append(namespaces, item.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+func logHelmDriver(format string, args ...interface{}) {
+	log.Debug().Msgf("helm storage driver: "+format, args...)
+}
+
+// Exec runs a command in a pod container, stdin and stdout can be nil.
+func (c *Client) Exec(namespace string, pod string, container string, stdin io.Reader, stdout io.Writer,
+	stderr io.Writer, command ...string) error {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+		}, scheme.ParameterCodec)
+
+	// The commands can run longer than the requests timeout
+	config := *c.config
+	config.Timeout = 0
+	executor, err := remotecommand.NewSPDYExecutor(&config, "POST", req.URL())
+	if err != nil {
+		return utils.Errorf(err, L("failed to execute in pod %s"), pod)
+	}
+	return executor.StreamWithContext(context.Background(), remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
+
+func (c *Client) namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return c.namespace
+	}
+	return namespace
+}
+
+// wrapError adds the cluster to the errors which are not reported by the API server like connection ones.
+func (c *Client) wrapError(err error) error {
+	if _, isStatus := err.(k8serrors.APIStatus); isStatus {
+		return err
+	}
+	return utils.Errorf(err, L("failed to query %s"), c)
+}
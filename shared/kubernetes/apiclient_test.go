@@ -0,0 +1,430 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !nok8s
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// setFakeClient uses a client of a fake cluster containing objects for the test.
+func setFakeClient(t *testing.T, objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	client := &Client{
+		clientset: clientset,
+		config:    &rest.Config{Host: "https://cluster:6443"},
+		context:   "default",
+		namespace: "default",
+	}
+	t.Cleanup(setAPIClient(client))
+	return clientset
+}
+
+func newTestPod(namespace string, name string, phase corev1.PodPhase, ready bool, labels map[string]string) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: corev1.PodSpec{
+			NodeName: "node1",
+			Containers: []corev1.Container{
+				{Name: "uyuni", Image: "registry.opensuse.org/uyuni/server:latest"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: readyStatus}},
+		},
+	}
+}
+
+func TestAPIPods(t *testing.T) {
+	labels := map[string]string{"app": ServerApp}
+	setFakeClient(t,
+		newTestPod("uyuni", "uyuni-old", corev1.PodSucceeded, false, labels),
+		newTestPod("uyuni", "uyuni-starting", corev1.PodRunning, false, labels),
+		newTestPod("uyuni", "uyuni-ready", corev1.PodRunning, true, labels),
+		newTestPod("default", "uyuni-proxy", corev1.PodRunning, true, map[string]string{"app": ProxyApp}),
+	)
+
+	pods, err := FindPods(ServerFilter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of pods", 3, len(pods))
+
+	pod, err := FindRunningPod(ServerFilter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "ready pod not preferred", "uyuni-ready", pod.Name)
+	test_utils.AssertEquals(t, "wrong namespace", "uyuni", pod.Namespace)
+	test_utils.AssertEquals(t, "wrong image", "registry.opensuse.org/uyuni/server:latest", pod.Images["uyuni"])
+
+	if _, err := FindRunningPod("-lapp=missing"); err == nil {
+		t.Error("Expected an error for missing pods")
+	}
+
+	// Without namespace, kubectl looks in the one of the context
+	proxyPods, err := GetPods(ProxyFilter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong proxy pods", "uyuni-proxy", proxyPods[0])
+	node, err := GetNode("", "uyuni-proxy")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong node", "node1", node)
+}
+
+func TestAPIPersistentVolumeClaims(t *testing.T) {
+	storageClass := "local-path"
+	setFakeClient(t,
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "var-pgsql", Namespace: "uyuni"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				VolumeName:       "pvc-1234",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Phase:    corev1.ClaimBound,
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+			},
+		},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}},
+	)
+
+	claims, err := GetPersistentVolumeClaims("uyuni")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of claims", 1, len(claims))
+	test_utils.AssertEquals(t, "wrong claim", PersistentVolumeClaim{
+		Name:         "var-pgsql",
+		StorageClass: "local-path",
+		Volume:       "pvc-1234",
+		Requested:    "50Gi",
+		Capacity:     "50Gi",
+		Phase:        "Bound",
+	}, claims[0])
+
+	// Without namespace, the one of the context is used
+	claims, err = GetPersistentVolumeClaims("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of claims in the default namespace", 1, len(claims))
+	test_utils.AssertEquals(t, "wrong default namespace claim", "other", claims[0].Name)
+}
+
+// newFakeHelmClient creates a client of a fake cluster containing helm releases stored by a helm storage driver.
+func newFakeHelmClient(t *testing.T, configMaps bool, releases ...*release.Release) *Client {
+	clientset := fake.NewSimpleClientset()
+	for _, item := range releases {
+		item.Info = &release.Info{Status: release.StatusDeployed}
+		var storage driver.Driver = driver.NewSecrets(clientset.CoreV1().Secrets(item.Namespace))
+		if configMaps {
+			storage = driver.NewConfigMaps(clientset.CoreV1().ConfigMaps(item.Namespace))
+		}
+		key := fmt.Sprintf("sh.helm.release.v1.%s.v%d", item.Name, item.Version)
+		if err := storage.Create(key, item); err != nil {
+			t.Fatalf("failed to store the %s release: %s", item.Name, err)
+		}
+	}
+	return &Client{
+		clientset: clientset,
+		config:    &rest.Config{Host: "https://cluster:6443"},
+		namespace: "default",
+	}
+}
+
+func TestAPIHelmReleases(t *testing.T) {
+	releases := []*release.Release{
+		{Name: ServerApp, Namespace: "uyuni", Version: 1},
+		{Name: ServerApp, Namespace: "uyuni", Version: 2},
+		{Name: ProxyApp, Namespace: "proxy", Version: 1},
+	}
+
+	for _, configMaps := range []bool{false, true} {
+		if configMaps {
+			t.Setenv("HELM_DRIVER", "configmap")
+		}
+		t.Cleanup(setAPIClient(newFakeHelmClient(t, configMaps, releases...)))
+
+		namespace, err := GetReleaseNamespace(ServerApp, "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		test_utils.AssertEquals(t, "wrong server namespace", "uyuni", namespace)
+		test_utils.AssertTrue(t, "proxy release not found", HasHelmRelease(ProxyApp, ""))
+		test_utils.AssertTrue(t, "unexpected release found", !HasHelmRelease("cert-manager", ""))
+		if _, err := GetReleaseNamespace("cert-manager", ""); err == nil {
+			t.Error("Expected an error for a missing release")
+		}
+	}
+}
+
+func TestAPIHelmReleasesKubeconfig(t *testing.T) {
+	defaultClient := newFakeHelmClient(t, false, &release.Release{Name: ServerApp, Namespace: "default", Version: 1})
+	t.Cleanup(setAPIClient(defaultClient))
+
+	otherClient := newFakeHelmClient(t, false, &release.Release{Name: ServerApp, Namespace: "uyuni", Version: 1})
+	previousNewAPIClient := newAPIClient
+	newAPIClient = func(kubeconfig string) (apiClient, error) {
+		if kubeconfig == "/etc/other/kubeconfig" {
+			return otherClient, nil
+		}
+		return nil, errors.New("no cluster")
+	}
+	t.Cleanup(func() {
+		newAPIClient = previousNewAPIClient
+		kubeconfigClients = map[string]apiClient{}
+	})
+
+	namespace, err := GetReleaseNamespace(ServerApp, "/etc/other/kubeconfig")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "kubeconfig ignored", "uyuni", namespace)
+
+	namespace, err = GetReleaseNamespace(ServerApp, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong default namespace", "default", namespace)
+
+	// helm is used when the cluster of the kubeconfig doesn't answer
+	test_utils.AssertTrue(t, "API used for an unreachable cluster", helmReleasesAPI("/etc/broken/kubeconfig") == nil)
+
+	// The releases stored in an SQL database can only be read by helm
+	t.Setenv("HELM_DRIVER", "sql")
+	test_utils.AssertTrue(t, "API used for the sql driver", helmReleasesAPI("") == nil)
+}
+
+func TestAPIConfigMapAndSecret(t *testing.T) {
+	setFakeClient(t,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "uyuni-ca", Namespace: "default"},
+			Data:       map[string][]byte{"ca.crt": []byte("CA certificate")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "proxy-configMap", Namespace: "default"},
+			Data:       map[string]string{"config.yaml": "server: uyuni.example.com"},
+		},
+	)
+
+	ca, err := GetSecret("uyuni-ca", "ca.crt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong secret value", "CA certificate", ca)
+
+	config, err := GetConfigMap("proxy-configMap", "config.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong config map value", "server: uyuni.example.com", config)
+
+	if _, err := GetSecret("uyuni-ca", "tls.crt"); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+	if _, err := GetConfigMap("uyuni-ca", "ca.crt"); err == nil {
+		t.Error("Expected an error for a missing config map")
+	}
+}
+
+func TestAPICheckCluster(t *testing.T) {
+	controller := newTestPod("ingress", "ingress-nginx-controller", corev1.PodRunning, true, nil)
+	controller.Spec.Containers[0].Args = []string{"/nginx-ingress-controller", "--election-id=ingress"}
+	setFakeClient(t,
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.28.9+rke2r1"}},
+		},
+		controller,
+	)
+
+	infos, err := CheckCluster()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "rke2 not detected", infos.IsRke2())
+	test_utils.AssertEquals(t, "wrong ingress", "nginx", infos.Ingress)
+}
+
+func TestAPIReplicas(t *testing.T) {
+	replicas := int32(2)
+	clientset := setFakeClient(t, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: ServerApp, Namespace: "uyuni"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	})
+
+	actual, err := GetReplicas("uyuni", ServerApp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong replicas", uint(2), actual)
+
+	if err := ReplicasTo("uyuni", ServerApp, 0); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	deployment, err := clientset.AppsV1().Deployments("uyuni").Get(context.Background(), ServerApp, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "deployment not scaled", int32(0), *deployment.Spec.Replicas)
+
+	// Without namespace, the one of the context is used
+	if _, err := GetReplicas("", ServerApp); err == nil {
+		t.Error("Expected an error for a deployment in another namespace")
+	}
+}
+
+// setPodPhase makes the fake cluster set the phase of the created pods.
+func setPodPhase(clientset *fake.Clientset, phase corev1.PodPhase) {
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = phase
+		return false, nil, nil
+	})
+}
+
+func TestAPIStartPod(t *testing.T) {
+	clientset := setFakeClient(t)
+	setPodPhase(clientset, corev1.PodRunning)
+
+	override, err := GenerateOverrideDeployment(types.Deployment{
+		APIVersion: "v1",
+		Spec: &types.Spec{
+			RestartPolicy: "Never",
+			NodeName:      "node1",
+			Containers: []types.Container{
+				{
+					Name:         "uyuni-backup",
+					VolumeMounts: []types.VolumeMount{{MountPath: "/var/lib/pgsql", Name: "var-pgsql"}},
+				},
+			},
+			Volumes: []types.Volume{
+				{Name: "var-pgsql", PersistentVolumeClaim: &types.PersistentVolumeClaim{ClaimName: "var-pgsql"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if err := StartPod("uyuni", "uyuni-backup", ServerFilter, "server:latest", "IfNotPresent", override,
+		"sleep", "infinity"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	pod, err := clientset.CoreV1().Pods("uyuni").Get(context.Background(), "uyuni-backup", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong label", ServerApp, pod.Labels["app"])
+	test_utils.AssertEquals(t, "override not applied", "node1", pod.Spec.NodeName)
+	test_utils.AssertEquals(t, "override not applied", corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	test_utils.AssertEquals(t, "containers not merged", 1, len(pod.Spec.Containers))
+	container := pod.Spec.Containers[0]
+	test_utils.AssertEquals(t, "wrong image", "server:latest", container.Image)
+	test_utils.AssertEquals(t, "wrong pull policy", corev1.PullIfNotPresent, container.ImagePullPolicy)
+	test_utils.AssertEquals(t, "wrong command", "sleep infinity", strings.Join(container.Command, " "))
+	test_utils.AssertEquals(t, "volume mount not applied", "/var/lib/pgsql", container.VolumeMounts[0].MountPath)
+	test_utils.AssertEquals(t, "volume not applied", "var-pgsql", pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+	if err := DeletePod("uyuni", "uyuni-backup", ServerFilter); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	pods, err := clientset.CoreV1().Pods("uyuni").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "pod not deleted", 0, len(pods.Items))
+}
+
+func TestAPIRunPodOutput(t *testing.T) {
+	clientset := setFakeClient(t)
+	setPodPhase(clientset, corev1.PodSucceeded)
+
+	out, err := RunPodOutput("inspector", ServerFilter, "server:latest", "Always", "sh", "-c", "true")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// The fake cluster returns the same logs for all pods
+	test_utils.AssertEquals(t, "wrong output", "fake logs", string(out))
+
+	pods, err := clientset.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "pod not deleted", 0, len(pods.Items))
+}
+
+func TestAPISupportFiles(t *testing.T) {
+	setFakeClient(t,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: ProxyApp, Namespace: "default"}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "proxy-configMap", Namespace: "default"},
+			Data:       map[string]string{"config.yaml": "server: uyuni.example.com"},
+		},
+		newTestPod("default", "uyuni-proxy", corev1.PodRunning, true, map[string]string{"app": ProxyApp}),
+	)
+	dir := t.TempDir()
+
+	namespace, err := fetchNamespace(ProxyApp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong namespace", "default", namespace)
+
+	configmapFile, err := fetchConfigMap(dir, namespace)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	content, err := os.ReadFile(configmapFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "not a list", strings.Contains(string(content), "kind: List"))
+	test_utils.AssertTrue(t, "missing config map", strings.Contains(string(content), "name: proxy-configMap"))
+
+	podFiles, err := fetchPodYaml(dir, namespace)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of pod files", 1, len(podFiles))
+	content, err = os.ReadFile(podFiles[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "not a pod", strings.Contains(string(content), "kind: Pod"))
+	test_utils.AssertTrue(t, "missing image", strings.Contains(string(content), "image: registry.opensuse.org/uyuni/server:latest"))
+}
@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// Pod describes a pod of the cluster.
+type Pod struct {
+	Name      string
+	Namespace string
+	NodeName  string
+	// Phase is the pod phase like Running or Succeeded.
+	Phase string
+	// Ready is true if all the containers of the pod are ready.
+	Ready bool
+	// Images maps the containers names to their image.
+	Images map[string]string
+}
+
+// PersistentVolumeClaim describes a persistent volume claim of the cluster.
+type PersistentVolumeClaim struct {
+	Name         string
+	StorageClass string
+	// Volume is the name of the bound persistent volume.
+	Volume string
+	// Requested and Capacity are storage sizes like 10Gi.
+	Requested string
+	Capacity  string
+	// Phase is the claim phase like Bound or Pending.
+	Phase string
+}
+
+// apiClient queries the Kubernetes API server instead of running kubectl.
+type apiClient interface {
+	// String describes the cluster for the error messages.
+	String() string
+	// Namespace returns the namespace of the kubeconfig context, used when none is given.
+	Namespace() string
+	GetKubeletVersion() (string, error)
+	HasResource(kind string) (bool, error)
+	// HasContainerCommand returns whether a container of the cluster runs a command containing text.
+	HasContainerCommand(text string) (bool, error)
+	// GetPods lists the pods matching a label selector, in all the namespaces if namespace is empty.
+	GetPods(namespace string, selector string) ([]Pod, error)
+	// GetPod returns a pod, nil if it doesn't exist.
+	GetPod(namespace string, name string) (*Pod, error)
+	GetPodLogs(namespace string, name string) ([]byte, error)
+	// CreatePod creates a pod running command like kubectl run does.
+	// The labels are a selector like app=uyuni and the overrides are strategic merge patches of the pod definition.
+	CreatePod(namespace string, name string, labels string, image string, pullPolicy string, overrides []string,
+		command []string) error
+	// DeletePod deletes a pod without waiting for it to be removed.
+	DeletePod(namespace string, name string) error
+	GetDeploymentReplicas(namespace string, name string) (uint, error)
+	ScaleDeployment(namespace string, name string, replicas uint) error
+	// GetConfigMapsYaml returns the config maps of a namespace in YAML like kubectl get configmap -o yaml.
+	GetConfigMapsYaml(namespace string) ([]byte, error)
+	GetPodYaml(namespace string, name string) ([]byte, error)
+	GetConfigMapValue(namespace string, name string, key string) (string, error)
+	GetSecretValue(namespace string, name string, key string) ([]byte, error)
+	GetPersistentVolumeClaims(namespace string) ([]PersistentVolumeClaim, error)
+	// GetReleaseNamespaces returns the namespaces where a helm release is installed.
+	GetReleaseNamespaces(release string) ([]string, error)
+	// Exec runs a command in a pod container, stdin and stdout can be nil.
+	Exec(namespace string, pod string, container string, stdin io.Reader, stdout io.Writer, stderr io.Writer,
+		command ...string) error
+}
+
+// newAPIClient creates the Kubernetes API client, nil when built without kubernetes support.
+//
+// If kubeconfig is empty, the kubeconfig is looked for like kubectl does.
+var newAPIClient func(kubeconfig string) (apiClient, error)
+
+var (
+	apiMutex   sync.Mutex
+	apiCache   apiClient
+	apiChecked bool
+	// kubeconfigClients caches the clients of the explicitly given kubeconfig files, nil if not answering.
+	kubeconfigClients = map[string]apiClient{}
+)
+
+// setAPIClient replaces the Kubernetes API client and returns a function restoring the previous one.
+//
+// A nil client forces the use of kubectl.
+func setAPIClient(client apiClient) func() {
+	apiMutex.Lock()
+	defer apiMutex.Unlock()
+	previous, previousChecked := apiCache, apiChecked
+	apiCache, apiChecked = client, true
+	return func() {
+		apiMutex.Lock()
+		defer apiMutex.Unlock()
+		apiCache, apiChecked = previous, previousChecked
+	}
+}
+
+// api returns the client of the Kubernetes API or nil if kubectl has to be used.
+//
+// The API is only used when the commands run on the local host and the cluster of the kubeconfig answers.
+func api() apiClient {
+	if _, isLocal := utils.CurrentExecutor().(utils.HostExecutor); !isLocal || newAPIClient == nil {
+		return nil
+	}
+
+	apiMutex.Lock()
+	defer apiMutex.Unlock()
+	if !apiChecked {
+		apiChecked = true
+		client, err := newAPIClient("")
+		if err != nil {
+			log.Debug().Err(err).Msg("Kubernetes API not available, using kubectl")
+		} else {
+			apiCache = client
+		}
+	}
+	return apiCache
+}
+
+// kubeconfigAPI returns the client of the Kubernetes API for the cluster of a kubeconfig file
+// or nil if kubectl or helm have to be used.
+//
+// An empty kubeconfig designates the default one, like for api().
+func kubeconfigAPI(kubeconfig string) apiClient {
+	if kubeconfig == "" {
+		return api()
+	}
+	if _, isLocal := utils.CurrentExecutor().(utils.HostExecutor); !isLocal || newAPIClient == nil {
+		return nil
+	}
+
+	apiMutex.Lock()
+	defer apiMutex.Unlock()
+	client, checked := kubeconfigClients[kubeconfig]
+	if !checked {
+		var err error
+		client, err = newAPIClient(kubeconfig)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Kubernetes API not available with %s, using helm", kubeconfig)
+			client = nil
+		}
+		kubeconfigClients[kubeconfig] = client
+	}
+	return client
+}
+
+// helmReleasesAPI returns the client of the Kubernetes API to read the helm releases from
+// or nil if helm has to be used.
+//
+// The API can only be used if the releases are stored in the cluster with the secrets or config maps
+// helm storage drivers.
+func helmReleasesAPI(kubeconfig string) apiClient {
+	switch os.Getenv("HELM_DRIVER") {
+	case "", "secret", "secrets", "configmap", "configmaps":
+		return kubeconfigAPI(kubeconfig)
+	}
+	return nil
+}
+
+// HasAPIClient returns whether the Kubernetes API server is used instead of kubectl.
+func HasAPIClient() bool {
+	return api() != nil
+}
+
+// filterSelector converts a kubectl filter parameter like -lapp=uyuni into a label selector.
+//
+// A filter which is not a label one is a pod name and is returned as name.
+func filterSelector(filter string) (selector string, name string) {
+	if value, found := strings.CutPrefix(filter, "-l"); found {
+		return value, ""
+	}
+	if value, found := strings.CutPrefix(filter, "--selector="); found {
+		return value, ""
+	}
+	return "", filter
+}
+
+// FindPods lists the pods matching a kubectl filter like -lapp=uyuni in all the namespaces
+// using the Kubernetes API server.
+func FindPods(filter string) ([]Pod, error) {
+	client := api()
+	if client == nil {
+		return nil, fmt.Errorf(L("Kubernetes API server not available"))
+	}
+	return findPods(client, "", filter)
+}
+
+// findPods lists the pods matching a kubectl filter, in all the namespaces if namespace is empty.
+//
+// Pods designated by their name are searched in the namespace of the kubeconfig context if none is given.
+func findPods(client apiClient, namespace string, filter string) ([]Pod, error) {
+	selector, name := filterSelector(filter)
+	if name == "" {
+		return client.GetPods(namespace, selector)
+	}
+	if namespace == "" {
+		namespace = client.Namespace()
+	}
+	pod, err := client.GetPod(namespace, name)
+	if err != nil {
+		return nil, err
+	} else if pod == nil {
+		return []Pod{}, nil
+	}
+	return []Pod{*pod}, nil
+}
+
+// FindRunningPod returns a running pod matching a kubectl filter like -lapp=uyuni in all the namespaces
+// using the Kubernetes API server.
+//
+// Ready pods are preferred to the ones still starting or terminating.
+func FindRunningPod(filter string) (*Pod, error) {
+	pods, err := FindPods(filter)
+	if err != nil {
+		return nil, err
+	}
+	var running *Pod
+	for i, pod := range pods {
+		if pod.Phase != "Running" {
+			continue
+		}
+		if pod.Ready {
+			return &pods[i], nil
+		}
+		if running == nil {
+			running = &pods[i]
+		}
+	}
+	if running == nil {
+		return nil, fmt.Errorf(L("no running pod matching %[1]s on %[2]s"), filter, api())
+	}
+	return running, nil
+}
+
+// ExecInPod runs a command in a pod container through the Kubernetes API server.
+//
+// stdin can be nil if the command doesn't need any input and the error output is written to stderr.
+func ExecInPod(pod *Pod, container string, stdin io.Reader, stdout io.Writer, stderr io.Writer,
+	command ...string) error {
+	client := api()
+	if client == nil {
+		return fmt.Errorf(L("Kubernetes API server not available"))
+	}
+	log.Debug().Msgf("Running in pod %s/%s: %s", pod.Namespace, pod.Name, strings.Join(command, " "))
+	return client.Exec(pod.Namespace, pod.Name, container, stdin, stdout, stderr, command...)
+}
+
+// GetReleaseNamespace returns the namespace where a helm release is installed.
+func GetReleaseNamespace(release string, kubeconfig string) (string, error) {
+	var namespaces []string
+	var err error
+	if client := helmReleasesAPI(kubeconfig); client != nil {
+		namespaces, err = client.GetReleaseNamespaces(release)
+	} else {
+		namespaces, err = helmReleaseNamespaces(release, kubeconfig)
+	}
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to detect %s's namespace"), release)
+	}
+
+	if len(namespaces) == 1 {
+		return namespaces[0], nil
+	}
+	return "", fmt.Errorf(L("found no or more than one %s deployment"), release)
+}
@@ -6,8 +6,8 @@ package kubernetes
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -86,7 +86,11 @@ func HelmUninstall(namespace string, kubeconfig string, deployment string, dryRu
 
 // HasHelmRelease returns whether a helm release is installed or not, even if it failed.
 func HasHelmRelease(release string, kubeconfig string) bool {
-	if _, err := exec.LookPath("helm"); err == nil {
+	if client := helmReleasesAPI(kubeconfig); client != nil {
+		namespaces, err := client.GetReleaseNamespaces(release)
+		return len(namespaces) != 0 && err == nil
+	}
+	if utils.IsInstalled("helm") {
 		args := []string{}
 		if kubeconfig != "" {
 			args = append(args, "--kubeconfig", kubeconfig)
@@ -97,3 +101,37 @@ func HasHelmRelease(release string, kubeconfig string) bool {
 	}
 	return false
 }
+
+// helmReleaseNamespaces returns the namespaces where a helm release is installed using helm.
+func helmReleaseNamespaces(release string, kubeconfig string) ([]string, error) {
+	args := []string{}
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	args = append(args, "list", "-aA", "-f", release, "-o", "json")
+
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "helm", args...)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to list the helm releases"))
+	}
+
+	var data []helmRelease
+	if err = json.Unmarshal(out, &data); err != nil {
+		return nil, utils.Errorf(err, L("helm provided an invalid JSON output"))
+	}
+
+	// The helm filter is a regular expression matching other releases like uyuni-proxy for uyuni
+	namespaces := []string{}
+	for _, item := range data {
+		if item.Name == release {
+			namespaces = append(namespaces, item.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// helmRelease is a release in the helm list JSON output.
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
@@ -7,7 +7,6 @@ package kubernetes
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"time"
 
@@ -53,10 +52,9 @@ func UninstallK3sTraefikConfig(dryRun bool) {
 
 // InspectKubernetes check values on a given image and deploy.
 func InspectKubernetes(serverImage string, pullPolicy string) (*utils.ServerInspectData, error) {
-	for _, binary := range []string{"kubectl", "helm"} {
-		if _, err := exec.LookPath(binary); err != nil {
-			return nil, fmt.Errorf(L("install %s before running this command"), binary)
-		}
+	// The pod is run through the Kubernetes API server if available: kubectl is only needed otherwise
+	if !HasAPIClient() && !utils.IsInstalled("kubectl") {
+		return nil, fmt.Errorf(L("install %s before running this command"), "kubectl")
 	}
 
 	scriptDir, err := os.MkdirTemp("", "mgradm-*")
@@ -75,12 +73,12 @@ func InspectKubernetes(serverImage string, pullPolicy string) (*utils.ServerInsp
 	const podName = "inspector"
 
 	//delete pending pod and then check the node, because in presence of more than a pod GetNode return is wrong
-	if err := DeletePod(podName, ServerFilter); err != nil {
+	if err := DeletePod("", podName, ServerFilter); err != nil {
 		return nil, utils.Errorf(err, L("cannot delete %s"), podName)
 	}
 
 	//this is needed because folder with script needs to be mounted
-	nodeName, err := GetNode("uyuni")
+	nodeName, err := GetNode("", "uyuni")
 	if err != nil {
 		return nil, utils.Errorf(err, L("cannot find node running uyuni"))
 	}
@@ -47,15 +47,21 @@ func (infos ClusterInfos) GetKubeconfig() string {
 
 // CheckCluster return cluster information.
 func CheckCluster() (*ClusterInfos, error) {
+	var infos ClusterInfos
+	var err error
 	// Get the kubelet version
-	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "node",
-		"-o", "jsonpath={.items[0].status.nodeInfo.kubeletVersion}")
+	if client := api(); client != nil {
+		infos.KubeletVersion, err = client.GetKubeletVersion()
+	} else {
+		var out []byte
+		out, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "node",
+			"-o", "jsonpath={.items[0].status.nodeInfo.kubeletVersion}")
+		infos.KubeletVersion = string(out)
+	}
 	if err != nil {
 		return nil, utils.Errorf(err, L("failed to get kubelet version"))
 	}
 
-	var infos ClusterInfos
-	infos.KubeletVersion = string(out)
 	infos.Ingress, err = guessIngress()
 	if err != nil {
 		return nil, err
@@ -65,6 +71,10 @@ func CheckCluster() (*ClusterInfos, error) {
 }
 
 func guessIngress() (string, error) {
+	if client := api(); client != nil {
+		return guessIngressAPI(client)
+	}
+
 	// Check for a traefik resource
 	err := utils.RunCmd("kubectl", "explain", "ingressroutetcp")
 	if err == nil {
@@ -80,7 +90,6 @@ func guessIngress() (string, error) {
 		return "", utils.Errorf(err, L("failed to get pod commands to look for nginx controller"))
 	}
 
-	const nginxController = "/nginx-ingress-controller"
 	if strings.Contains(string(out), nginxController) {
 		return "nginx", nil
 	}
@@ -88,6 +97,28 @@ func guessIngress() (string, error) {
 	return "", nil
 }
 
+const nginxController = "/nginx-ingress-controller"
+
+func guessIngressAPI(client apiClient) (string, error) {
+	hasTraefik, err := client.HasResource("IngressRouteTCP")
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to look for traefik resources"))
+	}
+	if hasTraefik {
+		return "traefik", nil
+	}
+	log.Debug().Msg("No ingressroutetcp resource deployed")
+
+	hasNginx, err := client.HasContainerCommand(nginxController)
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to get pod commands to look for nginx controller"))
+	}
+	if hasNginx {
+		return "nginx", nil
+	}
+	return "", nil
+}
+
 // Restart restarts the pod.
 func Restart(app string) error {
 	if err := Stop(app); err != nil {
@@ -99,8 +130,8 @@ func Restart(app string) error {
 // Start starts the pod.
 func Start(app string) error {
 	// if something is running, we don't need to set replicas to 1
-	if _, err := GetNode("-lapp=" + app); err != nil {
-		return ReplicasTo(app, 1)
+	if _, err := GetNode("", "-lapp="+app); err != nil {
+		return ReplicasTo("", app, 1)
 	}
 	log.Debug().Msgf("Already running")
 	return nil
@@ -108,40 +139,40 @@ func Start(app string) error {
 
 // Stop stop the pod.
 func Stop(app string) error {
-	return ReplicasTo(app, 0)
+	return ReplicasTo("", app, 0)
 }
 
-func get(component string, componentName string, args ...string) ([]byte, error) {
-	kubectlArgs := []string{
-		"get",
-		component,
-		componentName,
-	}
-
-	kubectlArgs = append(kubectlArgs, args...)
-
-	output, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", kubectlArgs...)
-	if err != nil {
-		return []byte{}, err
+// GetConfigMap returns the value of a config map key.
+func GetConfigMap(configMapName string, key string) (string, error) {
+	if client := api(); client != nil {
+		value, err := client.GetConfigMapValue("", configMapName, key)
+		if err != nil {
+			return "", utils.Errorf(err, L("failed to get %[1]s from config map %[2]s"), key, configMapName)
+		}
+		return value, nil
 	}
-	return output, nil
-}
 
-// GetConfigMap returns the value of a given config map.
-func GetConfigMap(configMapName string, filter string) (string, error) {
-	out, err := get("configMap", configMapName, filter)
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "configMap", configMapName, dataJsonPath(key))
 	if err != nil {
-		return "", utils.Errorf(err, L("failed to run kubectl get configMap %[1]s %[2]s"), configMapName, filter)
+		return "", utils.Errorf(err, L("failed to get %[1]s from config map %[2]s"), key, configMapName)
 	}
 
 	return string(out), nil
 }
 
-// GetSecret returns the value of a given secret.
-func GetSecret(secretName string, filter string) (string, error) {
-	out, err := get("secret", secretName, filter)
+// GetSecret returns the decoded value of a secret key.
+func GetSecret(secretName string, key string) (string, error) {
+	if client := api(); client != nil {
+		value, err := client.GetSecretValue("", secretName, key)
+		if err != nil {
+			return "", utils.Errorf(err, L("failed to get %[1]s from secret %[2]s"), key, secretName)
+		}
+		return string(value), nil
+	}
+
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "secret", secretName, dataJsonPath(key))
 	if err != nil {
-		return "", utils.Errorf(err, L("failed to run kubectl get secret %[1]s %[2]s"), secretName, filter)
+		return "", utils.Errorf(err, L("failed to get %[1]s from secret %[2]s"), key, secretName)
 	}
 	decoded, err := base64.StdEncoding.DecodeString(string(out))
 	if err != nil {
@@ -150,3 +181,8 @@ func GetSecret(secretName string, filter string) (string, error) {
 
 	return string(decoded), nil
 }
+
+// dataJsonPath returns the kubectl output parameter to get the value of a config map or secret key.
+func dataJsonPath(key string) string {
+	return "-o=jsonpath={.data." + strings.ReplaceAll(key, ".", "\\.") + "}"
+}
@@ -43,6 +43,13 @@ func RunSupportConfigOnKubernetesHost(dir string) ([]string, error) {
 	return files, nil
 }
 func fetchNamespace(app string) (string, error) {
+	// Like kubectl, the deployment is searched in the namespace of the kubeconfig context
+	if client := api(); client != nil {
+		if _, err := client.GetDeploymentReplicas("", app); err != nil {
+			return "", utils.Errorf(err, L("cannot fetch namespace"))
+		}
+		return client.Namespace(), nil
+	}
 	//kubectl get deployment uyuni-proxy -o jsonpath='{.metadata.namespace}'
 	namespace, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "deployment", app, "-o=jsonpath={.metadata.namespace}")
 	if err != nil {
@@ -57,7 +64,12 @@ func fetchConfigMap(dir string, namespace string) (string, error) {
 		return "", utils.Errorf(err, L("cannot create %s"), configmapFile.Name())
 	}
 	defer configmapFile.Close()
-	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "configmap", "-o", "yaml", "--namespace", namespace)
+	var out []byte
+	if client := api(); client != nil {
+		out, err = client.GetConfigMapsYaml(namespace)
+	} else {
+		out, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "configmap", "-o", "yaml", "--namespace", namespace)
+	}
 	if err != nil {
 		return "", utils.Errorf(err, L("cannot fetch configmap"))
 	}
@@ -83,7 +95,12 @@ func fetchPodYaml(dir string, namespace string) ([]string, error) {
 			continue
 		}
 		defer podFile.Close()
-		out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "pod", pod, "-o", "yaml", "--namespace", namespace)
+		var out []byte
+		if client := api(); client != nil {
+			out, err = client.GetPodYaml(namespace, pod)
+		} else {
+			out, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "get", "pod", pod, "-o", "yaml", "--namespace", namespace)
+		}
 		if err != nil {
 			log.Warn().Msgf(L("failed to fetch info for pod %s"), podFile.Name())
 			continue
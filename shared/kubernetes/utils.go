@@ -147,26 +147,59 @@ func GetDeploymentStatus(namespace string, name string) (*DeploymentStatus, erro
 	return &status, nil
 }
 
+// GetReplicas returns the number of replicas the deployment of an app is scaled to.
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func GetReplicas(namespace string, app string) (uint, error) {
+	if client := api(); client != nil {
+		replicas, err := client.GetDeploymentReplicas(namespace, app)
+		if err != nil {
+			return 0, utils.Errorf(err, L("cannot get the replicas of %s"), app)
+		}
+		return replicas, nil
+	}
+
+	args := withNamespace([]string{"get", "deploy", app, "-o", "jsonpath={.spec.replicas}"}, namespace)
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", args...)
+	if err != nil {
+		return 0, utils.Errorf(err, L("cannot get the replicas of %s"), app)
+	}
+	replicas, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 32)
+	if err != nil {
+		return 0, utils.Errorf(err, L("cannot get the replicas of %s"), app)
+	}
+	return uint(replicas), nil
+}
+
 // ReplicasTo set the replica for an app to the given value.
 // Scale the number of replicas of the server.
-func ReplicasTo(app string, replica uint) error {
-	args := []string{"scale", "deploy", app, "--replicas"}
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func ReplicasTo(namespace string, app string, replica uint) error {
 	log.Debug().Msgf("Setting replicas for pod in %s to %d", app, replica)
-	args = append(args, fmt.Sprint(replica))
+	if client := api(); client != nil {
+		if err := client.ScaleDeployment(namespace, app, replica); err != nil {
+			return utils.Errorf(err, L("cannot scale %[1]s to %[2]d replicas"), app, replica)
+		}
+	} else {
+		args := []string{"scale", "deploy", app, "--replicas"}
+		args = append(args, fmt.Sprint(replica))
+		args = withNamespace(args, namespace)
 
-	_, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", args...)
-	if err != nil {
-		return utils.Errorf(err, L("cannot run kubectl %s"), args)
+		_, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", args...)
+		if err != nil {
+			return utils.Errorf(err, L("cannot run kubectl %s"), args)
+		}
 	}
 
-	pods, err := GetPods("-lapp=" + app)
+	pods, err := getPods(namespace, "-lapp="+app)
 	if err != nil {
 		return utils.Errorf(err, L("cannot get pods for %s"), app)
 	}
 
 	for _, pod := range pods {
 		if len(pod) > 0 {
-			err = waitForReplica(pod, replica)
+			err = waitForReplica(namespace, pod, replica)
 			if err != nil {
 				return utils.Errorf(err, L("replica to %d failed"), replica)
 			}
@@ -178,8 +211,8 @@ func ReplicasTo(app string, replica uint) error {
 	return err
 }
 
-func isPodRunning(podname string, filter string) (bool, error) {
-	pods, err := GetPods(filter)
+func isPodRunning(namespace string, podname string, filter string) (bool, error) {
+	pods, err := getPods(namespace, filter)
 	if err != nil {
 		return false, utils.Errorf(err, L("cannot check if pod %[1]s is running in app %[2]s"), podname, filter)
 	}
@@ -188,8 +221,30 @@ func isPodRunning(podname string, filter string) (bool, error) {
 
 // GetPods return the list of the pod given a filter.
 func GetPods(filter string) (pods []string, err error) {
+	return getPods("", filter)
+}
+
+// getPods returns the list of the pods of a namespace given a filter.
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func getPods(namespace string, filter string) (pods []string, err error) {
 	log.Debug().Msgf("Checking all pods for %s", filter)
-	cmdArgs := []string{"get", "pods", filter, "--output=custom-columns=:.metadata.name", "--no-headers"}
+	if client := api(); client != nil {
+		if namespace == "" {
+			namespace = client.Namespace()
+		}
+		found, err := findPods(client, namespace, filter)
+		if err != nil {
+			return pods, utils.Errorf(err, L("cannot get pods matching %s"), filter)
+		}
+		for _, pod := range found {
+			pods = append(pods, pod.Name)
+		}
+		log.Debug().Msgf("Pods in %s are %s", filter, pods)
+		return pods, nil
+	}
+	cmdArgs := withNamespace([]string{"get", "pods", filter, "--output=custom-columns=:.metadata.name", "--no-headers"},
+		namespace)
 	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
 	if err != nil {
 		return pods, utils.Errorf(err, L("cannot execute %s"), strings.Join(cmdArgs, string(" ")))
@@ -203,9 +258,15 @@ func GetPods(filter string) (pods []string, err error) {
 	return pods, err
 }
 
-func waitForReplicaZero(podname string) error {
+func waitForReplicaZero(namespace string, podname string) error {
 	waitSeconds := 120
-	cmdArgs := []string{"get", "pod", podname}
+	if client := api(); client != nil {
+		if err := waitForPodDeletion(client, namespace, podname, waitSeconds); err != nil {
+			return utils.Errorf(err, L("cannot set replicas for %s to zero"), podname)
+		}
+		return nil
+	}
+	cmdArgs := withNamespace([]string{"get", "pod", podname}, namespace)
 
 	for i := 0; i < waitSeconds; i++ {
 		out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
@@ -225,30 +286,69 @@ func waitForReplicaZero(podname string) error {
 	return fmt.Errorf(L("cannot set replicas for %s to zero"), podname)
 }
 
-func waitForReplica(podname string, replica uint) error {
+func waitForReplica(namespace string, podname string, replica uint) error {
 	waitSeconds := 120
 	log.Debug().Msgf("Checking replica for %s ready to %d", podname, replica)
 	if replica == 0 {
-		return waitForReplicaZero(podname)
+		return waitForReplicaZero(namespace, podname)
 	}
-	cmdArgs := []string{"get", "pod", podname, "--output=custom-columns=STATUS:.status.phase", "--no-headers"}
+	cmdArgs := withNamespace([]string{"get", "pod", podname, "--output=custom-columns=STATUS:.status.phase", "--no-headers"},
+		namespace)
 
 	for i := 0; i < waitSeconds; i++ {
-		out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
+		phase, err := getPodPhase(namespace, podname, cmdArgs)
 		if err != nil {
-			return utils.Errorf(err, L("cannot execute %s"), strings.Join(cmdArgs, string(" ")))
+			return err
 		}
-		outStr := strings.TrimSuffix(string(out), "\n")
-		if string(outStr) == "Running" {
+		if phase == "Running" {
 			log.Debug().Msgf("%s pod replica is now %d", podname, replica)
 			break
 		}
-		log.Debug().Msgf("Pod %s replica is %s in %d seconds.", podname, string(out), i)
+		log.Debug().Msgf("Pod %s replica is %s in %d seconds.", podname, phase, i)
 		time.Sleep(1 * time.Second)
 	}
 	return nil
 }
 
+// getPodPhase returns the phase of a pod like Running, using kubectl with cmdArgs without the API server.
+func getPodPhase(namespace string, podname string, cmdArgs []string) (string, error) {
+	if client := api(); client != nil {
+		if namespace == "" {
+			namespace = client.Namespace()
+		}
+		pod, err := client.GetPod(namespace, podname)
+		if err != nil {
+			return "", utils.Errorf(err, L("cannot get pod informations %s"), podname)
+		} else if pod == nil {
+			return "", fmt.Errorf(L("pod %s not found"), podname)
+		}
+		return pod.Phase, nil
+	}
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
+	if err != nil {
+		return "", utils.Errorf(err, L("cannot execute %s"), strings.Join(cmdArgs, string(" ")))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// waitForPodDeletion waits at most waitSeconds for a pod to be removed from the cluster.
+func waitForPodDeletion(client apiClient, namespace string, podname string, waitSeconds int) error {
+	if namespace == "" {
+		namespace = client.Namespace()
+	}
+	for i := 0; i < waitSeconds; i++ {
+		pod, err := client.GetPod(namespace, podname)
+		if err != nil {
+			return err
+		} else if pod == nil {
+			log.Debug().Msgf("Pod %s has been deleted", podname)
+			return nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return fmt.Errorf(L("pod %[1]s is still not removed after %[2]d seconds"), podname, waitSeconds)
+}
+
 func addNamespace(args []string, namespace string) []string {
 	if namespace != "" {
 		args = append(args, "-n", namespace)
@@ -258,6 +358,73 @@ func addNamespace(args []string, namespace string) []string {
 	return args
 }
 
+// withNamespace adds the namespace parameter to kubectl arguments if namespace is not empty.
+func withNamespace(args []string, namespace string) []string {
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	return args
+}
+
+// pvcList maps the parts of the kubectl get pvc output we need.
+type pvcList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			StorageClassName string `json:"storageClassName"`
+			VolumeName       string `json:"volumeName"`
+			Resources        struct {
+				Requests map[string]string `json:"requests"`
+			} `json:"resources"`
+		} `json:"spec"`
+		Status struct {
+			Phase    string            `json:"phase"`
+			Capacity map[string]string `json:"capacity"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// GetPersistentVolumeClaims lists the persistent volume claims of a namespace.
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func GetPersistentVolumeClaims(namespace string) ([]PersistentVolumeClaim, error) {
+	if client := api(); client != nil {
+		claims, err := client.GetPersistentVolumeClaims(namespace)
+		if err != nil {
+			return nil, utils.Errorf(err, L("failed to get the persistent volume claims"))
+		}
+		return claims, nil
+	}
+
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", withNamespace([]string{"get", "pvc", "-o", "json"},
+		namespace)...)
+	if err != nil {
+		return nil, utils.Errorf(err, L("failed to get the persistent volume claims"))
+	}
+	return parsePersistentVolumeClaims(out)
+}
+
+func parsePersistentVolumeClaims(out []byte) ([]PersistentVolumeClaim, error) {
+	var pvcs pvcList
+	if err := json.Unmarshal(out, &pvcs); err != nil {
+		return nil, utils.Errorf(err, L("failed to parse the persistent volume claims"))
+	}
+	claims := make([]PersistentVolumeClaim, len(pvcs.Items))
+	for i, pvc := range pvcs.Items {
+		claims[i] = PersistentVolumeClaim{
+			Name:         pvc.Metadata.Name,
+			StorageClass: pvc.Spec.StorageClassName,
+			Volume:       pvc.Spec.VolumeName,
+			Requested:    pvc.Spec.Resources.Requests["storage"],
+			Capacity:     pvc.Status.Capacity["storage"],
+			Phase:        pvc.Status.Phase,
+		}
+	}
+	return claims, nil
+}
+
 // GetPullPolicy return pullpolicy in lower case, if exists.
 func GetPullPolicy(name string) string {
 	policies := map[string]string{
@@ -274,36 +441,52 @@ func GetPullPolicy(name string) string {
 
 // RunPod runs a pod, waiting for its execution and deleting it.
 func RunPod(podname string, filter string, image string, pullPolicy string, command string, override ...string) error {
-	arguments := []string{"run", podname, "--image", image, "--image-pull-policy", pullPolicy, filter}
-
-	if len(override) > 0 {
-		arguments = append(arguments, `--override-type=strategic`)
-		for _, arg := range override {
-			overrideParam := "--overrides=" + arg
-			arguments = append(arguments, overrideParam)
-		}
+	if err := createPod("", podname, filter, image, pullPolicy, override, command); err != nil {
+		return err
 	}
-
-	arguments = append(arguments, "--command", "--", command)
-	err := utils.RunCmdStdMapping(zerolog.DebugLevel, "kubectl", arguments...)
-	if err != nil {
-		return utils.Errorf(err, PL("The first placeholder is a command",
-			"cannot run %[1]s using image %[2]s"), command, image)
-	}
-	err = waitForPod(podname)
+	err := waitForPod(podname)
 	if err != nil {
 		return utils.Errorf(err, L("deleting pod %s. Status fails with error"), podname)
 	}
 
 	defer func() {
-		err = DeletePod(podname, filter)
+		err = DeletePod("", podname, filter)
 	}()
 	return nil
 }
 
+// RunPodOutput runs a pod like RunPod and returns the output of its command.
+func RunPodOutput(podname string, filter string, image string, pullPolicy string, command ...string) ([]byte, error) {
+	if err := createPod("", podname, filter, image, pullPolicy, []string{}, command...); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := DeletePod("", podname, filter); err != nil {
+			log.Warn().Err(err).Send()
+		}
+	}()
+	if err := waitForPod(podname); err != nil {
+		return nil, utils.Errorf(err, L("deleting pod %s. Status fails with error"), podname)
+	}
+
+	var out []byte
+	var err error
+	if client := api(); client != nil {
+		out, err = client.GetPodLogs("", podname)
+	} else {
+		out, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", "logs", podname)
+	}
+	if err != nil {
+		return nil, utils.Errorf(err, L("cannot get the logs of pod %s"), podname)
+	}
+	return out, nil
+}
+
 // Delete a kubernetes pod named podname.
-func DeletePod(podname string, filter string) error {
-	isRunning, err := isPodRunning(podname, filter)
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func DeletePod(namespace string, podname string, filter string) error {
+	isRunning, err := isPodRunning(namespace, podname, filter)
 	if err != nil {
 		return utils.Errorf(err, L("cannot delete pod %s"), podname)
 	}
@@ -311,7 +494,17 @@ func DeletePod(podname string, filter string) error {
 		log.Debug().Msgf("no need to delete pod %s because is not running", podname)
 		return nil
 	}
-	arguments := []string{"delete", "pod", podname}
+	// Like kubectl delete, wait for the pod to be removed to allow recreating it
+	if client := api(); client != nil {
+		if err := client.DeletePod(namespace, podname); err != nil {
+			return utils.Errorf(err, L("cannot delete pod %s"), podname)
+		}
+		if err := waitForPodDeletion(client, namespace, podname, 120); err != nil {
+			return utils.Errorf(err, L("cannot delete pod %s"), podname)
+		}
+		return nil
+	}
+	arguments := withNamespace([]string{"delete", "pod", podname}, namespace)
 	_, err = utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", arguments...)
 	if err != nil {
 		return utils.Errorf(err, L("cannot delete pod %s"), podname)
@@ -319,17 +512,71 @@ func DeletePod(podname string, filter string) error {
 	return nil
 }
 
+// StartPod runs a pod like RunPod and waits for it to be running.
+//
+// Unlike RunPod, the pod keeps running after this call and needs to be removed using DeletePod:
+// this is needed to execute commands in the pod.
+// If namespace is empty, the one of the kubeconfig context is used.
+func StartPod(namespace string, podname string, filter string, image string, pullPolicy string, override string,
+	command ...string) error {
+	overrides := []string{}
+	if override != "" {
+		overrides = append(overrides, override)
+	}
+	if err := createPod(namespace, podname, filter, image, pullPolicy, overrides, command...); err != nil {
+		return err
+	}
+	return waitForPodStatus(namespace, podname, "Running")
+}
+
+// createPod creates a pod running command with the overrides applied to its definition.
+func createPod(namespace string, podname string, filter string, image string, pullPolicy string, overrides []string,
+	command ...string) error {
+	if client := api(); client != nil {
+		selector, _ := filterSelector(filter)
+		log.Debug().Msgf("Creating pod %[1]s running %[2]s", podname, strings.Join(command, " "))
+		if err := client.CreatePod(namespace, podname, selector, image, pullPolicy, overrides, command); err != nil {
+			return utils.Errorf(err, PL("The first placeholder is a command",
+				"cannot run %[1]s using image %[2]s"), strings.Join(command, " "), image)
+		}
+		return nil
+	}
+
+	arguments := withNamespace([]string{"run", podname, "--image", image, "--image-pull-policy", pullPolicy, filter},
+		namespace)
+
+	if len(overrides) > 0 {
+		arguments = append(arguments, `--override-type=strategic`)
+		for _, arg := range overrides {
+			overrideParam := "--overrides=" + arg
+			arguments = append(arguments, overrideParam)
+		}
+	}
+
+	arguments = append(arguments, "--command", "--")
+	arguments = append(arguments, command...)
+	err := utils.RunCmdStdMapping(zerolog.DebugLevel, "kubectl", arguments...)
+	if err != nil {
+		return utils.Errorf(err, PL("The first placeholder is a command",
+			"cannot run %[1]s using image %[2]s"), strings.Join(command, " "), image)
+	}
+	return nil
+}
+
 func waitForPod(podname string) error {
-	status := "Succeeded"
+	return waitForPodStatus("", podname, "Succeeded")
+}
+
+func waitForPodStatus(namespace string, podname string, status string) error {
 	waitSeconds := 120
 	log.Debug().Msgf("Checking status for %s pod. Waiting %s seconds until status is %s", podname, strconv.Itoa(waitSeconds), status)
-	cmdArgs := []string{"get", "pod", podname, "--output=custom-columns=STATUS:.status.phase", "--no-headers"}
+	cmdArgs := withNamespace([]string{"get", "pod", podname, "--output=custom-columns=STATUS:.status.phase", "--no-headers"},
+		namespace)
 	var err error
 	for i := 0; i < waitSeconds; i++ {
-		out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
-		outStr := strings.TrimSuffix(string(out), "\n")
+		outStr, err := getPodPhase(namespace, podname, cmdArgs)
 		if err != nil {
-			return utils.Errorf(err, L("cannot execute %s"), strings.Join(cmdArgs, string(" ")))
+			return err
 		}
 		if strings.EqualFold(outStr, status) {
 			log.Debug().Msgf("%s pod status is %s", podname, status)
@@ -345,13 +592,14 @@ func waitForPod(podname string) error {
 }
 
 // GetNode return the node where the app is running.
-func GetNode(filter string) (string, error) {
+//
+// If namespace is empty, the one of the kubeconfig context is used.
+func GetNode(namespace string, filter string) (string, error) {
 	nodeName := ""
-	cmdArgs := []string{"get", "pod", filter, "-o", "jsonpath={.items[*].spec.nodeName}"}
 	for i := 0; i < 60; i++ {
-		out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", cmdArgs...)
+		out, err := getNodeNames(namespace, filter)
 		if err == nil {
-			nodeName = string(out)
+			nodeName = out
 			break
 		}
 	}
@@ -363,6 +611,27 @@ func GetNode(filter string) (string, error) {
 	return nodeName, nil
 }
 
+// getNodeNames returns the space separated names of the nodes running the pods matching the filter.
+func getNodeNames(namespace string, filter string) (string, error) {
+	if client := api(); client != nil {
+		if namespace == "" {
+			namespace = client.Namespace()
+		}
+		pods, err := findPods(client, namespace, filter)
+		if err != nil {
+			return "", err
+		}
+		names := []string{}
+		for _, pod := range pods {
+			names = append(names, pod.NodeName)
+		}
+		return strings.Join(names, " "), nil
+	}
+	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "kubectl", withNamespace([]string{"get", "pod", filter,
+		"-o", "jsonpath={.items[*].spec.nodeName}"}, namespace)...)
+	return string(out), err
+}
+
 // GenerateOverrideDeployment generate a JSON files represents the deployment information.
 func GenerateOverrideDeployment(deployData types.Deployment) (string, error) {
 	ret, err := json.Marshal(deployData)
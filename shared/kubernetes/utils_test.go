@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestParsePersistentVolumeClaims(t *testing.T) {
+	out := `{"items": [
+  {
+    "metadata": {"name": "var-pgsql"},
+    "spec": {
+      "storageClassName": "local-path",
+      "volumeName": "pvc-1234",
+      "resources": {"requests": {"storage": "50Gi"}}
+    },
+    "status": {"phase": "Bound", "capacity": {"storage": "50Gi"}}
+  },
+  {"metadata": {"name": "other"}}
+]}`
+
+	claims, err := parsePersistentVolumeClaims([]byte(out))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong number of claims", 2, len(claims))
+	test_utils.AssertEquals(t, "wrong claims", PersistentVolumeClaim{
+		Name:         "var-pgsql",
+		StorageClass: "local-path",
+		Volume:       "pvc-1234",
+		Requested:    "50Gi",
+		Capacity:     "50Gi",
+		Phase:        "Bound",
+	}, claims[0])
+	test_utils.AssertEquals(t, "wrong claim name", "other", claims[1].Name)
+}
+
+func TestGetReplicas(t *testing.T) {
+	fake := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(fake))
+	fake.
+		Expect("0", nil, "kubectl", "get", "deploy", "uyuni", "-o", "jsonpath={.spec.replicas}", "-n", "uyuni").
+		Expect("", test_utils.ErrCommandFailed, "kubectl", "get", "deploy", "uyuni", "-o", "jsonpath={.spec.replicas}")
+
+	replicas, err := GetReplicas("uyuni", ServerApp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong replicas", uint(0), replicas)
+
+	if _, err := GetReplicas("", ServerApp); err == nil {
+		t.Error("Expected an error for a missing deployment")
+	}
+}
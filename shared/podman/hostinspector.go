@@ -5,8 +5,6 @@
 package podman
 
 import (
-	"os"
-
 	"github.com/rs/zerolog"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/types"
@@ -31,7 +29,7 @@ func NewHostInspector(scriptDir string) HostInspector {
 
 			types.NewInspectData(
 				"has_uyuni_server",
-				"systemctl list-unit-files uyuni-server.service >/dev/null && echo true || echo false"),
+				"systemctl list-unit-files "+ServerService+".service >/dev/null && echo true || echo false"),
 		},
 		ScriptDir: scriptDir,
 	}
@@ -54,11 +52,11 @@ func (i *HostInspector) ReadInspectData() (*HostInspectData, error) {
 
 // InspectHost gathers data on the host where to install the server or proxy.
 func InspectHost() (*HostInspectData, error) {
-	scriptDir, err := os.MkdirTemp("", "mgradm-*")
-	defer os.RemoveAll(scriptDir)
+	scriptDir, err := utils.MkdirHostTemp("mgradm-*")
 	if err != nil {
 		return nil, utils.Errorf(err, L("failed to create temporary directory"))
 	}
+	defer utils.RemoveHostAll(scriptDir)
 
 	inspector := NewHostInspector(scriptDir)
 	if err := inspector.GenerateScript(); err != nil {
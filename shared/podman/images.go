@@ -7,10 +7,10 @@ package podman
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/url"
-	"os"
+	"os/exec"
 	"path"
 	"regexp"
 	"strings"
@@ -113,25 +113,19 @@ func GetRpmImagePath(image string) string {
 
 	rpmImageFile, tag := GetRpmImageName(image)
 
-	files, err := os.ReadDir(rpmImageDir)
+	files, err := utils.ListHostDir(rpmImageDir)
 	if err != nil {
 		log.Debug().Err(err).Msgf("Cannot read directory %s", rpmImageDir)
 		return ""
 	}
 
 	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), "metadata") {
+		if !strings.HasSuffix(file, "metadata") {
 			continue
 		}
-		fullPathFileName := path.Join(rpmImageDir, file.Name())
+		fullPathFileName := path.Join(rpmImageDir, file)
 		log.Debug().Msgf("Parsing metadata file %s", fullPathFileName)
-		fileHandler, err := os.Open(fullPathFileName)
-		if err != nil {
-			log.Debug().Err(err).Msgf("Error opening metadata file %s", fullPathFileName)
-			continue
-		}
-		defer fileHandler.Close()
-		byteValue, err := io.ReadAll(fileHandler)
+		byteValue, err := utils.ReadHostFile(fullPathFileName)
 		if err != nil {
 			log.Debug().Err(err).Msgf("Error reading metadata file %s", fullPathFileName)
 			continue
@@ -153,6 +147,13 @@ func GetRpmImagePath(image string) string {
 }
 
 func loadRpmImage(rpmImageBasePath string) (string, error) {
+	if client := libpod(); client != nil {
+		loadedImage, err := client.LoadImage(rpmImageBasePath)
+		if err == nil {
+			return loadedImage, nil
+		}
+		log.Warn().Err(err).Msgf(L("failed to load %s with the podman API, using the podman command"), rpmImageBasePath)
+	}
 	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", "load", "--quiet", "--input", rpmImageBasePath)
 	if err != nil {
 		return "", err
@@ -167,6 +168,13 @@ func loadRpmImage(rpmImageBasePath string) (string, error) {
 // IsImagePresent return true if the image is present.
 func IsImagePresent(image string) (string, error) {
 	log.Debug().Msgf("Checking for %s", image)
+	if client := libpod(); client != nil {
+		presentImage, err := isImagePresentAPI(client, image)
+		if err == nil {
+			return presentImage, nil
+		}
+		log.Warn().Err(err).Msgf(L("failed to check image %s with the podman API, using the podman command"), image)
+	}
 	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", "images", "--format={{ .Repository }}", image)
 	if err != nil {
 		return "", fmt.Errorf(L("failed to check if image %s has already been pulled"), image)
@@ -192,6 +200,59 @@ func IsImagePresent(image string) (string, error) {
 	return "", nil
 }
 
+// isImagePresentAPI checks if the image or its localhost variant is present using the podman API.
+func isImagePresentAPI(client *LibpodClient, image string) (string, error) {
+	candidates := []string{image}
+	if splitImage := strings.SplitN(image, "/", 2); len(splitImage) == 2 {
+		candidates = append(candidates, "localhost/"+splitImage[1])
+	}
+	for _, candidate := range candidates {
+		exists, err := client.ImageExists(candidate)
+		if err != nil {
+			return "", utils.Errorf(err, L("failed to check if image %s has already been pulled"), image)
+		}
+		if exists {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// IsImageAvailable returns whether the image is present locally or can be found in its registry.
+//
+// The manifest of the exact image reference is inspected, without pulling the image.
+func IsImageAvailable(authFile string, image string) (bool, error) {
+	if localImage, err := IsImagePresent(image); err != nil {
+		return false, err
+	} else if localImage != "" {
+		return true, nil
+	}
+
+	args := []string{"manifest", "inspect"}
+	if authFile != "" {
+		args = append(args, "--authfile", authFile)
+	}
+	args = append(args, image)
+	if _, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", args...); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && isMissingManifest(string(exitErr.Stderr)) {
+			return false, nil
+		}
+		return false, utils.Errorf(err, L("cannot inspect the manifest of image %s"), image)
+	}
+	return true, nil
+}
+
+// isMissingManifest returns whether the error output of podman manifest inspect reports an image not found.
+func isMissingManifest(stderr string) bool {
+	for _, message := range []string{"manifest unknown", "name unknown", "not found"} {
+		if strings.Contains(strings.ToLower(stderr), message) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetPulledImageName returns the fullname of a pulled image.
 func GetPulledImageName(image string) (string, error) {
 	parts := strings.Split(image, "/")
@@ -207,6 +268,15 @@ func pullImage(authFile string, image string) error {
 	if utils.ContainsUpperCase(image) {
 		return fmt.Errorf(L("%s should contains just lower case character, otherwise podman pull would fails"), image)
 	}
+	if client := libpod(); client != nil {
+		log.Info().Msgf(L("Pulling image %s"), image)
+		err := client.PullImage(image, authFile)
+		if err == nil {
+			return nil
+		}
+		log.Warn().Err(err).Msgf(L("failed to pull image %s with the podman API, using the podman command"), image)
+	}
+
 	log.Info().Msgf(L("Running podman pull %s"), image)
 	podmanArgs := []string{"pull", image}
 
@@ -235,6 +305,17 @@ func ShowAvailableTag(registry string, image types.ImageFlags) error {
 
 // GetRunningImage given a container name, return the image name.
 func GetRunningImage(container string) (string, error) {
+	if client := libpod(); client != nil {
+		info, err := client.InspectContainer(container)
+		if err == nil {
+			if !info.State.Running {
+				return "", nil
+			}
+			return info.ImageName, nil
+		}
+		log.Warn().Err(err).Msgf(L("failed to inspect container %s with the podman API, using the podman command"), container)
+	}
+
 	log.Info().Msgf(L("Running podman ps --filter=name=%s --format={{ .Image }}"), container)
 
 	out, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", "ps", fmt.Sprintf("--filter=name=%s", container), "--format='{{ .Image }}'")
@@ -5,7 +5,11 @@
 package podman
 
 import (
+	"os/exec"
 	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
 )
 
 func TestGetRpmImageName(t *testing.T) {
@@ -77,3 +81,33 @@ func TestMatchingMetadata(t *testing.T) {
 		t.Error("typo in json: this should fail")
 	}
 }
+
+func TestIsImageAvailable(t *testing.T) {
+	// Get a real exit error with the output of podman for a missing image
+	_, missingErr := exec.Command("sh", "-c", "echo 'Error: reading manifest 1.0: manifest unknown' >&2; exit 125").Output()
+
+	executor := test_utils.NewFakeExecutor(t).Install("podman")
+	executor.
+		Stub("", nil, "podman", "images", "--format={{ .Repository }}", "registry.lab/uyuni/server:1.0").
+		Stub("", nil, "podman", "images", "--quiet", "localhost/uyuni/server:1.0").
+		Expect("{}", nil, "podman", "manifest", "inspect", "--authfile", "/auth.json", "registry.lab/uyuni/server:1.0").
+		Expect("", missingErr, "podman", "manifest", "inspect", "registry.lab/uyuni/server:1.0").
+		Expect("", test_utils.ErrCommandFailed, "podman", "manifest", "inspect", "registry.lab/uyuni/server:1.0")
+	t.Cleanup(utils.SetExecutor(executor))
+
+	found, err := IsImageAvailable("/auth.json", "registry.lab/uyuni/server:1.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "image not found", found)
+
+	found, err = IsImageAvailable("", "registry.lab/uyuni/server:1.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertTrue(t, "missing image found", !found)
+
+	if _, err := IsImageAvailable("", "registry.lab/uyuni/server:1.0"); err == nil {
+		t.Error("Expected an error when the registry can't be queried")
+	}
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+const defaultNamePrefix = "uyuni"
+
+// instanceConfFile is the systemd configuration file of the server service storing the instance settings.
+const instanceConfFile = "instance.conf"
+
+const portsOffsetPrefix = "Environment=UYUNI_PORTS_OFFSET="
+
+var instanceNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// stateFolder is the folder storing the state of the tools for the default instance.
+var stateFolder = "/var/lib/uyuni-tools"
+
+// instance is the name of the server instance to work on, empty for the default one.
+var instance string
+
+// NamePrefix is the prefix of the server containers, systemd services and network names.
+var NamePrefix = defaultNamePrefix
+
+var (
+	defaultServerVolumeMounts    = utils.ServerVolumeMounts
+	defaultHubXmlrpcVolumeMounts = utils.HubXmlrpcVolumeMounts
+)
+
+// AddInstanceFlag adds the --instance flag to a root command.
+func AddInstanceFlag(cmd *cobra.Command, globalFlags *types.GlobalFlags) {
+	cmd.PersistentFlags().StringVar(&globalFlags.Instance, "instance", "",
+		L("name of the server instance to work on, to run several servers on a single podman host"))
+}
+
+// Instance returns the name of the server instance, empty for the default one.
+func Instance() string {
+	return instance
+}
+
+// SetInstance namespaces the server containers, systemd services, network and volumes for a named instance
+// and returns a function restoring the previous instance.
+//
+// The containers, services and network names are prefixed with uyuni-<name> instead of uyuni
+// and the volumes names with <name>-. An empty name selects the default instance.
+func SetInstance(name string) (func(), error) {
+	if name != "" && !instanceNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf(L("invalid instance name %s: only lowercase letters, digits and dashes are allowed"), name)
+	}
+	previous := instance
+	applyInstance(name)
+	return func() {
+		applyInstance(previous)
+	}, nil
+}
+
+func applyInstance(name string) {
+	instance = name
+	NamePrefix = defaultNamePrefix
+	if name != "" {
+		NamePrefix = defaultNamePrefix + "-" + name
+	}
+
+	ServerContainerName = NamePrefix + "-server"
+	HubXmlrpcContainerName = NamePrefix + "-hub-xmlrpc"
+	ServerService = NamePrefix + "-server"
+	ServerAttestationService = NamePrefix + "-server-attestation"
+	HubXmlrpcService = NamePrefix + "-hub-xmlrpc"
+	UyuniNetwork = NamePrefix
+
+	utils.ServerVolumeMounts = instanceVolumeMounts(defaultServerVolumeMounts)
+	utils.HubXmlrpcVolumeMounts = instanceVolumeMounts(defaultHubXmlrpcVolumeMounts)
+}
+
+// InstanceVolume returns the name of a server volume for the current instance.
+func InstanceVolume(name string) string {
+	if instance == "" {
+		return name
+	}
+	return instance + "-" + name
+}
+
+// InstanceStateFolder returns the folder storing the state of the tools for the current instance,
+// like the deployments history or the data of an interrupted migration.
+func InstanceStateFolder() string {
+	if instance == "" {
+		return stateFolder
+	}
+	return path.Join(stateFolder, "instances", instance)
+}
+
+// SetStateFolder changes the folder storing the state of the tools and returns a function restoring it.
+//
+// This is meant to store the state in a temporary folder in tests.
+func SetStateFolder(folder string) func() {
+	previous := stateFolder
+	stateFolder = folder
+	return func() {
+		stateFolder = previous
+	}
+}
+
+func instanceVolumeMounts(volumes []types.VolumeMount) []types.VolumeMount {
+	if instance == "" {
+		return volumes
+	}
+	mounts := make([]types.VolumeMount, len(volumes))
+	for i, volume := range volumes {
+		mounts[i] = volume
+		mounts[i].Name = InstanceVolume(volume.Name)
+	}
+	return mounts
+}
+
+// SetPortsOffset stores the offset added to the exposed ports of the server instance.
+//
+// The offset is kept in a configuration file of the server service to be reused by the later commands.
+// ports are the ports to expose, used to check that the offset keeps them valid.
+func SetPortsOffset(offset int, ports []types.PortMap) error {
+	if offset == 0 {
+		return nil
+	}
+	for _, port := range ports {
+		if offset < 0 || port.Exposed+offset > 65535 {
+			return fmt.Errorf(L("invalid ports offset %[1]d: port %[2]d cannot be exposed"), offset, port.Exposed)
+		}
+	}
+	return GenerateSystemdConfFile(ServerService, instanceConfFile, portsOffsetPrefix+strconv.Itoa(offset), true)
+}
+
+// GetPortsOffset returns the offset added to the exposed ports by the server instance.
+func GetPortsOffset() int {
+	confPath := path.Join(GetServiceConfFolder(ServerService), instanceConfFile)
+	if !utils.FileExists(confPath) {
+		return 0
+	}
+	for _, line := range strings.Split(string(utils.ReadFile(confPath)), "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), portsOffsetPrefix); found {
+			if offset, err := strconv.Atoi(value); err == nil {
+				return offset
+			}
+		}
+	}
+	return 0
+}
+
+// OffsetPorts returns the ports with the offset of the server instance added to the exposed ones.
+func OffsetPorts(ports []types.PortMap) []types.PortMap {
+	offset := GetPortsOffset()
+	if offset == 0 {
+		return ports
+	}
+	result := make([]types.PortMap, len(ports))
+	for i, port := range ports {
+		result[i] = port
+		result[i].Exposed = port.Exposed + offset
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/types"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+func TestSetInstance(t *testing.T) {
+	restore, err := SetInstance("staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	test_utils.AssertEquals(t, "wrong container name", "uyuni-staging-server", ServerContainerName)
+	test_utils.AssertEquals(t, "wrong hub container name", "uyuni-staging-hub-xmlrpc", HubXmlrpcContainerName)
+	test_utils.AssertEquals(t, "wrong service name", "uyuni-staging-server", ServerService)
+	test_utils.AssertEquals(t, "wrong attestation service name",
+		"uyuni-staging-server-attestation", ServerAttestationService)
+	test_utils.AssertEquals(t, "wrong hub service name", "uyuni-staging-hub-xmlrpc", HubXmlrpcService)
+	test_utils.AssertEquals(t, "wrong network name", "uyuni-staging", UyuniNetwork)
+	test_utils.AssertEquals(t, "wrong volume name", "staging-var-pgsql", InstanceVolume("var-pgsql"))
+	for _, volume := range utils.ServerVolumeMounts {
+		test_utils.AssertTrue(t, "volume not namespaced: "+volume.Name, strings.HasPrefix(volume.Name, "staging-"))
+	}
+	test_utils.AssertTrue(t, "cgroup volume not namespaced",
+		utils.Contains(GetCommonParams(), "staging-cgroup:/sys/fs/cgroup:rw"))
+
+	restore()
+	test_utils.AssertEquals(t, "container name not restored", "uyuni-server", ServerContainerName)
+	test_utils.AssertEquals(t, "network name not restored", "uyuni", UyuniNetwork)
+	test_utils.AssertEquals(t, "volume not restored", "var-pgsql", InstanceVolume("var-pgsql"))
+	test_utils.AssertTrue(t, "cgroup volume not restored",
+		utils.Contains(GetCommonParams(), "cgroup:/sys/fs/cgroup:rw"))
+
+	for _, name := range []string{"Staging", "-test", "a_b", "test-"} {
+		if _, err := SetInstance(name); err == nil {
+			t.Errorf("Expected an error for instance name %s", name)
+		}
+	}
+	test_utils.AssertEquals(t, "invalid instance applied", "uyuni-server", ServerContainerName)
+}
+
+func TestPortsOffset(t *testing.T) {
+	t.Cleanup(SetServicesFolder(t.TempDir()))
+	restore, err := SetInstance("test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	t.Cleanup(restore)
+
+	ports := []types.PortMap{utils.NewPortMap("https", 443, 443), utils.NewPortMap("cobbler", 25151, 25151)}
+	test_utils.AssertEquals(t, "no offset expected", 443, OffsetPorts(ports)[0].Exposed)
+
+	if err := SetPortsOffset(50000, ports); err == nil {
+		t.Error("Expected an error for ports out of range")
+	}
+	if err := SetPortsOffset(10000, ports); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong offset", 10000, GetPortsOffset())
+
+	shifted := OffsetPorts(ports)
+	test_utils.AssertEquals(t, "wrong exposed port", 10443, shifted[0].Exposed)
+	test_utils.AssertEquals(t, "container port changed", 443, shifted[0].Port)
+	test_utils.AssertEquals(t, "original ports changed", 443, ports[0].Exposed)
+}
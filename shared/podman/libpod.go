@@ -0,0 +1,636 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// libpodAPIRoot is the root path of the libpod REST API. Podman accepts any version in the path.
+const libpodAPIRoot = "http://d/v4.0.0/libpod/"
+
+// podmanSocket is the path of the podman API socket enabled by EnablePodmanSocket.
+var podmanSocket = "/run/podman/podman.sock"
+
+var (
+	libpodMutex   sync.Mutex
+	libpodClient  *LibpodClient
+	libpodChecked bool
+)
+
+// LibpodClient calls the podman libpod REST API on a unix socket.
+type LibpodClient struct {
+	client     *http.Client
+	socketPath string
+}
+
+// LibpodVolume is the inspection data of a volume.
+type LibpodVolume struct {
+	Name       string
+	Mountpoint string
+}
+
+// LibpodContainer is the inspection data of a container.
+type LibpodContainer struct {
+	Name      string
+	Image     string
+	ImageName string
+	State     struct {
+		Running bool
+		Status  string
+	}
+}
+
+// libpodPullReport is one of the JSON objects streamed while pulling an image.
+type libpodPullReport struct {
+	Stream string   `json:"stream"`
+	Error  string   `json:"error"`
+	Images []string `json:"images"`
+}
+
+// libpodError is the body of the libpod API errors.
+type libpodError struct {
+	Cause    string `json:"cause"`
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+// libpodFailure wraps the podman API errors happening before an operation has started.
+type libpodFailure struct {
+	err error
+}
+
+func (f *libpodFailure) Error() string {
+	return f.err.Error()
+}
+
+func (f *libpodFailure) Unwrap() error {
+	return f.err
+}
+
+// IsLibpodFailure returns whether err is a podman API error happening before the operation has started.
+//
+// Nothing has been done in that case: the operation can safely be run with the podman command instead.
+func IsLibpodFailure(err error) bool {
+	var failure *libpodFailure
+	return errors.As(err, &failure)
+}
+
+// NewLibpodClient creates a client for the libpod API served on a unix socket.
+func NewLibpodClient(socketPath string) *LibpodClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &LibpodClient{client: &http.Client{Transport: transport}, socketPath: socketPath}
+}
+
+// SetPodmanSocket changes the podman API socket to use and returns a function restoring the previous one.
+//
+// An empty path disables the API and all the podman operations use the command line.
+func SetPodmanSocket(socketPath string) func() {
+	libpodMutex.Lock()
+	defer libpodMutex.Unlock()
+	previous := podmanSocket
+	podmanSocket = socketPath
+	libpodChecked = false
+	return func() {
+		libpodMutex.Lock()
+		defer libpodMutex.Unlock()
+		podmanSocket = previous
+		libpodChecked = false
+	}
+}
+
+// libpod returns the client of the podman API or nil if the podman command line has to be used.
+//
+// The API is only used when the commands run on the local host and the socket is answering.
+// The CONTAINER_HOST variable can point to another unix socket.
+func libpod() *LibpodClient {
+	if _, isLocal := utils.CurrentExecutor().(utils.HostExecutor); !isLocal {
+		return nil
+	}
+
+	libpodMutex.Lock()
+	defer libpodMutex.Unlock()
+	if libpodChecked {
+		return libpodClient
+	}
+	libpodChecked = true
+	libpodClient = nil
+
+	socketPath := podmanSocket
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		socketPath = strings.TrimPrefix(host, "unix://")
+	}
+	if socketPath == "" || !utils.FileExists(socketPath) {
+		return nil
+	}
+	client := NewLibpodClient(socketPath)
+	if err := client.Ping(); err != nil {
+		log.Debug().Err(err).Msgf("podman API not available on %s, using the podman command", socketPath)
+		return nil
+	}
+	libpodClient = client
+	return libpodClient
+}
+
+// Ping checks that the podman API is answering.
+func (c *LibpodClient) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, libpodAPIRoot+"_ping", nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf(L("podman API replied with code %d"), res.StatusCode)
+	}
+	return nil
+}
+
+func (c *LibpodClient) call(
+	method string,
+	path string,
+	query url.Values,
+	header http.Header,
+	body io.Reader,
+) (*http.Response, error) {
+	target := libpodAPIRoot + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	log.Trace().Msgf("podman API: %s %s", method, target)
+	return c.client.Do(req)
+}
+
+// exists calls one of the libpod exists endpoints.
+func (c *LibpodClient) exists(path string) (bool, error) {
+	res, err := c.call(http.MethodGet, path, nil, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, readLibpodError(res)
+}
+
+// inspect calls one of the libpod json endpoints and decodes the result in value.
+func (c *LibpodClient) inspect(path string, value interface{}) error {
+	res, err := c.call(http.MethodGet, path, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return readLibpodError(res)
+	}
+	if err := json.NewDecoder(res.Body).Decode(value); err != nil {
+		return utils.Errorf(err, L("failed to parse the podman API response"))
+	}
+	return nil
+}
+
+func readLibpodError(res *http.Response) error {
+	var apiErr libpodError
+	if err := json.NewDecoder(res.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+		return fmt.Errorf(L("podman API replied with code %d"), res.StatusCode)
+	}
+	return errors.New(apiErr.Message)
+}
+
+// VolumeExists returns whether a volume exists.
+func (c *LibpodClient) VolumeExists(name string) (bool, error) {
+	return c.exists("volumes/" + url.PathEscape(name) + "/exists")
+}
+
+// InspectVolume returns the inspection data of a volume.
+func (c *LibpodClient) InspectVolume(name string) (*LibpodVolume, error) {
+	var volume LibpodVolume
+	if err := c.inspect("volumes/"+url.PathEscape(name)+"/json", &volume); err != nil {
+		return nil, err
+	}
+	return &volume, nil
+}
+
+// ImageExists returns whether an image is available locally.
+func (c *LibpodClient) ImageExists(name string) (bool, error) {
+	return c.exists("images/" + url.PathEscape(name) + "/exists")
+}
+
+// NetworkExists returns whether a network exists.
+func (c *LibpodClient) NetworkExists(name string) (bool, error) {
+	return c.exists("networks/" + url.PathEscape(name) + "/exists")
+}
+
+// InspectContainer returns the inspection data of a container.
+func (c *LibpodClient) InspectContainer(name string) (*LibpodContainer, error) {
+	var container LibpodContainer
+	if err := c.inspect("containers/"+url.PathEscape(name)+"/json", &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// LoadImage loads an image archive and returns the name of the loaded image.
+func (c *LibpodClient) LoadImage(archivePath string) (string, error) {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to open %s"), archivePath)
+	}
+	defer archive.Close()
+
+	header := http.Header{"Content-Type": []string{"application/x-tar"}}
+	res, err := c.call(http.MethodPost, "images/load", nil, header, archive)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", readLibpodError(res)
+	}
+
+	var report struct {
+		Names []string
+	}
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		return "", utils.Errorf(err, L("failed to parse the podman API response"))
+	}
+	if len(report.Names) == 0 {
+		return "", fmt.Errorf(L("no image loaded from %s"), archivePath)
+	}
+	return report.Names[0], nil
+}
+
+// PullImage pulls an image, logging the progress of the pull.
+//
+// authFile is the path to a registry authentication file like the one written by podman login, or empty.
+func (c *LibpodClient) PullImage(image string, authFile string) error {
+	header := http.Header{}
+	if authFile != "" {
+		auth, err := registryAuthHeader(authFile)
+		if err != nil {
+			return err
+		}
+		header.Set("X-Registry-Auth", auth)
+	}
+
+	query := url.Values{"reference": []string{image}, "policy": []string{"always"}}
+	res, err := c.call(http.MethodPost, "images/pull", query, header, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return readLibpodError(res)
+	}
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var report libpodPullReport
+		if err := decoder.Decode(&report); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return utils.Errorf(err, L("failed to parse the podman API response"))
+		}
+		if report.Error != "" {
+			return fmt.Errorf(L("failed to pull image %[1]s: %[2]s"), image, report.Error)
+		}
+		if progress := strings.TrimSpace(report.Stream); progress != "" {
+			log.Info().Msg(progress)
+		}
+	}
+}
+
+// registryAuthHeader converts a podman login authentication file into the X-Registry-Auth header value.
+func registryAuthHeader(authFile string) (string, error) {
+	var content struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	data, err := os.ReadFile(authFile)
+	if err != nil {
+		return "", utils.Errorf(err, L("failed to read %s"), authFile)
+	}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return "", utils.Errorf(err, L("failed to parse the registry authentication file %s"), authFile)
+	}
+
+	type registryAuth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	auths := map[string]registryAuth{}
+	for registry, auth := range content.Auths {
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", utils.Errorf(err, L("invalid credentials for %s in the registry authentication file"), registry)
+		}
+		user, password, _ := strings.Cut(string(decoded), ":")
+		auths[registry] = registryAuth{Username: user, Password: password}
+	}
+	header, err := json.Marshal(auths)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(header), nil
+}
+
+// libpodExecInspect is the inspection data of an exec session.
+type libpodExecInspect struct {
+	Running  bool
+	ExitCode int
+}
+
+// Exec runs a command in a running container and waits for it to finish.
+//
+// stdin can be nil if the command doesn't need any input.
+// An error is returned if the command exits with a non zero code.
+func (c *LibpodClient) Exec(
+	container string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command ...string,
+) error {
+	config, err := json.Marshal(map[string]interface{}{
+		"AttachStdin":  stdin != nil,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          command,
+	})
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	res, err := c.call(http.MethodPost, "containers/"+url.PathEscape(container)+"/exec", nil, header,
+		bytes.NewReader(config))
+	if err != nil {
+		return &libpodFailure{err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		return &libpodFailure{readLibpodError(res)}
+	}
+	var session struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&session); err != nil {
+		return utils.Errorf(err, L("failed to parse the podman API response"))
+	}
+
+	if err := c.attachExec(session.ID, stdin, stdout, stderr); err != nil {
+		return err
+	}
+
+	// The session may still be running for a short time once its streams are closed
+	var inspect libpodExecInspect
+	for i := 0; i < 50; i++ {
+		if err := c.inspect("exec/"+url.PathEscape(session.ID)+"/json", &inspect); err != nil {
+			return err
+		}
+		if !inspect.Running {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if inspect.Running {
+		return fmt.Errorf(L("%s is still running after its output has been closed"), command[0])
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf(L("%[1]s exited with code %[2]d"), command[0], inspect.ExitCode)
+	}
+	return nil
+}
+
+// attachExec starts an exec session and streams its input and outputs.
+//
+// The API hijacks the connection: the raw connection is used to send the standard input
+// after the request and to read the multiplexed outputs once the response headers are received.
+func (c *LibpodClient) attachExec(id string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return &libpodFailure{err}
+	}
+	defer conn.Close()
+
+	body := strings.NewReader(`{"Detach": false, "Tty": false}`)
+	req, err := http.NewRequest(http.MethodPost, libpodAPIRoot+"exec/"+url.PathEscape(id)+"/start", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	log.Trace().Msgf("podman API: %s %s", req.Method, req.URL)
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusSwitchingProtocols {
+		defer res.Body.Close()
+		return readLibpodError(res)
+	}
+
+	if stdin != nil {
+		go func() {
+			if _, err := io.Copy(conn, stdin); err != nil {
+				log.Debug().Err(err).Msg("failed to send the standard input to the podman API")
+			}
+			// Closing the write side sends the end of the input to the command
+			if unixConn, ok := conn.(*net.UnixConn); ok {
+				_ = unixConn.CloseWrite()
+			}
+		}()
+	}
+	return demuxStreams(reader, stdout, stderr)
+}
+
+// demuxStreams splits the multiplexed output of an attached session into stdout and stderr.
+//
+// Each frame starts with a header: the stream type, 3 zero bytes and the big endian size of the payload.
+// The writers can be nil to ignore the corresponding output.
+func demuxStreams(reader io.Reader, stdout io.Writer, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return utils.Errorf(err, L("failed to read the podman API stream"))
+		}
+
+		var target io.Writer
+		switch header[0] {
+		case 1:
+			target = stdout
+		case 2:
+			target = stderr
+		}
+		if target == nil {
+			target = io.Discard
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(target, reader, size); err != nil {
+			return utils.Errorf(err, L("failed to read the podman API stream"))
+		}
+	}
+}
+
+// CopyToContainer copies a local file to a path in a container.
+func (c *LibpodClient) CopyToContainer(container string, src string, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return utils.Errorf(err, L("failed to open %s"), src)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf(L("only files can be copied to the container: %s"), src)
+	}
+
+	// The archive is extracted in the folder of the destination
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	header := &tar.Header{
+		Name:    path.Base(dst),
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, file); err != nil {
+		return utils.Errorf(err, L("failed to read %s"), src)
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{"path": []string{path.Dir(dst)}}
+	contentType := http.Header{"Content-Type": []string{"application/x-tar"}}
+	res, err := c.call(http.MethodPut, "containers/"+url.PathEscape(container)+"/archive", query, contentType, &archive)
+	if err != nil {
+		return &libpodFailure{err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &libpodFailure{readLibpodError(res)}
+	}
+	return nil
+}
+
+// CopyFromContainer copies a file of a container to a local path.
+func (c *LibpodClient) CopyFromContainer(container string, src string, dst string) error {
+	query := url.Values{"path": []string{src}}
+	res, err := c.call(http.MethodGet, "containers/"+url.PathEscape(container)+"/archive", query, nil, nil)
+	if err != nil {
+		return &libpodFailure{err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &libpodFailure{readLibpodError(res)}
+	}
+
+	reader := tar.NewReader(res.Body)
+	header, err := reader.Next()
+	if err != nil {
+		return &libpodFailure{utils.Errorf(err, L("failed to read the archive of %s"), src)}
+	}
+	if header.Typeflag != tar.TypeReg {
+		return fmt.Errorf(L("only files can be copied from the container: %s"), src)
+	}
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return utils.Errorf(err, L("failed to create %s"), dst)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, reader); err != nil {
+		return utils.Errorf(err, L("failed to write %s"), dst)
+	}
+	return nil
+}
+
+// HasLibpod returns whether the podman API is used instead of the podman command.
+func HasLibpod() bool {
+	return libpod() != nil
+}
+
+// ExecInContainer runs a command in a container through the podman API.
+//
+// stdin can be nil if the command doesn't need any input and the error output is written to stderr.
+func ExecInContainer(container string, stdin io.Reader, stdout io.Writer, stderr io.Writer, command ...string) error {
+	client := libpod()
+	if client == nil {
+		return &libpodFailure{errors.New(L("podman API not available"))}
+	}
+	log.Debug().Msgf("Running in container %s: %s", container, strings.Join(command, " "))
+	return client.Exec(container, stdin, stdout, stderr, command...)
+}
+
+// CopyToContainer copies a local file to a path in a container through the podman API.
+func CopyToContainer(container string, src string, dst string) error {
+	client := libpod()
+	if client == nil {
+		return &libpodFailure{errors.New(L("podman API not available"))}
+	}
+	return client.CopyToContainer(container, src, dst)
+}
+
+// CopyFromContainer copies a file of a container to a local path through the podman API.
+func CopyFromContainer(container string, src string, dst string) error {
+	client := libpod()
+	if client == nil {
+		return &libpodFailure{errors.New(L("podman API not available"))}
+	}
+	return client.CopyFromContainer(container, src, dst)
+}
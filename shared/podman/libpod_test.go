@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package podman
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+	"github.com/uyuni-project/uyuni-tools/shared/utils"
+)
+
+// startFakeLibpod serves the handlers on a unix socket used as podman API socket for the test.
+func startFakeLibpod(t *testing.T, handlers map[string]http.HandlerFunc) {
+	socketPath := path.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v4.0.0/libpod/_ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+	for pattern, handler := range handlers {
+		mux.HandleFunc("/v4.0.0/libpod/"+pattern, handler)
+	}
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	t.Cleanup(SetPodmanSocket(socketPath))
+	t.Setenv("CONTAINER_HOST", "")
+}
+
+// fakePodmanCommand puts a podman command failing and logging its arguments in the PATH.
+//
+// The returned function gives the podman command lines run so far.
+func fakePodmanCommand(t *testing.T) func() string {
+	binDir := t.TempDir()
+	logPath := path.Join(binDir, "podman.log")
+	test_utils.WriteFile(t, path.Join(binDir, "podman"), "#!/bin/sh\necho \"podman $*\" >> "+logPath+"\nexit 125\n")
+	if err := os.Chmod(path.Join(binDir, "podman"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+	return func() string {
+		content, _ := os.ReadFile(logPath)
+		return string(content)
+	}
+}
+
+func TestLibpodVolumes(t *testing.T) {
+	startFakeLibpod(t, map[string]http.HandlerFunc{
+		"volumes/var-pgsql/json": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"Name": "var-pgsql", "Mountpoint": "/var/lib/containers/storage/volumes/var-pgsql/_data"}`))
+		},
+		"volumes/var-pgsql/exists": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"volumes/missing/json": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"cause": "no such volume", "message": "no volume with name \"missing\" found", "response": 404}`))
+		},
+	})
+
+	mountPoint, err := GetVolumeMountPoint("var-pgsql")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong mount point", "/var/lib/containers/storage/volumes/var-pgsql/_data", mountPoint)
+	test_utils.AssertTrue(t, "volume not found", isVolumePresent("var-pgsql"))
+	test_utils.AssertTrue(t, "unexpected volume found", !isVolumePresent("missing"))
+
+	// API errors fall back to the podman command
+	podmanCalls := fakePodmanCommand(t)
+	if _, err := GetVolumeMountPoint("missing"); err == nil {
+		t.Error("Expected an error for a missing volume")
+	}
+	test_utils.AssertEquals(t, "podman command not used", "podman volume inspect --format {{.Mountpoint}} missing\n",
+		podmanCalls())
+}
+
+func TestLibpodImages(t *testing.T) {
+	authFile := path.Join(t.TempDir(), "auth.json")
+	test_utils.WriteFile(t, authFile,
+		`{"auths": {"registry.suse.com": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("user:s3cr:t"))+`"}}}`)
+	archive := path.Join(t.TempDir(), "image.tar")
+	test_utils.WriteFile(t, archive, "archive")
+
+	var loaded string
+	startFakeLibpod(t, map[string]http.HandlerFunc{
+		"images/localhost/server:latest/exists": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"images/registry.opensuse.org/server:latest/exists": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+		"images/load": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			loaded = string(body)
+			_, _ = w.Write([]byte(`{"Names": ["registry.suse.com/suse/manager/5.0/x86_64/server:5.0.0"]}`))
+		},
+		"images/pull": func(w http.ResponseWriter, r *http.Request) {
+			header, _ := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+			var auths map[string]map[string]string
+			if err := json.Unmarshal(header, &auths); err != nil || auths["registry.suse.com"]["password"] != "s3cr:t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message": "unauthorized"}`))
+				return
+			}
+			if r.URL.Query().Get("reference") == "registry.suse.com/missing" {
+				_, _ = w.Write([]byte(`{"stream": "Trying to pull registry.suse.com/missing...\n"}{"error": "manifest unknown"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"stream": "Copying blob 4c0ffee\n"}{"images": ["4c0ffee"], "id": "4c0ffee"}`))
+		},
+	})
+
+	image, err := IsImagePresent("registry.opensuse.org/server:latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong present image", "localhost/server:latest", image)
+
+	image, err = loadRpmImage(archive)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong loaded image", "registry.suse.com/suse/manager/5.0/x86_64/server:5.0.0", image)
+	test_utils.AssertEquals(t, "wrong archive sent", "archive", loaded)
+
+	if err := pullImage(authFile, "registry.suse.com/server"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	// API errors fall back to the podman command
+	podmanCalls := fakePodmanCommand(t)
+	if err := pullImage(authFile, "registry.suse.com/missing"); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+	if err := pullImage("", "registry.suse.com/server"); err == nil {
+		t.Error("Expected an error without credentials")
+	}
+	test_utils.AssertEquals(t, "podman command not used",
+		"podman pull registry.suse.com/missing --authfile "+authFile+"\npodman pull registry.suse.com/server\n",
+		podmanCalls())
+}
+
+func TestLibpodFallback(t *testing.T) {
+	t.Cleanup(SetPodmanSocket(path.Join(t.TempDir(), "missing.sock")))
+	t.Setenv("CONTAINER_HOST", "")
+	executor := test_utils.NewFakeExecutor(t)
+	t.Cleanup(utils.SetExecutor(executor))
+	executor.Expect("/var/lib/volumes/etc-rhn/_data\n", nil,
+		"podman", "volume", "inspect", "--format", "{{.Mountpoint}}", "etc-rhn")
+
+	mountPoint, err := GetVolumeMountPoint("etc-rhn")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong mount point", "/var/lib/volumes/etc-rhn/_data", mountPoint)
+}
+
+func TestLibpodExec(t *testing.T) {
+	exitCode := 0
+	var command []string
+	attachStdin := false
+	startFakeLibpod(t, map[string]http.HandlerFunc{
+		"containers/uyuni-server/exec": func(w http.ResponseWriter, r *http.Request) {
+			var config struct {
+				AttachStdin bool
+				Cmd         []string
+			}
+			_ = json.NewDecoder(r.Body).Decode(&config)
+			command, attachStdin = config.Cmd, config.AttachStdin
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id": "4c0ffee"}`))
+		},
+		"exec/4c0ffee/start": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			conn, buffer, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			_, _ = buffer.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			_ = buffer.Flush()
+
+			// The standard input is sent until the client closes its side of the connection
+			input := []byte{}
+			if attachStdin {
+				input, _ = io.ReadAll(buffer)
+			}
+			writeFrame := func(stream byte, data string) {
+				header := []byte{stream, 0, 0, 0, 0, 0, 0, byte(len(data))}
+				_, _ = conn.Write(append(header, data...))
+			}
+			writeFrame(1, "out:"+string(input))
+			writeFrame(2, "some warning")
+		},
+		"exec/4c0ffee/json": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"Running": false, "ExitCode": %d}`, exitCode)))
+		},
+	})
+
+	var stdout, stderr strings.Builder
+	err := ExecInContainer("uyuni-server", strings.NewReader("input"), &stdout, &stderr, "sh", "-c", "cat")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong command", "sh -c cat", strings.Join(command, " "))
+	test_utils.AssertEquals(t, "wrong output", "out:input", stdout.String())
+	test_utils.AssertEquals(t, "wrong error output", "some warning", stderr.String())
+
+	exitCode = 2
+	stdout.Reset()
+	if err := ExecInContainer("uyuni-server", nil, &stdout, nil, "false"); err == nil || IsLibpodFailure(err) {
+		t.Errorf("Expected an error for a failing command, got %v", err)
+	}
+	if err := ExecInContainer("missing", nil, &stdout, nil, "true"); !IsLibpodFailure(err) {
+		t.Errorf("Expected a podman API failure for a missing container, got %v", err)
+	}
+	test_utils.AssertEquals(t, "wrong output without input", "out:", stdout.String())
+}
+
+func TestLibpodCopy(t *testing.T) {
+	var uploadPath, uploadName, uploadContent string
+	startFakeLibpod(t, map[string]http.HandlerFunc{
+		"containers/uyuni-server/archive": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				uploadPath = r.URL.Query().Get("path")
+				reader := tar.NewReader(r.Body)
+				header, err := reader.Next()
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				content, _ := io.ReadAll(reader)
+				uploadName, uploadContent = header.Name, string(content)
+				return
+			}
+			if r.URL.Query().Get("path") != "/etc/rhn/rhn.conf" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message": "no such file or directory"}`))
+				return
+			}
+			writer := tar.NewWriter(w)
+			content := "db_name = susemanager\n"
+			_ = writer.WriteHeader(&tar.Header{Name: "rhn.conf", Mode: 0644, Size: int64(len(content))})
+			_, _ = writer.Write([]byte(content))
+			_ = writer.Close()
+		},
+	})
+
+	srcPath := path.Join(t.TempDir(), "setup.sh")
+	test_utils.WriteFile(t, srcPath, "#!/bin/sh\n")
+	if err := CopyToContainer("uyuni-server", srcPath, "/tmp/setup.sh"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong destination folder", "/tmp", uploadPath)
+	test_utils.AssertEquals(t, "wrong destination name", "setup.sh", uploadName)
+	test_utils.AssertEquals(t, "wrong copied content", "#!/bin/sh\n", uploadContent)
+
+	dstPath := path.Join(t.TempDir(), "rhn.conf")
+	if err := CopyFromContainer("uyuni-server", "/etc/rhn/rhn.conf", dstPath); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong copied content", "db_name = susemanager\n", test_utils.ReadFile(t, dstPath))
+
+	if err := CopyFromContainer("uyuni-server", "/missing", dstPath); !IsLibpodFailure(err) ||
+		!strings.Contains(err.Error(), "no such file") {
+		t.Errorf("Expected the API error message, got %v", err)
+	}
+}
@@ -7,7 +7,7 @@ package podman
 import (
 	"encoding/base64"
 	"fmt"
-	"os"
+	"path"
 
 	"github.com/rs/zerolog/log"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
@@ -34,24 +34,19 @@ func PodmanLogin(hostData *HostInspectData, scc types.SCCCredentials) (string, f
 		}
 	}
 }`, token)
-		authFile, err := os.CreateTemp("", "mgradm-")
+		authDir, err := utils.MkdirHostTemp("mgradm-*")
 		if err != nil {
-			return "", nil, err
+			return "", nil, utils.Errorf(err, L("failed to create temporary directory"))
 		}
-		authFilePath := authFile.Name()
+		authFilePath := path.Join(authDir, "auth.json")
 
-		if _, err := authFile.Write([]byte(authFileContent)); err != nil {
-			os.Remove(authFilePath)
-			return "", nil, err
-		}
-
-		if err := authFile.Close(); err != nil {
-			os.Remove(authFilePath)
-			return "", nil, utils.Errorf(err, L("failed to close the temporary auth file"))
+		if err := utils.WriteHostFile(authFilePath, []byte(authFileContent), 0600); err != nil {
+			_ = utils.RemoveHostAll(authDir)
+			return "", nil, utils.Errorf(err, L("failed to write the temporary auth file"))
 		}
 
 		return authFilePath, func() {
-			os.Remove(authFilePath)
+			_ = utils.RemoveHostAll(authDir)
 		}, nil
 	}
 
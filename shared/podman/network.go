@@ -5,7 +5,6 @@
 package podman
 
 import (
-	"os/exec"
 	"strings"
 
 	"github.com/rs/zerolog"
@@ -15,7 +14,7 @@ import (
 )
 
 // The name of the podman network for Uyuni and its proxies.
-const UyuniNetwork = "uyuni"
+var UyuniNetwork = "uyuni"
 
 func hasIpv6Enabled(network string) bool {
 	hasIpv6, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", "network", "inspect",
@@ -118,9 +117,13 @@ func DeleteNetwork(dryRun bool) {
 
 // IsNetworkPresent returns whether a network is already present.
 func IsNetworkPresent(network string) bool {
-	cmd := exec.Command("podman", "network", "exists", network)
-	if err := cmd.Run(); err != nil {
-		return false
+	if client := libpod(); client != nil {
+		exists, err := client.NetworkExists(network)
+		if err == nil {
+			return exists
+		}
+		log.Warn().Err(err).Msgf(L("failed to check network %s with the podman API, using the podman command"), network)
 	}
-	return cmd.ProcessState.ExitCode() == 0
+	_, err := utils.RunCmdOutput(zerolog.Disabled, "podman", "network", "exists", network)
+	return err == nil
 }
@@ -8,10 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
 	"github.com/uyuni-project/uyuni-tools/shared/utils"
@@ -20,13 +20,13 @@ import (
 var servicesPath = "/etc/systemd/system/"
 
 // Name of the systemd service for the server.
-const ServerService = "uyuni-server"
+var ServerService = "uyuni-server"
 
 // Name of the systemd service for the coco attestation container.
-const ServerAttestationService = "uyuni-server-attestation"
+var ServerAttestationService = "uyuni-server-attestation"
 
 // Name of the systemd service for the Hub XMLRPC container.
-const HubXmlrpcService = "uyuni-hub-xmlrpc"
+var HubXmlrpcService = "uyuni-hub-xmlrpc"
 
 // Name of the systemd service for the proxy.
 const ProxyService = "uyuni-proxy-pod"
@@ -34,14 +34,14 @@ const ProxyService = "uyuni-proxy-pod"
 // HasService returns if a systemd service is installed.
 // name is the name of the service without the '.service' part.
 func HasService(name string) bool {
-	err := utils.RunCmd("systemctl", "list-unit-files", name+".service")
+	_, err := utils.RunCmdOutput(zerolog.Disabled, "systemctl", "list-unit-files", name+".service")
 	return err == nil
 }
 
 // ServiceIsEnabled returns if a service is enabled
 // name is the name of the service without the '.service' part.
 func ServiceIsEnabled(name string) bool {
-	err := utils.RunCmd("systemctl", "is-enabled", name+".service")
+	_, err := utils.RunCmdOutput(zerolog.Disabled, "systemctl", "is-enabled", name+".service")
 	return err == nil
 }
 
@@ -54,6 +54,22 @@ func DisableService(name string) error {
 	return nil
 }
 
+// SetServicesFolder changes the folder containing the systemd services and returns a function restoring it.
+//
+// This is meant to install or remove services in a temporary folder in tests.
+func SetServicesFolder(folder string) func() {
+	previous := servicesPath
+	servicesPath = folder
+	return func() {
+		servicesPath = previous
+	}
+}
+
+// GetServicesFolder returns the folder containing the systemd services.
+func GetServicesFolder() string {
+	return servicesPath
+}
+
 // GetServicePath return the path for a given service.
 func GetServicePath(name string) string {
 	return path.Join(servicesPath, name+".service")
@@ -69,6 +85,16 @@ func GetServiceConfPath(name string) string {
 	return path.Join(GetServiceConfFolder(name), "generated.conf")
 }
 
+// checkLocalServiceFiles returns an error if the services files are on a remote host.
+//
+// The files are written and removed locally while systemctl would run on the remote host.
+func checkLocalServiceFiles() error {
+	if utils.IsRemoteExecutor() {
+		return errors.New(L("the systemd services files of a remote host cannot be changed"))
+	}
+	return nil
+}
+
 // UninstallService stops and remove a systemd service.
 // If dryRun is set to true, nothing happens but messages are logged to explain what would be done.
 func UninstallService(name string, dryRun bool) {
@@ -90,6 +116,11 @@ func UninstallService(name string, dryRun bool) {
 }
 
 func uninstallServiceFiles(name string, dryRun bool) {
+	if err := checkLocalServiceFiles(); err != nil {
+		log.Error().Err(err).Msgf(L("Failed to remove %s.service file"), name)
+		return
+	}
+
 	servicePath := GetServicePath(name)
 	serviceConfFolder := GetServiceConfFolder(name)
 
@@ -169,11 +200,8 @@ func ReloadDaemon(dryRun bool) error {
 
 // IsServiceRunning returns whether the systemd service is started or not.
 func IsServiceRunning(service string) bool {
-	cmd := exec.Command("systemctl", "is-active", "-q", service)
-	if err := cmd.Run(); err != nil {
-		return false
-	}
-	return cmd.ProcessState.ExitCode() == 0
+	_, err := utils.RunCmdOutput(zerolog.Disabled, "systemctl", "is-active", "-q", service)
+	return err == nil
 }
 
 // RestartService restarts the systemd service.
@@ -249,7 +277,7 @@ func GenerateSystemdConfFile(serviceName string, filename string, body string, w
 	systemdFilePath := GetServicePath(serviceName)
 
 	systemdConfFolder := systemdFilePath + ".d"
-	if err := os.MkdirAll(systemdConfFolder, 0750); err != nil {
+	if err := utils.MkdirHostAll(systemdConfFolder, 0750); err != nil {
 		return utils.Errorf(err, L("failed to create %s folder"), systemdConfFolder)
 	}
 	systemdConfFilePath := path.Join(systemdConfFolder, filename)
@@ -259,13 +287,46 @@ func GenerateSystemdConfFile(serviceName string, filename string, body string, w
 		header = confHeader
 	}
 	content := []byte(fmt.Sprintf("%s[Service]\n%s\n", header, body))
-	if err := os.WriteFile(systemdConfFilePath, content, 0640); err != nil {
+	if err := utils.WriteHostFile(systemdConfFilePath, content, 0640); err != nil {
 		return utils.Errorf(err, L("cannot write %s file"), systemdConfFilePath)
 	}
 
 	return nil
 }
 
+// SetServiceImage changes the image of a service in its generated.conf file.
+//
+// The other lines of the file are kept as is.
+func SetServiceImage(serviceName string, image string) error {
+	const imagePrefix = "Environment=UYUNI_IMAGE="
+	confPath := GetServiceConfPath(serviceName)
+	if !utils.HostFileExists(confPath) {
+		return GenerateSystemdConfFile(serviceName, "generated.conf", imagePrefix+image, true)
+	}
+
+	confContent, err := utils.ReadHostFile(confPath)
+	if err != nil {
+		return utils.Errorf(err, L("failed to read %s"), confPath)
+	}
+	lines := strings.Split(string(confContent), "\n")
+	found := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), imagePrefix) {
+			lines[i] = imagePrefix + image
+			found = true
+		}
+	}
+	content := strings.Join(lines, "\n")
+	if !found {
+		content = strings.TrimRight(content, "\n") + "\n" + imagePrefix + image + "\n"
+	}
+
+	if err := utils.WriteHostFile(confPath, []byte(content), 0640); err != nil {
+		return utils.Errorf(err, L("cannot write %s file"), confPath)
+	}
+	return nil
+}
+
 // CleanSystemdConfFile separates the Service.conf file once generated into generated.conf and custom.conf.
 func CleanSystemdConfFile(serviceName string) error {
 	systemdFilePath := GetServicePath(serviceName) + ".d"
@@ -276,8 +337,12 @@ func CleanSystemdConfFile(serviceName string) error {
 	// If this file exists split it in two:
 	// - generated.conf with the image
 	// - custom.conf with everything that shouldn't be touched at upgrade
-	if utils.FileExists(oldConfPath) {
-		content := string(utils.ReadFile(oldConfPath))
+	if utils.HostFileExists(oldConfPath) {
+		oldContent, err := utils.ReadHostFile(oldConfPath)
+		if err != nil {
+			return utils.Errorf(err, L("failed to read %s"), oldConfPath)
+		}
+		content := string(oldContent)
 		lines := strings.Split(content, "\n")
 
 		generated := ""
@@ -304,12 +369,12 @@ func CleanSystemdConfFile(serviceName string) error {
 
 		if hasCustom {
 			customPath := path.Join(systemdFilePath, "custom.conf")
-			if err := os.WriteFile(customPath, []byte(custom), 0644); err != nil {
+			if err := utils.WriteHostFile(customPath, []byte(custom), 0644); err != nil {
 				return utils.Errorf(err, L("failed to write %s file"), customPath)
 			}
 		}
 
-		if err := os.Remove(oldConfPath); err != nil {
+		if err := utils.RemoveHostFile(oldConfPath); err != nil {
 			return utils.Errorf(err, L("failed to remove old %s systemd service configuration file"), oldConfPath)
 		}
 	}
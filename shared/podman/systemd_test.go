@@ -93,3 +93,62 @@ Environment="PODMAN_EXTRA_ARGS="
 	actual = test_utils.ReadFile(t, path.Join(serviceConfDir, "custom.conf"))
 	test_utils.AssertEquals(t, "invalid custom.conf file", customFile, actual)
 }
+
+func TestSetServiceImage(t *testing.T) {
+	testDir, cleaner := test_utils.CreateTmpFolder(t)
+	defer cleaner()
+	servicesPath = testDir
+
+	// No configuration yet
+	if err := SetServiceImage("uyuni-server", "path/to/image:1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	actual := test_utils.ReadFile(t, GetServiceConfPath("uyuni-server"))
+	test_utils.AssertEquals(t, "invalid created generated.conf", confHeader+`[Service]
+Environment=UYUNI_IMAGE=path/to/image:1
+`, actual)
+
+	// Other environment variables need to be preserved
+	serviceName := "uyuni-server-attestation@"
+	if err := os.MkdirAll(GetServiceConfFolder(serviceName), 0750); err != nil {
+		t.Fatalf("failed to create fake service configuration directory: %s", err)
+	}
+	test_utils.WriteFile(t, GetServiceConfPath(serviceName), `[Service]
+Environment=UYUNI_IMAGE=path/to/coco:1
+Environment=database_user=spacewalk
+`)
+	if err := SetServiceImage(serviceName, "path/to/coco:2"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	actual = test_utils.ReadFile(t, GetServiceConfPath(serviceName))
+	test_utils.AssertEquals(t, "invalid updated generated.conf", `[Service]
+Environment=UYUNI_IMAGE=path/to/coco:2
+Environment=database_user=spacewalk
+`, actual)
+}
+
+func TestRemoteServiceFiles(t *testing.T) {
+	testDir := t.TempDir()
+	defer SetServicesFolder(testDir)()
+
+	fake := test_utils.NewFakeExecutor(t)
+	defer utils.SetExecutor(fake)()
+	confFolder := path.Join(testDir, "uyuni-server.service.d")
+	fake.
+		Expect("", nil, "ssh", "--", "admin@server.lab", "mkdir -p -m 750 -- "+confFolder).
+		Expect("", nil, "ssh", "--", "admin@server.lab",
+			`sh -c 'umask 077 && cat > "$1" && chmod 640 "$1"' sh `+path.Join(confFolder, "generated.conf"))
+
+	restore, err := utils.UseSSHBackend("ssh://admin@server.lab")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer restore()
+
+	if err := GenerateSystemdConfFile("uyuni-server", "generated.conf", "Environment=UYUNI_IMAGE=image", true); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if utils.FileExists(confFolder) {
+		t.Error("the services files of a remote host have been written locally")
+	}
+}
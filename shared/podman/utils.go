@@ -5,10 +5,8 @@
 package podman
 
 import (
-	"errors"
 	"io"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
 
@@ -23,10 +21,10 @@ import (
 const commonArgs = "--rm --cap-add NET_RAW --tmpfs /run -v cgroup:/sys/fs/cgroup:rw"
 
 // ServerContainerName represents the server container name.
-const ServerContainerName = "uyuni-server"
+var ServerContainerName = "uyuni-server"
 
 // HubXmlrpcContainerName is the container name for the Hub XML-RPC API.
-const HubXmlrpcContainerName = "uyuni-hub-xmlrpc"
+var HubXmlrpcContainerName = "uyuni-hub-xmlrpc"
 
 // ProxyContainerNames represents all the proxy container names.
 var ProxyContainerNames = []string{
@@ -39,12 +37,19 @@ var ProxyContainerNames = []string{
 
 // PodmanFlags stores the podman arguments.
 type PodmanFlags struct {
-	Args []string `mapstructure:"arg"`
+	Args        []string `mapstructure:"arg"`
+	PortsOffset int
 }
 
 // GetCommonParams splits the common arguments.
 func GetCommonParams() []string {
-	return strings.Split(commonArgs, " ")
+	params := strings.Split(commonArgs, " ")
+	for i, param := range params {
+		if strings.HasPrefix(param, "cgroup:") {
+			params[i] = InstanceVolume("cgroup") + strings.TrimPrefix(param, "cgroup")
+		}
+	}
+	return params
 }
 
 // AddPodmanArgFlag add the podman arguments to a command.
@@ -52,15 +57,45 @@ func AddPodmanArgFlag(cmd *cobra.Command) {
 	cmd.Flags().StringSlice("podman-arg", []string{}, L("Extra arguments to pass to podman"))
 }
 
+// AddPortsOffsetFlag adds the flag to shift the ports exposed by a server instance to a command.
+func AddPortsOffsetFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("podman-portsOffset", 0,
+		L("offset added to all the ports exposed on the host, to run several server instances on the same host"))
+}
+
 // EnablePodmanSocket enables the podman socket.
+//
+// Once the socket answers, the volumes, images, networks and containers queries, the images pulls and loads
+// and the commands run or files copied in the containers use the podman API served on it.
+// The podman command is still used for interactive sessions and when the commands run on a remote host.
 func EnablePodmanSocket() error {
 	err := utils.RunCmd("systemctl", "enable", "--now", "podman.socket")
 	if err != nil {
 		return utils.Errorf(err, L("failed to enable podman.socket unit"))
 	}
+	libpodMutex.Lock()
+	libpodChecked = false
+	libpodMutex.Unlock()
 	return err
 }
 
+// GetVolumeMountPoint returns the folder where a volume is mounted on the host.
+func GetVolumeMountPoint(name string) (string, error) {
+	if client := libpod(); client != nil {
+		volume, err := client.InspectVolume(name)
+		if err == nil {
+			return volume.Mountpoint, nil
+		}
+		log.Warn().Err(err).Msgf(L("failed to inspect volume %s with the podman API, using the podman command"), name)
+	}
+	args := []string{"volume", "inspect", "--format", "{{.Mountpoint}}", name}
+	mountPoint, err := utils.RunCmdOutput(zerolog.DebugLevel, "podman", args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(mountPoint), "\n"), nil
+}
+
 // RunContainer execute a container.
 func RunContainer(name string, image string, volumes []types.VolumeMount, extraArgs []string, cmd []string) error {
 	podmanArgs := append([]string{"run", "--name", name}, GetCommonParams()...)
@@ -107,11 +142,16 @@ func DeleteContainer(name string, dryRun bool) {
 // GetServiceImage returns the value of the UYUNI_IMAGE variable for a systemd service.
 func GetServiceImage(service string) string {
 	serviceConfPath := GetServiceConfPath(service)
-	if !utils.FileExists(serviceConfPath) {
+	if !utils.HostFileExists(serviceConfPath) {
 		return ""
 	}
 
-	content := string(utils.ReadFile(serviceConfPath))
+	confContent, err := utils.ReadHostFile(serviceConfPath)
+	if err != nil {
+		log.Warn().Err(err).Msgf(L("Failed to read %s"), serviceConfPath)
+		return ""
+	}
+	content := string(confContent)
 	lines := strings.Split(content, "\n")
 	const imagePrefix = "Environment=UYUNI_IMAGE="
 	for _, line := range lines {
@@ -142,11 +182,15 @@ func DeleteImage(name string, dryRun bool) error {
 }
 
 func imageExists(volume string) bool {
-	cmd := exec.Command("podman", "image", "exists", volume)
-	if err := cmd.Run(); err != nil {
-		return false
+	if client := libpod(); client != nil {
+		exists, err := client.ImageExists(volume)
+		if err == nil {
+			return exists
+		}
+		log.Warn().Err(err).Msgf(L("failed to check image %s with the podman API, using the podman command"), volume)
 	}
-	return cmd.ProcessState.ExitCode() == 0
+	_, err := utils.RunCmdOutput(zerolog.Disabled, "podman", "image", "exists", volume)
+	return err == nil
 }
 
 // DeleteVolume deletes a podman volume based on its name.
@@ -179,24 +223,39 @@ func DeleteVolume(name string, dryRun bool) error {
 	return nil
 }
 
+// CreateVolume creates a podman volume if it doesn't exist yet.
+func CreateVolume(name string) error {
+	if isVolumePresent(name) {
+		log.Debug().Msgf("Volume %s already exists", name)
+		return nil
+	}
+	if err := utils.RunCmd("podman", "volume", "create", name); err != nil {
+		return utils.Errorf(err, L("failed to create %s volume"), name)
+	}
+	return nil
+}
+
 func isVolumePresent(volume string) bool {
-	var exitError *exec.ExitError
-	cmd := exec.Command("podman", "volume", "exists", volume)
-	if err := cmd.Run(); err != nil && errors.As(err, &exitError) {
+	if client := libpod(); client != nil {
+		exists, err := client.VolumeExists(volume)
+		if err == nil {
+			return exists
+		}
+		log.Warn().Err(err).Msgf(L("failed to check volume %s with the podman API, using the podman command"), volume)
+	}
+	if _, err := utils.RunCmdOutput(zerolog.Disabled, "podman", "volume", "exists", volume); err != nil {
 		log.Debug().Err(err).Msgf("podman volume exists %s", volume)
 		return false
 	}
-	return cmd.ProcessState.Success()
+	return true
 }
 
 func isVolumePathMounted(volume string) bool {
-	cmd := exec.Command("findmnt", "--target", volume)
-	var exitError *exec.ExitError
-	if err := cmd.Run(); err != nil && errors.As(err, &exitError) {
+	if _, err := utils.RunCmdOutput(zerolog.Disabled, "findmnt", "--target", volume); err != nil {
 		log.Debug().Err(err).Msgf("findmnt --target %s", volume)
 		return false
 	}
-	return cmd.ProcessState.Success()
+	return true
 }
 
 func isVolumePathEmpty(volume string) bool {
@@ -211,8 +270,8 @@ func isVolumePathEmpty(volume string) bool {
 }
 
 func getPodmanVolumeBasePath() (string, error) {
-	cmd := exec.Command("podman", "system", "info", "--format={{ .Store.VolumePath }}")
-	out, err := cmd.Output()
+	out, err := utils.RunCmdOutput(zerolog.Disabled, "podman", "system", "info",
+		"--format={{ .Store.VolumePath }}")
 	return strings.TrimSpace(string(out)), err
 }
 
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package test_utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	apiRootPath       = "/rhn/manager/api/"
+	apiSessionCookie  = "pxt-session-cookie"
+	apiSessionMaxAge  = 3600
+	apiLoginPath      = "auth/login"
+	apiLogoutPath     = "auth/logout"
+	apiAuthFailureMsg = "Could not authenticate"
+)
+
+// APICall is a request received by the fake API server.
+type APICall struct {
+	Method string
+	// Path is the API path without the API root, like system/getDetails.
+	Path  string
+	Query url.Values
+	Body  []byte
+}
+
+// Decode unmarshals the JSON body of the call in value.
+func (c *APICall) Decode(value interface{}) error {
+	return json.Unmarshal(c.Body, value)
+}
+
+// APIHandler returns the result of an API call.
+//
+// A returned error is sent as a failed API response, unless it is an APIError.
+type APIHandler func(call *APICall) (interface{}, error)
+
+// APIError is an error sent with an HTTP error status like the server does for unknown or invalid calls.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// APIRawResponse is a handler result sent as is instead of a JSON response, like a downloaded file.
+type APIRawResponse struct {
+	ContentType string
+	Body        []byte
+}
+
+// FakeAPIServer is an in-process server replying like the Uyuni JSON over HTTP API.
+//
+// The auth/login and auth/logout calls manage session cookies and the other calls are only answered
+// for logged in clients, unless registered with HandlePublic.
+type FakeAPIServer struct {
+	*httptest.Server
+	// User and Password are the only valid credentials for the login.
+	User     string
+	Password string
+
+	mutex    sync.Mutex
+	handlers map[string]APIHandler
+	public   map[string]bool
+	sessions map[string]bool
+	calls    []APICall
+}
+
+// NewFakeAPIServer starts a fake API server accepting the user credentials, stopped at the end of the test.
+func NewFakeAPIServer(t *testing.T, user string, password string) *FakeAPIServer {
+	server := &FakeAPIServer{
+		User:     user,
+		Password: password,
+		handlers: map[string]APIHandler{},
+		public:   map[string]bool{},
+		sessions: map[string]bool{},
+	}
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Host returns the host and port of the server to use as API server FQDN with an insecure connection.
+func (s *FakeAPIServer) Host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// Handle registers the handler of an API path requiring a session.
+func (s *FakeAPIServer) Handle(path string, handler APIHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[path] = handler
+	delete(s.public, path)
+}
+
+// HandlePublic registers the handler of an API path not requiring a session, like org/createFirst.
+func (s *FakeAPIServer) HandlePublic(path string, handler APIHandler) {
+	s.Handle(path, handler)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.public[path] = true
+}
+
+// HandleResult registers a handler always returning the same result for an API path requiring a session.
+func (s *FakeAPIServer) HandleResult(path string, result interface{}) {
+	s.Handle(path, func(*APICall) (interface{}, error) {
+		return result, nil
+	})
+}
+
+// Calls returns the calls received for an API path, or all the calls if path is empty.
+func (s *FakeAPIServer) Calls(path string) []APICall {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	calls := []APICall{}
+	for _, call := range s.calls {
+		if path == "" || call.Path == path {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (s *FakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiRootPath) {
+		writeAPIError(w, &APIError{Status: http.StatusNotFound, Message: "Not found"})
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, &APIError{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	call := APICall{
+		Method: r.Method,
+		Path:   strings.TrimPrefix(r.URL.Path, apiRootPath),
+		Query:  r.URL.Query(),
+		Body:   body,
+	}
+
+	s.mutex.Lock()
+	s.calls = append(s.calls, call)
+	handler := s.handlers[call.Path]
+	public := s.public[call.Path]
+	s.mutex.Unlock()
+
+	switch call.Path {
+	case apiLoginPath:
+		s.login(w, &call)
+		return
+	case apiLogoutPath:
+		s.logout(w, r)
+		return
+	}
+
+	if handler == nil {
+		writeAPIError(w, &APIError{Status: http.StatusNotFound, Message: "Unknown API method " + call.Path})
+		return
+	}
+	if !public && !s.loggedIn(r) {
+		writeAPIError(w, &APIError{Status: http.StatusUnauthorized, Message: apiAuthFailureMsg})
+		return
+	}
+
+	result, err := handler(&call)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		writeAPIError(w, apiErr)
+		return
+	}
+	if err != nil {
+		writeAPIResponse(w, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	if raw, isRaw := result.(*APIRawResponse); isRaw {
+		w.Header().Set("Content-Type", raw.ContentType)
+		_, _ = w.Write(raw.Body)
+		return
+	}
+	writeAPIResponse(w, map[string]interface{}{"success": true, "result": result})
+}
+
+func (s *FakeAPIServer) login(w http.ResponseWriter, call *APICall) {
+	var credentials struct {
+		Login    string
+		Password string
+	}
+	if err := call.Decode(&credentials); err != nil {
+		writeAPIError(w, &APIError{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.User == "" || credentials.Login != s.User || credentials.Password != s.Password {
+		writeAPIError(w, &APIError{
+			Status:  http.StatusUnauthorized,
+			Message: "Either the password or username is incorrect.",
+		})
+		return
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		writeAPIError(w, &APIError{Status: http.StatusInternalServerError, Message: err.Error()})
+		return
+	}
+	session := hex.EncodeToString(random)
+	s.mutex.Lock()
+	s.sessions[session] = true
+	s.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: apiSessionCookie, Value: session, MaxAge: apiSessionMaxAge})
+	writeAPIResponse(w, map[string]interface{}{"success": true, "messages": []string{}})
+}
+
+func (s *FakeAPIServer) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(apiSessionCookie); err == nil {
+		s.mutex.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mutex.Unlock()
+	}
+	writeAPIResponse(w, map[string]interface{}{"success": true})
+}
+
+func (s *FakeAPIServer) loggedIn(r *http.Request) bool {
+	cookie, err := r.Cookie(apiSessionCookie)
+	if err != nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessions[cookie.Value]
+}
+
+func writeAPIError(w http.ResponseWriter, apiErr *APIError) {
+	writeJSON(w, apiErr.Status, map[string]interface{}{"success": false, "message": apiErr.Message})
+}
+
+func writeAPIResponse(w http.ResponseWriter, response map[string]interface{}) {
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, response map[string]interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package test_utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrCommandFailed is the error returned by the fake executor for failed commands.
+var ErrCommandFailed = errors.New("exit status 1")
+
+// FakeCommand is a command line expected by the fake executor and its result.
+type FakeCommand struct {
+	// Args are the command and its arguments. A "*" argument matches any value.
+	Args   []string
+	Output []byte
+	Err    error
+}
+
+func (c *FakeCommand) matches(args []string) bool {
+	if len(c.Args) != len(args) {
+		return false
+	}
+	for i, arg := range c.Args {
+		if arg != "*" && arg != args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FakeExecutor records the commands instead of running them and replies with canned results.
+//
+// The commands registered with Expect have to be run in the same order, those registered with Stub
+// can be run any number of times. Any other command makes the test fail.
+// It implements the utils.Executor interface.
+type FakeExecutor struct {
+	t         *testing.T
+	mutex     sync.Mutex
+	expected  []*FakeCommand
+	stubs     []*FakeCommand
+	installed []string
+	commands  []string
+}
+
+// NewFakeExecutor creates a fake executor checking that all the expected commands have run at the end of the test.
+func NewFakeExecutor(t *testing.T) *FakeExecutor {
+	executor := &FakeExecutor{t: t}
+	t.Cleanup(func() {
+		executor.mutex.Lock()
+		defer executor.mutex.Unlock()
+		for _, command := range executor.expected {
+			t.Errorf("Expected command not run: %s", strings.Join(command.Args, " "))
+		}
+	})
+	return executor
+}
+
+// Expect registers the next command to run with its output and error.
+func (e *FakeExecutor) Expect(output string, err error, args ...string) *FakeExecutor {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.expected = append(e.expected, &FakeCommand{Args: args, Output: []byte(output), Err: err})
+	return e
+}
+
+// Stub registers the output and error of a command that can be run at any time.
+func (e *FakeExecutor) Stub(output string, err error, args ...string) *FakeExecutor {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.stubs = append(e.stubs, &FakeCommand{Args: args, Output: []byte(output), Err: err})
+	return e
+}
+
+// Install makes LookPath find the tools.
+func (e *FakeExecutor) Install(tools ...string) *FakeExecutor {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.installed = append(e.installed, tools...)
+	return e
+}
+
+// Commands returns the command lines run so far.
+func (e *FakeExecutor) Commands() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string{}, e.commands...)
+}
+
+func (e *FakeExecutor) run(command string, args ...string) ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	cmdLine := append([]string{command}, args...)
+	e.commands = append(e.commands, strings.Join(cmdLine, " "))
+	if len(e.expected) > 0 && e.expected[0].matches(cmdLine) {
+		result := e.expected[0]
+		e.expected = e.expected[1:]
+		return result.Output, result.Err
+	}
+	for _, stub := range e.stubs {
+		if stub.matches(cmdLine) {
+			return stub.Output, stub.Err
+		}
+	}
+
+	next := "none"
+	if len(e.expected) > 0 {
+		next = strings.Join(e.expected[0].Args, " ")
+	}
+	e.t.Errorf("Unexpected command: %s, expected: %s", strings.Join(cmdLine, " "), next)
+	return nil, fmt.Errorf("unexpected command: %s", command)
+}
+
+// Run runs a command while showing a spinner.
+func (e *FakeExecutor) Run(command string, args ...string) error {
+	_, err := e.run(command, args...)
+	return err
+}
+
+// RunStdMapping runs a command with its standard and error outputs mapped to the current process ones.
+func (e *FakeExecutor) RunStdMapping(logLevel zerolog.Level, command string, args ...string) error {
+	_, err := e.run(command, args...)
+	return err
+}
+
+// RunOutput runs a command and returns its standard output.
+func (e *FakeExecutor) RunOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error) {
+	return e.run(command, args...)
+}
+
+// RunStream runs a command writing its output to stdout. The input is ignored.
+func (e *FakeExecutor) RunStream(stdin io.Reader, stdout io.Writer, command string, args ...string) error {
+	out, err := e.run(command, args...)
+	if stdout != nil && len(out) > 0 {
+		if _, writeErr := stdout.Write(out); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// LookPath finds the tools registered with Install.
+func (e *FakeExecutor) LookPath(file string) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, tool := range e.installed {
+		if tool == file {
+			return "/usr/bin/" + file, nil
+		}
+	}
+	return "", fmt.Errorf("executable file not found in $PATH: %s", file)
+}
@@ -9,4 +9,5 @@ type GlobalFlags struct {
 	Registry   string
 	ConfigPath string
 	LogLevel   string
+	Instance   string
 }
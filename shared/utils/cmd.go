@@ -54,9 +54,9 @@ func CommandHelper[T interface{}](
 	return fn(globalFlags, flags, cmd, args)
 }
 
-// AddBackendFlag add the flag for setting the backend ('podman', 'podman-remote', 'kubectl').
+// AddBackendFlag add the flag for setting the backend ('podman', 'podman-remote', 'kubectl' or 'ssh://[user@]host[:port]').
 func AddBackendFlag(cmd *cobra.Command) {
-	cmd.Flags().String("backend", "", L("tool to use to reach the container. Possible values: 'podman', 'podman-remote', 'kubectl'. Default guesses which to use."))
+	cmd.Flags().String("backend", "", L("tool to use to reach the container. Possible values: 'podman', 'podman-remote', 'kubectl' or 'ssh://[user@]host[:port]' to work on a remote host. Default guesses which to use."))
 }
 
 // AddPullPolicyFlag adds the --pullPolicy flag to a command.
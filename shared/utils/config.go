@@ -75,6 +75,10 @@ func ReadConfig(cmd *cobra.Command, configPaths ...string) (*viper.Viper, error)
 
 	v.AutomaticEnv()
 
+	if err := resolvePasswordFiles(cmd, v); err != nil {
+		return v, err
+	}
+
 	return v, nil
 }
 
@@ -83,6 +87,9 @@ func bindFlags(cmd *cobra.Command, v *viper.Viper) error {
 	var errors []error
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		configName := strings.ReplaceAll(f.Name, "-", ".")
+		if passwordKey, isPasswordFile := passwordConfigKey(f); isPasswordFile {
+			configName = passwordKey
+		}
 		if err := v.BindPFlag(configName, f); err != nil {
 			errors = append(errors, Errorf(err, L("failed to bind %[1]s config to parameter %[2]s"), configName, f.Name))
 		}
@@ -146,6 +153,12 @@ Configuration:
     ssl:
       password: secret
   
+  Passwords can be read from another source using the matching '--<flag>-file'
+  flag or the '_file' suffix in the configuration:
+  
+    ssl:
+      password_file: /path/to/secret
+  
   The configuration file will be searched in the following places and order:
   · /etc/uyuni/uyuni-tools.yaml
   · $XDG_CONFIG_HOME/{{ .Name }}/{{ .ConfigFile }}
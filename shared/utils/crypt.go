@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptionMagic is the header identifying the files encrypted with EncryptWriter.
+const encryptionMagic = "uyuni-tools-encrypted-v1\n"
+
+// encryptionSaltSize is the size of the random salt following the header.
+const encryptionSaltSize = 16
+
+// encryptionChunkSize is the size of the plain text chunks encrypted separately.
+const encryptionChunkSize = 64 * 1024
+
+// DeriveKey computes a 256 bits key from a passphrase using scrypt.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, Errorf(err, L("failed to derive key from passphrase"))
+	}
+	return key, nil
+}
+
+// EncryptWriter encrypts the data written to it with a passphrase.
+//
+// The data is split in chunks encrypted with AES-256-GCM, the nonce of each chunk contains its index
+// and a flag marking the last chunk to detect reordered or truncated data.
+type EncryptWriter struct {
+	writer  io.Writer
+	aead    cipher.AEAD
+	buffer  []byte
+	counter uint64
+}
+
+// NewEncryptWriter writes the encryption header to w and returns a writer encrypting the data to w.
+//
+// The writer needs to be closed to write the last chunk. Closing it doesn't close w.
+func NewEncryptWriter(w io.Writer, passphrase string) (*EncryptWriter, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, Errorf(err, L("failed to generate random salt"))
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append([]byte(encryptionMagic), salt...)); err != nil {
+		return nil, err
+	}
+	return &EncryptWriter{
+		writer: w,
+		aead:   aead,
+		buffer: make([]byte, 0, encryptionChunkSize),
+	}, nil
+}
+
+// Write encrypts data and writes it to the underlying writer.
+func (e *EncryptWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		// Only flush a full chunk when more data comes to keep the last one for Close
+		if len(e.buffer) == encryptionChunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buffer[len(e.buffer):encryptionChunkSize], data)
+		e.buffer = e.buffer[:len(e.buffer)+n]
+		data = data[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes the last chunk.
+func (e *EncryptWriter) Close() error {
+	return e.flush(true)
+}
+
+func (e *EncryptWriter) flush(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.counter, last), e.buffer, nil)
+	if _, err := e.writer.Write(sealed); err != nil {
+		return err
+	}
+	e.counter++
+	e.buffer = e.buffer[:0]
+	return nil
+}
+
+// decryptReader reads the data encrypted by EncryptWriter.
+type decryptReader struct {
+	reader  *bufio.Reader
+	aead    cipher.AEAD
+	chunk   []byte
+	plain   []byte
+	counter uint64
+	done    bool
+}
+
+// NewDecryptReader reads the encryption header from r and returns a reader decrypting the data from r.
+//
+// An error is returned by the reader if the passphrase is wrong or the data has been altered.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(encryptionMagic)+encryptionSaltSize)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New(L("the data is not encrypted"))
+	}
+
+	aead, err := newAEAD(passphrase, header[len(encryptionMagic):])
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		reader: reader,
+		aead:   aead,
+		chunk:  make([]byte, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// Read decrypts the next chunks of data.
+func (d *decryptReader) Read(data []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(data, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) readChunk() error {
+	n, err := io.ReadFull(d.reader, d.chunk)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return errors.New(L("the encrypted data is truncated"))
+		}
+		return err
+	}
+	// The last chunk is the one followed by no data
+	last := true
+	if n == len(d.chunk) {
+		if _, peekErr := d.reader.Peek(1); peekErr == nil {
+			last = false
+		}
+	}
+
+	plain, err := d.aead.Open(d.chunk[:0], chunkNonce(d.counter, last), d.chunk[:n], nil)
+	if err != nil {
+		return errors.New(L("failed to decrypt the data: wrong passphrase or altered data"))
+	}
+	d.plain = plain
+	d.counter++
+	d.done = last
+	return nil
+}
+
+// IsEncrypted checks if the file at path has been encrypted with EncryptWriter.
+func IsEncrypted(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(file, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(header) == encryptionMagic, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce computes the nonce of a chunk from its index and whether it is the last one.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	sizes := []int{0, 10, encryptionChunkSize, encryptionChunkSize + 1, 3*encryptionChunkSize - 7}
+
+	for i, size := range sizes {
+		data := bytes.Repeat([]byte("0123456789"), size/10+1)[:size]
+
+		var encrypted bytes.Buffer
+		writer, err := NewEncryptWriter(&encrypted, "secret")
+		if err != nil {
+			t.Fatalf("case %d: failed to create the writer: %s", i, err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			t.Fatalf("case %d: failed to write: %s", i, err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("case %d: failed to close: %s", i, err)
+		}
+		sealed := encrypted.Bytes()
+
+		decrypted, err := decrypt(sealed, "secret")
+		if err != nil {
+			t.Errorf("case %d: failed to decrypt: %s", i, err)
+		} else if !bytes.Equal(decrypted, data) {
+			t.Errorf("case %d: decrypted data differs from the original", i)
+		}
+
+		if _, err := decrypt(sealed, "wrong"); err == nil {
+			t.Errorf("case %d: expected an error with a wrong passphrase", i)
+		}
+
+		truncated := sealed[:len(sealed)-1]
+		if _, err := decrypt(truncated, "secret"); err == nil {
+			t.Errorf("case %d: expected an error with truncated data", i)
+		}
+
+		altered := bytes.Clone(sealed)
+		altered[len(altered)-20] ^= 1
+		if _, err := decrypt(altered, "secret"); err == nil {
+			t.Errorf("case %d: expected an error with altered data", i)
+		}
+	}
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	reader, err := NewDecryptReader(bytes.NewReader(data), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the reader: %s", err)
+	}
+	return io.ReadAll(reader)
+}
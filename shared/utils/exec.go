@@ -6,6 +6,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -33,8 +34,66 @@ func (l OutputLogWriter) Write(p []byte) (n int, err error) {
 	return
 }
 
+// Executor runs the commands on the host.
+//
+// All the commands are run through the current executor to be able to test the code running them.
+type Executor interface {
+	// Run runs a command while showing a spinner.
+	Run(command string, args ...string) error
+	// RunStdMapping runs a command with its standard and error outputs mapped to the current process ones.
+	RunStdMapping(logLevel zerolog.Level, command string, args ...string) error
+	// RunOutput runs a command and returns its standard output.
+	// The command is not logged and no spinner is shown with zerolog.Disabled, for the silent checks.
+	RunOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error)
+	// RunStream runs a command reading its input from stdin and writing its output to stdout.
+	// stdin can be nil if the command doesn't need any input.
+	RunStream(stdin io.Reader, stdout io.Writer, command string, args ...string) error
+	// LookPath searches for an executable in the PATH.
+	LookPath(file string) (string, error)
+}
+
+// HostExecutor runs the commands on the host.
+type HostExecutor struct{}
+
+var currentExecutor Executor = HostExecutor{}
+
+// CurrentExecutor returns the executor running the commands.
+func CurrentExecutor() Executor {
+	return currentExecutor
+}
+
+// SetExecutor replaces the executor running the commands and returns a function restoring the previous one.
+func SetExecutor(executor Executor) func() {
+	previous := currentExecutor
+	currentExecutor = executor
+	return func() {
+		currentExecutor = previous
+	}
+}
+
 // RunCmd execute a shell command.
 func RunCmd(command string, args ...string) error {
+	return currentExecutor.Run(command, args...)
+}
+
+// RunCmdStdMapping execute a shell command mapping the stdout and stderr.
+func RunCmdStdMapping(logLevel zerolog.Level, command string, args ...string) error {
+	return currentExecutor.RunStdMapping(logLevel, command, args...)
+}
+
+// RunCmdOutput execute a shell command and collects output.
+func RunCmdOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error) {
+	return currentExecutor.RunOutput(logLevel, command, args...)
+}
+
+// IsInstalled checks if a tool is in the path.
+func IsInstalled(tool string) bool {
+	_, err := currentExecutor.LookPath(tool)
+	return err == nil
+}
+
+// Run runs a command while showing a spinner.
+func (e HostExecutor) Run(command string, args ...string) error {
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond) // Build our new spinner
 	s.Suffix = fmt.Sprintf(" %s %s\n", command, strings.Join(args, " "))
 	s.Start() // Start the spinner
@@ -44,8 +103,8 @@ func RunCmd(command string, args ...string) error {
 	return err
 }
 
-// RunCmdStdMapping execute a shell command mapping the stdout and stderr.
-func RunCmdStdMapping(logLevel zerolog.Level, command string, args ...string) error {
+// RunStdMapping runs a command with its standard and error outputs mapped to the current process ones.
+func (e HostExecutor) RunStdMapping(logLevel zerolog.Level, command string, args ...string) error {
 	localLogger := log.Level(logLevel)
 	localLogger.Debug().Msgf("Running: %s %s", command, strings.Join(args, " "))
 
@@ -56,8 +115,8 @@ func RunCmdStdMapping(logLevel zerolog.Level, command string, args ...string) er
 	return err
 }
 
-// RunCmdOutput execute a shell command and collects output.
-func RunCmdOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error) {
+// RunOutput runs a command and returns its standard output.
+func (e HostExecutor) RunOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error) {
 	localLogger := log.Level(logLevel)
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond) // Build our new spinner
 	s.Suffix = fmt.Sprintf(" %s %s\n", command, strings.Join(args, " "))
@@ -73,10 +132,19 @@ func RunCmdOutput(logLevel zerolog.Level, command string, args ...string) ([]byt
 	return output, err
 }
 
-// IsInstalled checks if a tool is in the path.
-func IsInstalled(tool string) bool {
-	_, err := exec.LookPath("kubectl")
-	return err == nil
+// RunStream runs a command reading its input from stdin and writing its output to stdout.
+func (e HostExecutor) RunStream(stdin io.Reader, stdout io.Writer, command string, args ...string) error {
+	log.Debug().Msgf("Running: %s %s", command, strings.Join(args, " "))
+	runCmd := exec.Command(command, args...)
+	runCmd.Stdin = stdin
+	runCmd.Stdout = stdout
+	runCmd.Stderr = os.Stderr
+	return runCmd.Run()
+}
+
+// LookPath searches for an executable in the PATH.
+func (e HostExecutor) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
 }
 
 // Return list of environmental variables to be passed to exec.
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// The host files functions access the files of the host running the commands.
+//
+// They use the os functions with the local executor and run shell commands through a remote one:
+// the files written there can be used by the commands running on the remote host.
+
+// HostFileExists checks if a path exists on the host running the commands.
+func HostFileExists(path string) bool {
+	if !IsRemoteExecutor() {
+		return FileExists(path)
+	}
+	_, err := RunCmdOutput(zerolog.Disabled, "test", "-e", path)
+	return err == nil
+}
+
+// ReadHostFile returns the content of a file of the host running the commands.
+//
+// The returned error wraps os.ErrNotExist if the file doesn't exist.
+func ReadHostFile(path string) ([]byte, error) {
+	if !IsRemoteExecutor() {
+		return os.ReadFile(path)
+	}
+	out, err := RunCmdOutput(zerolog.DebugLevel, "cat", "--", path)
+	if err != nil && !HostFileExists(path) {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return out, err
+}
+
+// WriteHostFile writes content to a file of the host running the commands, creating it with perm if needed.
+func WriteHostFile(path string, content []byte, perm os.FileMode) error {
+	if !IsRemoteExecutor() {
+		return os.WriteFile(path, content, perm)
+	}
+	// Nobody else can read the file before it gets its permissions
+	script := fmt.Sprintf(`umask 077 && cat > "$1" && chmod %o "$1"`, perm.Perm())
+	return CurrentExecutor().RunStream(bytes.NewReader(content), nil, "sh", "-c", script, "sh", path)
+}
+
+// ListHostDir returns the names of the entries of a folder of the host running the commands.
+func ListHostDir(path string) ([]string, error) {
+	if !IsRemoteExecutor() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		return names, nil
+	}
+	out, err := RunCmdOutput(zerolog.DebugLevel, "ls", "-1A", "--", path)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, name := range strings.Split(string(out), "\n") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// MkdirHostAll creates a folder and its missing parents on the host running the commands.
+func MkdirHostAll(path string, perm os.FileMode) error {
+	if !IsRemoteExecutor() {
+		return os.MkdirAll(path, perm)
+	}
+	return RunCmd("mkdir", "-p", "-m", fmt.Sprintf("%o", perm.Perm()), "--", path)
+}
+
+// MkdirHostTemp creates a temporary folder on the host running the commands and returns its path.
+//
+// Like for os.MkdirTemp, a random string is appended to pattern or replaces its trailing *.
+func MkdirHostTemp(pattern string) (string, error) {
+	if !IsRemoteExecutor() {
+		return os.MkdirTemp("", pattern)
+	}
+	template := strings.TrimSuffix(pattern, "*") + "XXXXXX"
+	out, err := RunCmdOutput(zerolog.DebugLevel, "mktemp", "-d", "-t", template)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RemoveHostFile removes a file or an empty folder of the host running the commands.
+func RemoveHostFile(path string) error {
+	if !IsRemoteExecutor() {
+		return os.Remove(path)
+	}
+	return RunCmd("rm", "-d", "--", path)
+}
+
+// RemoveHostAll removes a path and all its content from the host running the commands.
+//
+// Nothing is done if the path doesn't exist.
+func RemoveHostAll(path string) error {
+	if !IsRemoteExecutor() {
+		return os.RemoveAll(path)
+	}
+	return RunCmd("rm", "-rf", "--", path)
+}
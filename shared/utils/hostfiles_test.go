@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestRemoteHostFiles(t *testing.T) {
+	fake := test_utils.NewFakeExecutor(t)
+	t.Cleanup(SetExecutor(fake))
+	fake.
+		Expect("/tmp/mgradm-Ab12Cd\n", nil, "ssh", "--", "server.lab", "mktemp -d -t mgradm-XXXXXX").
+		Expect("", nil, "ssh", "--", "server.lab",
+			`sh -c 'umask 077 && cat > "$1" && chmod 600 "$1"' sh /tmp/mgradm-Ab12Cd/auth.json`).
+		Expect("{}", nil, "ssh", "--", "server.lab", "cat -- /tmp/mgradm-Ab12Cd/auth.json").
+		Expect("", test_utils.ErrCommandFailed, "ssh", "--", "server.lab", "cat -- /tmp/missing").
+		Expect("", test_utils.ErrCommandFailed, "ssh", "--", "server.lab", "test -e /tmp/missing").
+		Expect("a metadata\nb\n", nil, "ssh", "--", "server.lab", "ls -1A -- /usr/share").
+		Expect("", nil, "ssh", "--", "server.lab", "rm -rf -- /tmp/mgradm-Ab12Cd")
+
+	restore, err := UseSSHBackend("ssh://server.lab")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer restore()
+
+	dir, err := MkdirHostTemp("mgradm-*")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong temporary folder", "/tmp/mgradm-Ab12Cd", dir)
+	if err := WriteHostFile(dir+"/auth.json", []byte("{}"), 0600); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	content, err := ReadHostFile(dir + "/auth.json")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong content", "{}", string(content))
+	if _, err := ReadHostFile("/tmp/missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a not existing file error, got %s", err)
+	}
+	names, err := ListHostDir("/usr/share")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong folder entries", "a metadata|b", strings.Join(names, "|"))
+	if err := RemoveHostAll(dir); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -6,7 +6,6 @@ package utils
 
 import (
 	"bytes"
-	"os"
 	"path"
 
 	"github.com/rs/zerolog/log"
@@ -78,7 +77,7 @@ func (i *BaseInspector) GetScriptPath() string {
 // This function is most likely to be used for the implementation of the inspectors, but can also be used directly.
 func ReadInspectData[T any](dataFile string) (*T, error) {
 	log.Debug().Msgf("Trying to read %s", dataFile)
-	data, err := os.ReadFile(dataFile)
+	data, err := ReadHostFile(dataFile)
 	if err != nil {
 		return nil, Errorf(err, L("cannot read file %s"), dataFile)
 	}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+)
+
+// passwordFileAnnotation is set on the flags providing the source of a password flag.
+const passwordFileAnnotation = "uyuni-tools/password-file"
+
+// passwordFileSuffix is appended to the configuration key of a password to get its source key.
+const passwordFileSuffix = "_file"
+
+const (
+	keyringPrefix       = "keyring:"
+	secretServicePrefix = "secret-service:"
+)
+
+// AddPasswordFileFlag adds a --<name>-file flag to read the value of the name password flag from another source.
+//
+// The source can be a file path, - for the standard input, keyring:<description> for a user key
+// of the kernel keyring or secret-service:<attribute>=<value>,... for the freedesktop secret service.
+// In the configuration file, the source is set with the <password key>_file key.
+func AddPasswordFileFlag(flags *pflag.FlagSet, name string) {
+	fileFlag := name + "-file"
+	flags.String(fileFlag, "", fmt.Sprintf(L("Read the value of --%s from a file, - for the standard input, "+
+		"keyring:<description> for the kernel keyring or secret-service:<attribute>=<value>,... for the secret service"),
+		name))
+	_ = flags.SetAnnotation(fileFlag, passwordFileAnnotation, []string{name})
+}
+
+// passwordConfigKey returns the configuration key of a password file flag.
+func passwordConfigKey(f *pflag.Flag) (string, bool) {
+	names := f.Annotations[passwordFileAnnotation]
+	if len(names) == 0 {
+		return "", false
+	}
+	return strings.ReplaceAll(names[0], "-", ".") + passwordFileSuffix, true
+}
+
+// resolvePasswordFiles sets the passwords provided through their password file flag or configuration.
+func resolvePasswordFiles(cmd *cobra.Command, v *viper.Viper) error {
+	var errors []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		fileKey, isPasswordFile := passwordConfigKey(f)
+		if !isPasswordFile {
+			return
+		}
+		source := v.GetString(fileKey)
+		if source == "" {
+			return
+		}
+		passwordKey := strings.TrimSuffix(fileKey, passwordFileSuffix)
+		if v.GetString(passwordKey) != "" {
+			errors = append(errors, fmt.Errorf(L("%[1]s and %[2]s cannot be both set"), passwordKey, fileKey))
+			return
+		}
+		password, err := ReadPasswordSource(source)
+		if err != nil {
+			errors = append(errors, Errorf(err, L("failed to read %s"), fileKey))
+			return
+		}
+		v.Set(passwordKey, password)
+	})
+
+	if len(errors) > 0 {
+		return errors[0]
+	}
+	return nil
+}
+
+// ReadPasswordSource reads a password from a file, the standard input, the kernel keyring or the secret service.
+//
+// See AddPasswordFileFlag for the syntax of the source.
+func ReadPasswordSource(source string) (string, error) {
+	var content []byte
+	var err error
+	switch {
+	case source == "-":
+		content, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, keyringPrefix):
+		content, err = readKeyring(strings.TrimPrefix(source, keyringPrefix))
+	case strings.HasPrefix(source, secretServicePrefix):
+		content, err = readSecretService(strings.TrimPrefix(source, secretServicePrefix))
+	default:
+		content, err = readPasswordFile(source)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	password := strings.TrimRight(string(content), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf(L("empty password read from %s"), source)
+	}
+	return password, nil
+}
+
+func readPasswordFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		log.Warn().Msgf(L("%s is accessible by other users"), path)
+	}
+	return os.ReadFile(path)
+}
+
+func readKeyring(description string) ([]byte, error) {
+	out, err := RunCmdOutput(zerolog.Disabled, "keyctl", "request", "user", description)
+	if err != nil {
+		return nil, Errorf(err, L("failed to find key %s in the kernel keyring"), description)
+	}
+	out, err = RunCmdOutput(zerolog.Disabled, "keyctl", "pipe", strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, Errorf(err, L("failed to read key %s from the kernel keyring"), description)
+	}
+	return out, nil
+}
+
+func readSecretService(attributes string) ([]byte, error) {
+	args := []string{"lookup"}
+	for _, attribute := range strings.Split(attributes, ",") {
+		parts := strings.SplitN(attribute, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf(L("invalid secret service attribute: %s"), attribute)
+		}
+		args = append(args, parts[0], parts[1])
+	}
+	out, err := RunCmdOutput(zerolog.Disabled, "secret-tool", args...)
+	if err != nil {
+		return nil, Errorf(err, L("failed to find the secret in the secret service"))
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func writeSecret(t *testing.T, content string) string {
+	secretPath := path.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return secretPath
+}
+
+func TestReadPasswordSource(t *testing.T) {
+	password, err := ReadPasswordSource(writeSecret(t, "s3cr3t pass\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong password from file", "s3cr3t pass", password)
+
+	if _, err := ReadPasswordSource(writeSecret(t, "\n")); err == nil {
+		t.Error("Expected an error for an empty password")
+	}
+	if _, err := ReadPasswordSource(path.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err := ReadPasswordSource(secretServicePrefix + "invalid"); err == nil {
+		t.Error("Expected an error for invalid secret service attributes")
+	}
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = reader
+	if _, err := writer.WriteString("from-stdin\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	password, err = ReadPasswordSource("-")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Wrong password from stdin", "from-stdin", password)
+}
+
+func newPasswordCommand(args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("admin-password", "", "")
+	AddPasswordFileFlag(cmd.Flags(), "admin-password")
+	_ = cmd.ParseFlags(args)
+	return cmd
+}
+
+func TestReadConfigPasswordFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	secretPath := writeSecret(t, "secret")
+	configPath := path.Join(t.TempDir(), "config.yaml")
+
+	v, err := ReadConfig(newPasswordCommand("--admin-password-file", secretPath), configPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Password not read from file", "secret", v.GetString("admin.password"))
+
+	v, err = ReadConfig(newPasswordCommand("--admin-password", "direct"), configPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Password flag should be used", "direct", v.GetString("admin.password"))
+
+	cmd := newPasswordCommand("--admin-password", "direct", "--admin-password-file", secretPath)
+	if _, err := ReadConfig(cmd, configPath); err == nil {
+		t.Error("Expected an error when both the password and its file are set")
+	}
+
+	config := "admin:\n  password_file: " + secretPath + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v, err = ReadConfig(newPasswordCommand(), configPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Password not read from configured file", "secret", v.GetString("admin.password"))
+	os.Remove(configPath)
+
+	t.Setenv("UYUNI_ADMIN_PASSWORD_FILE", secretPath)
+	v, err = ReadConfig(newPasswordCommand(), configPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "Password not read from environment file", "secret", v.GetString("admin.password"))
+}
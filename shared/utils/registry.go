@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+)
+
+// manifestTypes are the media types accepted when looking up an image manifest.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var authParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryClient is the HTTP client used to query the registries, replaceable for the tests.
+var registryClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsImageInRegistry returns whether the manifest of an image can be found in its registry.
+//
+// The registry is queried anonymously, without pulling the image nor requiring podman:
+// an error is returned if the registry requires credentials.
+func IsImageInRegistry(image string) (bool, error) {
+	manifestURL, err := getManifestURL(image)
+	if err != nil {
+		return false, err
+	}
+	log.Debug().Msgf("Looking up manifest %s", manifestURL)
+
+	resp, err := headManifest(manifestURL, "")
+	if err != nil {
+		return false, Errorf(err, L("cannot look up the manifest of image %s"), image)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := getRegistryToken(resp.Header.Get("Www-Authenticate"))
+		if err != nil {
+			return false, Errorf(err, L("cannot look up the manifest of image %s"), image)
+		}
+		if resp, err = headManifest(manifestURL, token); err != nil {
+			return false, Errorf(err, L("cannot look up the manifest of image %s"), image)
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf(L("cannot look up the manifest of image %[1]s: %[2]s"), image, resp.Status)
+	}
+}
+
+// getManifestURL returns the registry URL of the manifest of an image.
+func getManifestURL(image string) (string, error) {
+	name := image
+	for _, prefix := range []string{"docker://", "oci://", "http://", "https://"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+
+	reference := "latest"
+	if index := strings.LastIndex(name, "@"); index >= 0 {
+		reference = name[index+1:]
+		name = name[:index]
+	} else if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		reference = name[index+1:]
+		name = name[:index]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) < 2 || !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "", fmt.Errorf(L("image %s has no registry"), image)
+	}
+	return fmt.Sprintf("https://%s/v2/%s/manifests/%s", parts[0], parts[1], reference), nil
+}
+
+func headManifest(manifestURL string, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// getRegistryToken gets an anonymous token from the authentication service described by a Bearer challenge.
+func getRegistryToken(challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", errors.New(L("the registry requires credentials"))
+	}
+	params := map[string]string{}
+	for _, match := range authParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf(L("invalid registry authentication challenge: %s"), challenge)
+	}
+	query := realm.Query()
+	for _, param := range []string{"service", "scope"} {
+		if value := params[param]; value != "" {
+			query.Set(param, value)
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	resp, err := registryClient.Get(realm.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(L("bad status: %s"), resp.Status)
+	}
+
+	var data struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", err
+	}
+	if data.Token == "" {
+		return data.AccessToken, nil
+	}
+	return data.Token, nil
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetManifestURL(t *testing.T) {
+	data := [][]string{
+		{"registry.opensuse.org/uyuni/server:latest", "https://registry.opensuse.org/v2/uyuni/server/manifests/latest"},
+		{"registry.opensuse.org/uyuni/server", "https://registry.opensuse.org/v2/uyuni/server/manifests/latest"},
+		{"docker://localhost:5000/uyuni/server:5.0", "https://localhost:5000/v2/uyuni/server/manifests/5.0"},
+		{"localhost:5000/uyuni/server@sha256:1234", "https://localhost:5000/v2/uyuni/server/manifests/sha256:1234"},
+	}
+
+	for i, testCase := range data {
+		actual, err := getManifestURL(testCase[0])
+		if err != nil {
+			t.Errorf("Testcase %d: Unexpected error: %s", i, err)
+		}
+		if actual != testCase[1] {
+			t.Errorf("Testcase %d: Expected %s got %s", i, testCase[1], actual)
+		}
+	}
+
+	if _, err := getManifestURL("uyuni/server:latest"); err == nil {
+		t.Error("Expected an error for an image without registry")
+	}
+}
+
+func TestIsImageInRegistry(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:uyuni/server:pull" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry",scope="repository:uyuni/server:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodHead || !strings.Contains(r.Header.Get("Accept"), manifestTypes[0]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path != "/v2/uyuni/server/manifests/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	defaultClient := registryClient
+	registryClient = server.Client()
+	defer func() { registryClient = defaultClient }()
+
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	found, err := IsImageInRegistry(registry + "/uyuni/server:latest")
+	if err != nil || !found {
+		t.Errorf("Expected the image to be found, got %v, %v", found, err)
+	}
+
+	found, err = IsImageInRegistry(registry + "/uyuni/server-migration-14-16:latest")
+	if err != nil || found {
+		t.Errorf("Expected the image to be missing, got %v, %v", found, err)
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/uyuni-project/uyuni-tools/shared/types"
 )
 
+// ImagePgVersionCommand is the shell command printing the PostgreSQL major version installed in a server image.
+const ImagePgVersionCommand = "rpm -qa --qf '%{VERSION}\\n' 'name=postgresql[0-8][0-9]-server'  | cut -d. -f1 | sort -n | tail -1 || true"
+
 // ServerInspector inspects a running server container or its image.
 type ServerInspector struct {
 	BaseInspector
@@ -28,9 +31,7 @@ func NewServerInspector(scriptDir string) ServerInspector {
 			types.NewInspectData(
 				"fqdn",
 				"cat /etc/rhn/rhn.conf 2>/dev/null | grep 'java.hostname' | cut -d' ' -f3 || true"),
-			types.NewInspectData(
-				"image_pg_version",
-				"rpm -qa --qf '%{VERSION}\\n' 'name=postgresql[0-8][0-9]-server'  | cut -d. -f1 | sort -n | tail -1 || true"),
+			types.NewInspectData("image_pg_version", ImagePgVersionCommand),
 			types.NewInspectData("current_pg_version",
 				"(test -e /var/lib/pgsql/data/PG_VERSION && cat /var/lib/pgsql/data/PG_VERSION) || true"),
 			types.NewInspectData("db_user",
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog"
+	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
+)
+
+const sshScheme = "ssh://"
+
+var shellSafeRegexp = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// CommandWrapper is implemented by the executors running the commands through another command.
+type CommandWrapper interface {
+	// WrapCommand returns the command line to run on the local host to run command.
+	// tty requests a terminal for interactive commands.
+	WrapCommand(tty bool, command string, args ...string) (string, []string)
+}
+
+// SSHExecutor runs the commands on a remote host using the local ssh client.
+//
+// The ssh configuration and keys of the user are used to connect to the remote host.
+type SSHExecutor struct {
+	// Destination is the [user@]host to connect to.
+	Destination string
+	// Port is the SSH port of the remote host, empty for the default one.
+	Port string
+
+	local Executor
+}
+
+// IsSSHBackend returns whether a backend designates a remote host like ssh://admin@host.
+func IsSSHBackend(backend string) bool {
+	return strings.HasPrefix(backend, sshScheme)
+}
+
+// NewSSHExecutor creates an executor for a ssh://[user@]host[:port] backend.
+//
+// The ssh client is run by the current executor, or by the local one if the current executor is already remote.
+func NewSSHExecutor(backend string) (*SSHExecutor, error) {
+	target, err := url.Parse(backend)
+	if err != nil || !IsSSHBackend(backend) || target.Hostname() == "" || (target.Path != "" && target.Path != "/") {
+		return nil, fmt.Errorf(L("invalid SSH backend %s: expected ssh://[user@]host[:port]"), backend)
+	}
+
+	destination := target.Hostname()
+	if target.User != nil && target.User.Username() != "" {
+		destination = target.User.Username() + "@" + destination
+	}
+	// ssh would take them as options
+	if strings.HasPrefix(destination, "-") {
+		return nil, fmt.Errorf(L("invalid SSH backend %s: user and host cannot start with -"), backend)
+	}
+
+	local := CurrentExecutor()
+	if remote, isRemote := local.(*SSHExecutor); isRemote {
+		local = remote.local
+	}
+	return &SSHExecutor{Destination: destination, Port: target.Port(), local: local}, nil
+}
+
+// IsRemoteExecutor returns whether the current executor runs the commands on a remote host.
+//
+// The files of the remote host cannot be accessed directly in that case.
+func IsRemoteExecutor() bool {
+	_, isRemote := CurrentExecutor().(CommandWrapper)
+	return isRemote
+}
+
+// UseSSHBackend runs all the host commands on the remote host of a ssh:// backend
+// and returns a function restoring the previous executor.
+//
+// Nothing is changed for the other backends.
+func UseSSHBackend(backend string) (func(), error) {
+	if !IsSSHBackend(backend) {
+		return func() {}, nil
+	}
+	executor, err := NewSSHExecutor(backend)
+	if err != nil {
+		return nil, err
+	}
+	return SetExecutor(executor), nil
+}
+
+// WrapCommand returns the ssh command line running command on the remote host.
+func (e *SSHExecutor) WrapCommand(tty bool, command string, args ...string) (string, []string) {
+	sshArgs := []string{}
+	if tty {
+		sshArgs = append(sshArgs, "-t")
+	}
+	if e.Port != "" {
+		sshArgs = append(sshArgs, "-p", e.Port)
+	}
+	// The options end before the destination: ssh would otherwise parse the ones following it.
+	// ssh joins the arguments in a command line run by the remote shell: quote them
+	sshArgs = append(sshArgs, "--", e.Destination, ShellQuote(append([]string{command}, args...)...))
+	return "ssh", sshArgs
+}
+
+// Run runs a command on the remote host while showing a spinner.
+func (e *SSHExecutor) Run(command string, args ...string) error {
+	sshCommand, sshArgs := e.WrapCommand(false, command, args...)
+	return e.local.Run(sshCommand, sshArgs...)
+}
+
+// RunStdMapping runs a command on the remote host with its outputs mapped to the current process ones.
+func (e *SSHExecutor) RunStdMapping(logLevel zerolog.Level, command string, args ...string) error {
+	sshCommand, sshArgs := e.WrapCommand(false, command, args...)
+	return e.local.RunStdMapping(logLevel, sshCommand, sshArgs...)
+}
+
+// RunOutput runs a command on the remote host and returns its standard output.
+func (e *SSHExecutor) RunOutput(logLevel zerolog.Level, command string, args ...string) ([]byte, error) {
+	sshCommand, sshArgs := e.WrapCommand(false, command, args...)
+	return e.local.RunOutput(logLevel, sshCommand, sshArgs...)
+}
+
+// RunStream runs a command on the remote host, streaming its input and output through the ssh connection.
+func (e *SSHExecutor) RunStream(stdin io.Reader, stdout io.Writer, command string, args ...string) error {
+	sshCommand, sshArgs := e.WrapCommand(false, command, args...)
+	return e.local.RunStream(stdin, stdout, sshCommand, sshArgs...)
+}
+
+// LookPath searches for an executable in the PATH of the remote host.
+func (e *SSHExecutor) LookPath(file string) (string, error) {
+	out, err := e.RunOutput(zerolog.DebugLevel, "command", "-v", file)
+	found := strings.TrimSpace(string(out))
+	if err != nil || found == "" {
+		return "", fmt.Errorf(L("executable file not found on %[1]s: %[2]s"), e.Destination, file)
+	}
+	return found, nil
+}
+
+// ShellQuote joins the arguments in a command line for a POSIX shell, quoting them if needed.
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafeRegexp.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2024 SUSE LLC
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/uyuni-project/uyuni-tools/shared/test_utils"
+)
+
+func TestNewSSHExecutor(t *testing.T) {
+	type testCase struct {
+		backend     string
+		destination string
+		port        string
+	}
+	data := []testCase{
+		{"ssh://admin@server.lab", "admin@server.lab", ""},
+		{"ssh://server.lab:2222", "server.lab", "2222"},
+		{"ssh://admin@server.lab:2222/", "admin@server.lab", "2222"},
+	}
+	for _, test := range data {
+		executor, err := NewSSHExecutor(test.backend)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", test.backend, err)
+		}
+		test_utils.AssertEquals(t, "wrong destination", test.destination, executor.Destination)
+		test_utils.AssertEquals(t, "wrong port", test.port, executor.Port)
+	}
+
+	for _, backend := range []string{
+		"podman", "ssh://", "ssh://admin@", "ssh://server.lab/path", "ssh://-oProxyCommand=id@server.lab", "ssh://-server.lab",
+	} {
+		if _, err := NewSSHExecutor(backend); err == nil {
+			t.Errorf("Expected an error for backend %s", backend)
+		}
+	}
+}
+
+func TestSSHExecutor(t *testing.T) {
+	fake := test_utils.NewFakeExecutor(t)
+	t.Cleanup(SetExecutor(fake))
+	fake.
+		Expect("", nil, "ssh", "-p", "2222", "--", "admin@server.lab", "systemctl start uyuni-server").
+		Expect("4c0ffee\n", nil, "ssh", "-p", "2222", "--", "admin@server.lab",
+			"podman exec uyuni-server sh -c 'echo \"$HOME\" '\\''quoted'\\'''").
+		Expect("/usr/bin/podman\n", nil, "ssh", "-p", "2222", "--", "admin@server.lab", "command -v podman").
+		Expect("", test_utils.ErrCommandFailed, "ssh", "-p", "2222", "--", "admin@server.lab", "command -v kubectl")
+
+	restore, err := UseSSHBackend("ssh://admin@server.lab:2222")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer restore()
+
+	if err := RunCmd("systemctl", "start", "uyuni-server"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	out, err := RunCmdOutput(0, "podman", "exec", "uyuni-server", "sh", "-c", `echo "$HOME" 'quoted'`)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "wrong output", "4c0ffee\n", string(out))
+	test_utils.AssertTrue(t, "podman not found", IsInstalled("podman"))
+	test_utils.AssertTrue(t, "kubectl found", !IsInstalled("kubectl"))
+
+	// Nested ssh backends still run the ssh client locally
+	nested, err := NewSSHExecutor("ssh://other.lab")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	test_utils.AssertEquals(t, "ssh client not run locally", fake, nested.local.(*test_utils.FakeExecutor))
+}
+
+func TestShellQuote(t *testing.T) {
+	test_utils.AssertEquals(t, "wrong quoting", `ls -l /var/lib 'a b' '' 'it'\''s'`,
+		ShellQuote("ls", "-l", "/var/lib", "a b", "", "it's"))
+}
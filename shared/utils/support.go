@@ -7,7 +7,6 @@ package utils
 import (
 	"errors"
 	"fmt"
-	"os/exec"
 	"path"
 	"regexp"
 	"strings"
@@ -82,7 +81,7 @@ func RunSupportConfigOnHost(dir string) ([]string, error) {
 	extensions := []string{"", ".md5"}
 
 	// Run supportconfig on the host if installed
-	if _, err := exec.LookPath("supportconfig"); err == nil {
+	if IsInstalled("supportconfig") {
 		out, err := RunCmdOutput(zerolog.DebugLevel, "supportconfig")
 		if err != nil {
 			return []string{}, Errorf(err, L("failed to run supportconfig on the host"))
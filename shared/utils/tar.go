@@ -6,11 +6,16 @@ package utils
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	. "github.com/uyuni-project/uyuni-tools/shared/l10n"
@@ -74,31 +79,69 @@ func ExtractTarGz(tarballPath string, dstPath string) error {
 
 // Object holding a .tar.gz to write it to a file.
 type TarGz struct {
-	fileWriter *os.File
-	tarWriter  *tar.Writer
-	gzipWriter *gzip.Writer
+	fileWriter  *os.File
+	cryptWriter *EncryptWriter
+	tarWriter   *tar.Writer
+	gzipWriter  *gzip.Writer
+	checksums   map[string]string
+	closed      bool
 }
 
 // NewTarGz create a targz object with writers opened.
 // A successful call should be followed with a close.
 func NewTarGz(path string) (*TarGz, error) {
-	var targz TarGz
+	return NewEncryptedTarGz(path, "")
+}
+
+// NewEncryptedTarGz create a targz object encrypting the archive with passphrase.
+// The archive is not encrypted if the passphrase is empty.
+// A successful call should be followed with a close.
+func NewEncryptedTarGz(path string, passphrase string) (*TarGz, error) {
+	targz := TarGz{checksums: map[string]string{}}
 	var err error
 	targz.fileWriter, err = os.Create(path)
 	if err != nil {
 		return nil, Errorf(err, L("failed to write tar.gz to %s"), path)
 	}
 
-	targz.gzipWriter = gzip.NewWriter(targz.fileWriter)
+	var writer io.Writer = targz.fileWriter
+	if passphrase != "" {
+		targz.cryptWriter, err = NewEncryptWriter(targz.fileWriter, passphrase)
+		if err != nil {
+			targz.fileWriter.Close()
+			return nil, Errorf(err, L("failed to write tar.gz to %s"), path)
+		}
+		writer = targz.cryptWriter
+	}
+
+	targz.gzipWriter = gzip.NewWriter(writer)
 	targz.tarWriter = tar.NewWriter(targz.gzipWriter)
 	return &targz, nil
 }
 
 // Close stops all the writers.
-func (t *TarGz) Close() {
-	t.tarWriter.Close()
-	t.gzipWriter.Close()
-	t.fileWriter.Close()
+//
+// The returned error needs to be checked to ensure the archive is complete.
+// Closing an already closed archive does nothing.
+func (t *TarGz) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	err := JoinErrors(t.tarWriter.Close(), t.gzipWriter.Close())
+	if t.cryptWriter != nil {
+		err = JoinErrors(err, t.cryptWriter.Close())
+	}
+	return JoinErrors(err, t.fileWriter.Close())
+}
+
+// Checksums returns the SHA-256 of the regular files added to the archive indexed by entry name.
+func (t *TarGz) Checksums() map[string]string {
+	checksums := make(map[string]string, len(t.checksums))
+	for name, checksum := range t.checksums {
+		checksums[name] = checksum
+	}
+	return checksums
 }
 
 // AddFile adds the file at filepath to the archive as entrypath.
@@ -120,12 +163,246 @@ func (t *TarGz) AddFile(filepath string, entrypath string) error {
 	}
 
 	header.Name = entrypath
-	if err = t.tarWriter.WriteHeader(header); err != nil {
+	return t.AddEntry(header, file)
+}
+
+// AddData adds the data to the archive as a regular file named entrypath.
+func (t *TarGz) AddData(data []byte, entrypath string, mode os.FileMode) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entrypath,
+		Mode:     int64(mode.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	return t.AddEntry(header, bytes.NewReader(data))
+}
+
+// AddEntry adds an entry read from another tar stream to the archive.
+//
+// The content of the entry is read from reader and the header is written as is.
+func (t *TarGz) AddEntry(header *tar.Header, reader io.Reader) error {
+	if err := t.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if header.Typeflag != tar.TypeReg {
+		_, err := io.Copy(t.tarWriter, reader)
+		return err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(t.tarWriter, hash), reader); err != nil {
+		return err
+	}
+	t.checksums[header.Name] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// AddDirectory adds the content of the dirpath folder recursively to the archive in entrypath.
+//
+// Symbolic links are stored as links and the ownership and permissions of the files are preserved.
+// Sockets and other special files cannot be archived and are skipped.
+func (t *TarGz) AddDirectory(dirpath string, entrypath string) error {
+	return filepath.Walk(dirpath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dirpath, filePath)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			log.Warn().Err(err).Msgf(L("Skipping %s"), filePath)
+			return nil
+		}
+		header.Name = filepath.Join(entrypath, relPath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if !info.Mode().IsRegular() {
+			return t.AddEntry(header, bytes.NewReader(nil))
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return t.AddEntry(header, file)
+	})
+}
+
+// WalkTarGz calls fn for each entry of a tar.gz file.
+//
+// The reader passed to fn is only valid until fn returns.
+// fn can return io.EOF to stop walking the archive without error.
+func WalkTarGz(tarballPath string, fn func(header *tar.Header, reader io.Reader) error) error {
+	return WalkEncryptedTarGz(tarballPath, "", fn)
+}
+
+// WalkEncryptedTarGz calls fn for each entry of a tar.gz file encrypted with passphrase.
+//
+// The passphrase is ignored if the file is not encrypted.
+// The reader passed to fn is only valid until fn returns.
+// fn can return io.EOF to stop walking the archive without error.
+func WalkEncryptedTarGz(tarballPath string, passphrase string,
+	fn func(header *tar.Header, reader io.Reader) error,
+) error {
+	encrypted, err := IsEncrypted(tarballPath)
+	if err != nil {
+		return err
+	}
+	if encrypted && passphrase == "" {
+		return fmt.Errorf(L("%s is encrypted, a passphrase is required"), tarballPath)
+	}
+
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if encrypted {
+		if reader, err = NewDecryptReader(file, passphrase); err != nil {
+			return err
+		}
+	}
+
+	archive, err := gzip.NewReader(reader)
+	if err != nil {
 		return err
 	}
+	defer archive.Close()
+
+	tarReader := tar.NewReader(archive)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := fn(header, tarReader); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
 
-	if _, err = io.Copy(t.tarWriter, file); err != nil {
+// ExtractTarEntry writes a tar entry in the dstPath folder as name.
+//
+// Unlike ExtractTarGz, the numeric owner, permissions and modification time of the entry are restored
+// and symbolic links are recreated.
+// Symbolic links are recreated with their target unchanged, even pointing outside of dstPath,
+// but no entry is written through a symbolic link.
+func ExtractTarEntry(header *tar.Header, reader io.Reader, dstPath string, name string) error {
+	dstPath, err := filepath.Abs(dstPath)
+	if err != nil {
 		return err
 	}
+	target := filepath.Join(dstPath, name)
+	if !isInDir(dstPath, target) {
+		log.Warn().Msgf(L("Skipping extraction of %[1]s as it resolves outside of %[2]s"), header.Name, dstPath)
+		return nil
+	}
+
+	// A previous entry could have created a symbolic link to write outside of dstPath
+	if err := checkNoSymlink(dstPath, target); err != nil {
+		return Errorf(err, L("cannot extract %s"), header.Name)
+	}
+	// Replace an existing symbolic link instead of writing to the file it points to
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+
+	mode := header.FileInfo().Mode()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, mode.Perm()); err != nil {
+			return err
+		}
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, reader)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return err
+		}
+		return os.Lchown(target, header.Uid, header.Gid)
+	default:
+		log.Warn().Msgf(L("Skipping extraction of %s: unsupported file type"), header.Name)
+		return nil
+	}
+
+	if err := os.Lchown(target, header.Uid, header.Gid); err != nil {
+		return err
+	}
+	// Chmod is needed since the umask may have changed the permissions and to restore setuid bits
+	if err := os.Chmod(target, mode); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// isInDir returns whether path is dir or one of its descendants, without resolving the symbolic links.
+func isInDir(dir string, path string) bool {
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// checkNoSymlink returns an error if one of the existing components of target below dir is a symbolic link.
+//
+// The last component is only checked for regular files and directories: symbolic links are replaced.
+func checkNoSymlink(dir string, target string) error {
+	relative, err := filepath.Rel(dir, target)
+	if err != nil || relative == "." {
+		return err
+	}
+	current := dir
+	for _, part := range strings.Split(relative, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 && current != target {
+			return fmt.Errorf(L("%s is a symbolic link"), current)
+		}
+	}
 	return nil
 }
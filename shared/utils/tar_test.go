@@ -5,9 +5,14 @@
 package utils
 
 import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -123,3 +128,202 @@ func TestExtractTarGz(t *testing.T) {
 		}
 	}
 }
+
+func TestAddDirectory(t *testing.T) {
+	tmpDir, teardown := setup(t)
+	defer teardown(t)
+
+	dataPath := path.Join(tmpDir, dataDir)
+	if err := os.Symlink("file1", path.Join(dataPath, "link1")); err != nil {
+		t.Fatalf("failed to create test symlink: %s", err)
+	}
+
+	// Create the tarball
+	tarballPath := path.Join(tmpDir, "test.tar.gz")
+	tarball, err := NewTarGz(tarballPath)
+	if err != nil {
+		t.Fatalf("failed to create tarball: %s", err)
+	}
+	if err := tarball.AddDirectory(dataPath, "volumes/data"); err != nil {
+		t.Fatalf("failed to add data directory to tarball: %s", err)
+	}
+	if err := tarball.AddData([]byte("manifest content"), "manifest", 0600); err != nil {
+		t.Fatalf("failed to add data to tarball: %s", err)
+	}
+	tarball.Close()
+
+	// Check the tarball using the tar utility
+	testDir := path.Join(tmpDir, outDir)
+	if out, err := exec.Command("tar", "xzf", tarballPath, "-C", testDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to extract generated tarball: %s", string(out))
+	}
+
+	for name, content := range filesData {
+		if out, err := os.ReadFile(path.Join(testDir, "volumes/data", name)); err != nil {
+			t.Errorf("failed to read %s: %s", name, err)
+		} else if string(out) != content {
+			t.Errorf("expected %s content %s, but got %s", name, content, string(out))
+		}
+	}
+
+	if link, err := os.Readlink(path.Join(testDir, "volumes/data/link1")); err != nil {
+		t.Errorf("failed to read link1 symlink: %s", err)
+	} else if link != "file1" {
+		t.Errorf("expected link1 to point to file1, but got %s", link)
+	}
+
+	if out, err := os.ReadFile(path.Join(testDir, "manifest")); err != nil {
+		t.Errorf("failed to read manifest: %s", err)
+	} else if string(out) != "manifest content" {
+		t.Errorf("expected manifest content, but got %s", string(out))
+	}
+}
+
+func TestExtractTarEntry(t *testing.T) {
+	tmpDir, teardown := setup(t)
+	defer teardown(t)
+
+	dataPath := path.Join(tmpDir, dataDir)
+	if err := os.Symlink("file1", path.Join(dataPath, "link1")); err != nil {
+		t.Fatalf("failed to create test symlink: %s", err)
+	}
+
+	tarballPath := path.Join(tmpDir, "test.tar.gz")
+	tarball, err := NewTarGz(tarballPath)
+	if err != nil {
+		t.Fatalf("failed to create tarball: %s", err)
+	}
+	if err := tarball.AddDirectory(dataPath, "prefix"); err != nil {
+		t.Fatalf("failed to add data directory to tarball: %s", err)
+	}
+	tarball.Close()
+
+	testDir := path.Join(tmpDir, outDir)
+	err = WalkTarGz(tarballPath, func(header *tar.Header, reader io.Reader) error {
+		return ExtractTarEntry(header, reader, testDir+"/", strings.TrimPrefix(header.Name, "prefix"))
+	})
+	if err != nil {
+		t.Fatalf("failed to extract tarball: %s", err)
+	}
+
+	for name, content := range filesData {
+		if out, err := os.ReadFile(path.Join(testDir, name)); err != nil {
+			t.Errorf("failed to read %s: %s", name, err)
+		} else if string(out) != content {
+			t.Errorf("expected %s content %s, but got %s", name, content, string(out))
+		}
+	}
+
+	if link, err := os.Readlink(path.Join(testDir, "link1")); err != nil {
+		t.Errorf("failed to read link1 symlink: %s", err)
+	} else if link != "file1" {
+		t.Errorf("expected link1 to point to file1, but got %s", link)
+	}
+
+	// Entries outside of the target folder are skipped
+	header := tar.Header{Typeflag: tar.TypeReg, Name: "../evil", Mode: 0600}
+	if err := ExtractTarEntry(&header, strings.NewReader(""), testDir, header.Name); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if FileExists(path.Join(tmpDir, "evil")) {
+		t.Error("file extracted outside of the target folder")
+	}
+
+	// Symbolic links pointing outside of the target folder are recreated as is
+	for _, linkname := range []string{"/usr/lib/systemd/system/uyuni-server.service", "../../outside"} {
+		header = tar.Header{Typeflag: tar.TypeSymlink, Name: "escape", Linkname: linkname, Mode: 0777}
+		if err := ExtractTarEntry(&header, strings.NewReader(""), testDir, header.Name); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		if link, err := os.Readlink(path.Join(testDir, "escape")); err != nil {
+			t.Errorf("symbolic link to %s not extracted: %s", linkname, err)
+		} else if link != linkname {
+			t.Errorf("expected escape to point to %s, but got %s", linkname, link)
+		}
+	}
+
+	// Nothing is written through an existing symbolic link
+	outside := path.Join(tmpDir, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatalf("failed to create test folder: %s", err)
+	}
+	if err := os.Symlink(outside, path.Join(testDir, "sneaky")); err != nil {
+		t.Fatalf("failed to create test symlink: %s", err)
+	}
+	header = tar.Header{Typeflag: tar.TypeReg, Name: "sneaky/evil", Mode: 0600}
+	if err := ExtractTarEntry(&header, strings.NewReader("evil"), testDir, header.Name); err == nil {
+		t.Error("expected an error when writing through a symbolic link")
+	}
+	if FileExists(path.Join(outside, "evil")) {
+		t.Error("file extracted through a symbolic link")
+	}
+
+	// A file replaces an existing symbolic link instead of writing to its target
+	if err := os.Symlink(path.Join(outside, "target"), path.Join(testDir, "replaced")); err != nil {
+		t.Fatalf("failed to create test symlink: %s", err)
+	}
+	header = tar.Header{Typeflag: tar.TypeReg, Name: "replaced", Mode: 0600}
+	if err := ExtractTarEntry(&header, strings.NewReader("content"), testDir, header.Name); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if FileExists(path.Join(outside, "target")) {
+		t.Error("file written through a symbolic link")
+	}
+}
+
+func TestEncryptedTarGz(t *testing.T) {
+	tmpDir, teardown := setup(t)
+	defer teardown(t)
+
+	tarballPath := path.Join(tmpDir, "test.tar.gz")
+	tarball, err := NewEncryptedTarGz(tarballPath, "secret")
+	if err != nil {
+		t.Fatalf("failed to create tarball: %s", err)
+	}
+	if err := tarball.AddDirectory(path.Join(tmpDir, dataDir), "data"); err != nil {
+		t.Fatalf("failed to add data directory to tarball: %s", err)
+	}
+	if err := tarball.Close(); err != nil {
+		t.Fatalf("failed to close tarball: %s", err)
+	}
+
+	checksums := tarball.Checksums()
+	expectedChecksum := fmt.Sprintf("%x", sha256.Sum256([]byte(file1_content)))
+	if checksum := checksums["data/file1"]; checksum != expectedChecksum {
+		t.Errorf("expected checksum %s for data/file1, but got %s", expectedChecksum, checksum)
+	}
+	if _, found := checksums["data/sub"]; found {
+		t.Error("directories should have no checksum")
+	}
+
+	if encrypted, err := IsEncrypted(tarballPath); err != nil || !encrypted {
+		t.Errorf("expected the tarball to be encrypted")
+	}
+
+	if err := WalkTarGz(tarballPath, func(header *tar.Header, reader io.Reader) error { return nil }); err == nil {
+		t.Error("expected an error walking an encrypted tarball without passphrase")
+	}
+	if err := WalkEncryptedTarGz(tarballPath, "wrong",
+		func(header *tar.Header, reader io.Reader) error { return nil },
+	); err == nil {
+		t.Error("expected an error walking an encrypted tarball with a wrong passphrase")
+	}
+
+	contents := map[string]string{}
+	err = WalkEncryptedTarGz(tarballPath, "secret", func(header *tar.Header, reader io.Reader) error {
+		if header.Typeflag == tar.TypeReg {
+			data, err := io.ReadAll(reader)
+			contents[strings.TrimPrefix(header.Name, "data/")] = string(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk the encrypted tarball: %s", err)
+	}
+	for name, content := range filesData {
+		if contents[name] != content {
+			t.Errorf("expected %s content %s, but got %s", name, content, contents[name])
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -17,15 +18,26 @@ type Template interface {
 	Render(wr io.Writer) error
 }
 
-// WriteTemplateToFile writes a template to a file.
+// WriteTemplateToFile writes a template to a file of the host running the commands.
 func WriteTemplateToFile(template Template, path string, perm os.FileMode, overwrite bool) error {
 	// Check if the file is existing
 	if !overwrite {
-		if FileExists(path) {
+		if HostFileExists(path) {
 			return fmt.Errorf(L("%s file already present, not overwriting"), path)
 		}
 	}
 
+	if IsRemoteExecutor() {
+		var content bytes.Buffer
+		if err := template.Render(&content); err != nil {
+			return err
+		}
+		if err := WriteHostFile(path, content.Bytes(), perm); err != nil {
+			return Errorf(err, L("failed to write %s"), path)
+		}
+		return nil
+	}
+
 	// Write the configuration
 	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {